@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"value-sniffer-radar/internal/config"
+	"value-sniffer-radar/internal/engine"
 	"value-sniffer-radar/internal/labeler"
 	"value-sniffer-radar/internal/marketdata"
 )
@@ -39,6 +40,11 @@ func main() {
 	var maxPerRun int
 	var mockRate float64
 	var mockConfidence string
+	var ticksDir string
+	var tickMaxAge time.Duration
+	var kind string
+	var days string
+	var rewards string
 
 	flag.StringVar(&configPath, "config", "config.yaml", "Path to config YAML")
 	flag.StringVar(&inPath, "in", "", "Input paper_log JSONL path")
@@ -48,6 +54,11 @@ func main() {
 	flag.IntVar(&maxPerRun, "max", 200, "Max labels to write per run")
 	flag.Float64Var(&mockRate, "mock-rate", math.NaN(), "Optional: use a mock fusion rate (no network). Example: 1.6")
 	flag.StringVar(&mockConfidence, "mock-confidence", "PASS", "Mock confidence: PASS|FAIL (used only when -mock-rate is set)")
+	flag.StringVar(&ticksDir, "ticks", "", "Optional: label offline from recorded ticks in this dir (marketdata.record.dir); no grace, no network")
+	flag.DurationVar(&tickMaxAge, "tick-max-age", 30*time.Second, "Offline: oldest recorded tick accepted as the value at event_ts+window")
+	flag.StringVar(&kind, "kind", "", "Only label events of this kind (repo|cb|fund); empty labels all")
+	flag.StringVar(&days, "days", "1,3,5", "Trade-day windows for cb/fund rewards")
+	flag.StringVar(&rewards, "reward", "", "Reward per kind over the defaults, e.g. cb=price_return (rewards: "+strings.Join(labeler.RewardNames(), ", ")+")")
 	flag.Parse()
 
 	if inPath == "" {
@@ -64,7 +75,19 @@ func main() {
 		os.Exit(1)
 	}
 	var md marketdata.Fusion
-	if !math.IsNaN(mockRate) {
+	var history *marketdata.Replay
+	if ticksDir != "" {
+		dates, err := marketdata.TickDates(ticksDir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "[error] list ticks:", err.Error())
+			os.Exit(1)
+		}
+		history, err = marketdata.NewReplay(ticksDir, dates, nil, tickMaxAge)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "[error] load ticks:", err.Error())
+			os.Exit(1)
+		}
+	} else if !math.IsNaN(mockRate) {
 		conf := marketdata.ConfidencePass
 		if strings.EqualFold(strings.TrimSpace(mockConfidence), "FAIL") {
 			conf = marketdata.ConfidenceFail
@@ -89,13 +112,38 @@ func main() {
 		os.Exit(2)
 	}
 
+	ds, err := labeler.ParseDayWindows(days)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "[error] parse days:", err.Error())
+		os.Exit(2)
+	}
+	rw, err := labeler.ParseRewards(rewards)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "[error] parse reward:", err.Error())
+		os.Exit(2)
+	}
+
 	lcfg := labeler.DefaultConfig()
 	lcfg.Windows = ws
+	lcfg.DayWindows = ds
+	lcfg.Rewards = rw
+	lcfg.OnlyKind = strings.ToLower(strings.TrimSpace(kind))
 	lcfg.Grace = gd
 	lcfg.MaxPerRun = maxPerRun
+	if history != nil {
+		lcfg.History = history
+	}
+	if client, err := engine.NewTushareClient(cfg, nil); err == nil {
+		lcfg.Tushare = client
+	} else if lcfg.OnlyKind != "repo" {
+		fmt.Fprintln(os.Stderr, "[warn] cb/fund labels skipped:", err.Error())
+	}
 
 	r := labeler.New(cfg, md, lcfg)
 	wrote, skipped, err := r.RunOnce(context.Background(), inPath, outPath)
+	if uerr := lcfg.Tushare.Usage().Flush(time.Now()); uerr != nil {
+		fmt.Fprintln(os.Stderr, "[warn] tushare usage save:", uerr.Error())
+	}
 	if err != nil {
 		fmt.Fprintln(os.Stderr, "[error] run:", err.Error())
 		os.Exit(1)
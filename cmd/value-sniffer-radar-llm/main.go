@@ -163,6 +163,7 @@ func runEnrich(rows []optimizer.PaperRow, c llm.Client, outPath string) error {
 			continue
 		}
 		rec := map[string]any{
+			"event_id": optimizer.EventID(pr),
 			"ts":       pr.TS,
 			"event":    pr.Event,
 			"llm":      en,
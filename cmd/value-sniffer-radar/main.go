@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"value-sniffer-radar/internal/config"
@@ -33,8 +36,13 @@ func main() {
 		log.Printf("trade_date mode: %s", cfg.Engine.TradeDateMode)
 	}
 
-	if err := e.Run(); err != nil {
+	// SIGINT/SIGTERM cancel ctx; the engine finishes (or times out) the in-flight run and flushes notifiers.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := e.Run(ctx); err != nil {
 		fmt.Fprintln(os.Stderr, err.Error())
 		os.Exit(1)
 	}
+	log.Printf("value-sniffer-radar stopped")
 }
@@ -0,0 +1,62 @@
+// Package clock abstracts "now" so the engine, its policies and notifiers can
+// run on simulated time (backtests, tests) as well as on the wall clock.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+// Func adapts a plain func to Clock.
+type Func func() time.Time
+
+func (f Func) Now() time.Time { return f() }
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+// Real is the wall clock.
+var Real Clock = realClock{}
+
+// Or returns c, or Real when c is nil.
+func Or(c Clock) Clock {
+	if c == nil {
+		return Real
+	}
+	return c
+}
+
+// Sim is a manually driven clock. It is safe for concurrent use.
+type Sim struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewSim(start time.Time) *Sim { return &Sim{now: start} }
+
+func (s *Sim) Now() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.now
+}
+
+// Set moves the clock to t (backwards too).
+func (s *Sim) Set(t time.Time) {
+	s.mu.Lock()
+	s.now = t
+	s.mu.Unlock()
+}
+
+// Advance moves the clock forward by d and returns the new time.
+func (s *Sim) Advance(d time.Duration) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = s.now.Add(d)
+	return s.now
+}
@@ -0,0 +1,31 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSimAndOr(t *testing.T) {
+	t0 := time.Date(2026, 1, 8, 9, 30, 0, 0, time.UTC)
+	s := NewSim(t0)
+	if !s.Now().Equal(t0) {
+		t.Fatalf("now=%v", s.Now())
+	}
+	if got := s.Advance(90 * time.Second); !got.Equal(t0.Add(90*time.Second)) || !s.Now().Equal(got) {
+		t.Fatalf("advance=%v now=%v", got, s.Now())
+	}
+	s.Set(t0)
+	if !s.Now().Equal(t0) {
+		t.Fatalf("set: now=%v", s.Now())
+	}
+
+	if Or(nil) != Real {
+		t.Fatal("Or(nil) must be Real")
+	}
+	if Or(s) != Clock(s) {
+		t.Fatal("Or must keep a non-nil clock")
+	}
+	if got := Func(func() time.Time { return t0 }).Now(); !got.Equal(t0) {
+		t.Fatalf("func clock=%v", got)
+	}
+}
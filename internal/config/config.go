@@ -15,12 +15,37 @@ type Config struct {
 	Marketdata MarketdataConfig `yaml:"marketdata"`
 	Notifiers  []NotifierConfig `yaml:"notifiers"`
 	Signals    []SignalConfig   `yaml:"signals"`
+
+	// Dir is the directory of the loaded file; relative paths in params
+	// resolve against it.
+	Dir string `yaml:"-"`
+	// Path is the loaded file itself; the engine watches it for reloads.
+	Path string `yaml:"-"`
 }
 
 type TushareConfig struct {
 	BaseURL        string `yaml:"base_url"`
 	TokenEnv       string `yaml:"token_env"`
 	TimeoutSeconds int    `yaml:"timeout_seconds"`
+
+	Cache TushareCacheConfig `yaml:"cache"`
+
+	// Token buckets per api_name ("default" covers the rest); unset means unlimited.
+	RateLimits map[string]TushareRateLimit `yaml:"rate_limits"`
+	UsagePath  string                      `yaml:"usage_path"` // daily call counters per signal/api; default state/tushare_usage.json
+}
+
+type TushareRateLimit struct {
+	PerMinute int `yaml:"per_minute"`
+	Burst     int `yaml:"burst"` // default per_minute/10 (min 1)
+}
+
+// TushareCacheConfig caches successful, non-empty responses in memory and on disk
+// so ticks and restarts don't re-download slow reference data.
+type TushareCacheConfig struct {
+	Enabled    bool           `yaml:"enabled"`
+	Dir        string         `yaml:"dir"`         // default state/tushare_cache
+	TTLSeconds map[string]int `yaml:"ttl_seconds"` // per api_name, overrides built-in defaults; 0 disables caching for that api
 }
 
 type EngineConfig struct {
@@ -31,6 +56,12 @@ type EngineConfig struct {
 	DedupeSeconds   int    `yaml:"dedupe_seconds"`     // default 3600; set -1 to disable
 	MaxEventsPerRun int    `yaml:"max_events_per_run"` // default 50; 0 means no limit
 
+	// Signals are evaluated concurrently by at most this many workers.
+	MaxParallelSignals int `yaml:"max_parallel_signals"` // default 4
+
+	// Graceful shutdown: how long an in-flight run may keep going after SIGINT/SIGTERM.
+	ShutdownTimeoutSeconds int `yaml:"shutdown_timeout_seconds"` // default 10
+
 	// Tier controls: "action" (high quality) vs "observe" (broad coverage).
 	ActionMaxEventsPerRun  int `yaml:"action_max_events_per_run"`  // default 10; 0 means unlimited
 	ObserveMaxEventsPerRun int `yaml:"observe_max_events_per_run"` // default 50; 0 means unlimited
@@ -51,6 +82,10 @@ type EngineConfig struct {
 	DefaultFeePct      float64            `yaml:"default_fee_pct"`
 	FeePctByMarket     map[string]float64 `yaml:"fee_pct_by_market"`
 
+	// Fee schedules and order sizing; when enabled, fee_pct comes from the
+	// schedule instead of default_fee_pct / fee_pct_by_market.
+	Costs CostsConfig `yaml:"costs"`
+
 	// Per-signal action budgets (daily).
 	// Example:
 	//   action_max_events_per_signal_per_day:
@@ -59,6 +94,52 @@ type EngineConfig struct {
 
 	// Optional: load optimizer recommendations and override per-signal quotas at runtime.
 	RecoPath string `yaml:"reco_path"`
+
+	// Exchange session model (trading calendar + intraday phases).
+	Session SessionConfig `yaml:"session"`
+
+	// Policy state (dedupe/cooldown/daily caps) persisted across restarts.
+	StateStore string `yaml:"state_store"` // file | memory (default file)
+	StatePath  string `yaml:"state_path"`  // default state/engine.state.json
+
+	// Events are checked against eventschema (vsr.event.v2) before notify:
+	// warn logs violations, drop also withholds the event, off skips the check.
+	SchemaValidation string `yaml:"schema_validation"` // warn | drop | off (default warn)
+
+	// Hot reload: poll the config file and reco_path this often and apply
+	// changes without a restart. 0 disables.
+	ReloadSeconds int `yaml:"reload_seconds"`
+}
+
+// CostsConfig prices alerts with per-market fee schedules.
+type CostsConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Order size (CNY) for fees and book depth; default 100000. Signals
+	// inherit both unless they set trade_notional / notional_by_symbol.
+	TradeNotional    float64            `yaml:"trade_notional"`
+	NotionalBySymbol map[string]float64 `yaml:"notional_by_symbol"`
+
+	// market -> class (stock | etf | cb | repo) -> schedule, merged over the
+	// built-in CN-A rates. A configured class replaces its default entirely.
+	Fees map[string]map[string]FeeScheduleConfig `yaml:"fees"`
+}
+
+// FeeScheduleConfig rates are in pct of the traded notional.
+type FeeScheduleConfig struct {
+	CommissionPct     float64         `yaml:"commission_pct"`
+	MinCommission     float64         `yaml:"min_commission"` // CNY per order
+	StampDutyPct      float64         `yaml:"stamp_duty_pct"` // sells only
+	ExchangeFeePct    float64         `yaml:"exchange_fee_pct"`
+	RepoFeePctByTenor map[int]float64 `yaml:"repo_fee_pct_by_tenor"` // tenor days -> pct
+}
+
+// SessionConfig gates engine runs by SSE/SZSE session phase.
+// When disabled the engine ticks around the clock (legacy behaviour).
+type SessionConfig struct {
+	Enabled               bool   `yaml:"enabled"`
+	CalendarCachePath     string `yaml:"calendar_cache_path"`      // default state/trade_cal.json
+	PostCloseDelayMinutes int    `yaml:"post_close_delay_minutes"` // post-close pass runs at 15:00 + delay; default 5
 }
 
 type MarketdataConfig struct {
@@ -70,15 +151,35 @@ type MarketdataConfig struct {
 	RequiredSources int     `yaml:"required_sources"` // default 2
 	MaxAbsDiff      float64 `yaml:"max_abs_diff"`     // default 0.05 (pct points)
 	StalenessSec    int     `yaml:"staleness_sec"`    // default 10
-	MinValid        float64 `yaml:"min_valid"`        // default 0
+	MinValid        float64 `yaml:"min_valid"`        // repo rate range; default 0
 	MaxValid        float64 `yaml:"max_valid"`        // default 20
 
+	// Non-repo instruments (cb/etf/stock): consensus tolerance relative to the
+	// median, in pct of the value (max_abs_diff stays the repo tolerance).
+	MaxRelDiffPct float64 `yaml:"max_rel_diff_pct"` // default 0.1
+	// Price validity per instrument class (cb | etf | stock | unknown); overrides built-in ranges.
+	ValidRanges map[string]MarketdataValidRange `yaml:"valid_ranges"`
+
 	// Circuit breaker
 	FailThreshold    int `yaml:"fail_threshold"`    // default 3
 	OutlierThreshold int `yaml:"outlier_threshold"` // default 3
 	CooldownSec      int `yaml:"cooldown_sec"`      // default 120
 
 	Providers []MarketdataProviderConfig `yaml:"providers"`
+
+	// Append every fusion result to gzip JSONL under record.dir/<YYYYMMDD>/
+	// for replay and offline labeling.
+	Record TickRecordConfig `yaml:"record"`
+}
+
+type TickRecordConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Dir     string `yaml:"dir"` // default state/ticks
+}
+
+type MarketdataValidRange struct {
+	Min float64 `yaml:"min"`
+	Max float64 `yaml:"max"`
 }
 
 type MarketdataProviderConfig struct {
@@ -92,62 +193,42 @@ type MarketdataProviderConfig struct {
 
 	// tencent_repo
 	QuoteURL string `yaml:"quote_url"`
+
+	// ETF IOPV from the same endpoints. Field ids differ between endpoint
+	// versions; verify against a live response. Unset means no IOPV in quotes.
+	IOPVField string `yaml:"iopv_field"` // eastmoney_repo, e.g. "f441"
+	IOPVIndex int    `yaml:"iopv_index"` // tencent_repo, "~"-separated index
 }
 
 type NotifierConfig struct {
-	Type string `yaml:"type"` // stdout | email | webhook | aival_queue | paper_log
-
-	// email
-	SMTPHost      string   `yaml:"smtp_host"`
-	SMTPPort      int      `yaml:"smtp_port"`
-	UsernameEnv   string   `yaml:"username_env"`
-	PasswordEnv   string   `yaml:"password_env"`
-	From          string   `yaml:"from"`
-	To            []string `yaml:"to"`
-	SubjectPrefix string   `yaml:"subject_prefix"`
-
-	// webhook
-	URL            string            `yaml:"url"`
-	TimeoutSeconds int               `yaml:"timeout_seconds"`
-	Headers        map[string]string `yaml:"headers"`
-
-	// aival_queue (AI-Value / AstrBot file-queue)
-	QueueDir string   `yaml:"queue_dir"`
-	Market   string   `yaml:"market"`
-	Tags     []string `yaml:"tags"`
-
-	// paper_log (append JSONL for evaluation)
-	FilePath string `yaml:"file_path"`
+	Type string `yaml:"type"` // a registered notifier type: stdout | email | webhook | aival_queue | paper_log
+
+	// Params are the settings of the type, decoded by its registered factory
+	// (see notifier.Register). Entries may also list them next to type.
+	Params yaml.Node `yaml:"params"`
+	flat   bool
 }
 
 type SignalConfig struct {
-	Type               string `yaml:"type"` // cb_premium | cb_double_low | fund_premium | cn_repo_sniper | cn_repo_realtime
+	Type               string `yaml:"type"` // a registered signal type, e.g. cb_premium | cn_repo_realtime | cn_repo_ladder
 	Name               string `yaml:"name"` // instance name (optional). Allows multiple entries of same type.
 	Enabled            bool   `yaml:"enabled"`
 	Tier               string `yaml:"tier"`                 // action | observe
 	MinIntervalSeconds int    `yaml:"min_interval_seconds"` // 0 uses engine interval
+	TimeoutSeconds     int    `yaml:"timeout_seconds"`      // per-evaluation deadline; 0 means no per-signal timeout
 
-	// shared
-	MinAmount float64 `yaml:"min_amount"`
-	TopN      int     `yaml:"top_n"`
-	ConfirmK  int     `yaml:"confirm_k"` // optional k-confirm for action triggers (default 1)
-
-	// cb_premium
-	PremiumPctLow  float64 `yaml:"premium_pct_low"`
-	PremiumPctHigh float64 `yaml:"premium_pct_high"`
+	// Own evaluation lane: "@every 3s" or cron "*/5 9-15 * * 1-5" (exchange time).
+	// Empty runs on the shared engine.interval_seconds lane with min_interval_seconds.
+	Schedule string `yaml:"schedule"`
 
-	// cb_double_low
-	MaxDoubleLow float64 `yaml:"max_double_low"`
+	// Session phases in which the signal runs (engine.session.enabled only).
+	// Default: [morning, afternoon]. Use [post_close] for daily signals (one pass per trade day).
+	Phases []string `yaml:"phases"`
 
-	// fund_premium
-	Market          string `yaml:"market"`
-	PickTopByAmount int    `yaml:"pick_top_by_amount"`
-
-	// cn_repo_sniper (reverse repo yield monitor)
-	RepoCodes   []string `yaml:"repo_codes"`    // e.g. ["204001.SH","131810.SZ"]
-	MinYieldPct float64  `yaml:"min_yield_pct"` // threshold on weighted rate (%)
-	WindowStart string   `yaml:"window_start"`  // "HH:MM" or "HHMM" (optional)
-	WindowEnd   string   `yaml:"window_end"`    // "HH:MM" or "HHMM" (optional)
+	// Params are the settings of the type (thresholds, codes, windows),
+	// decoded and validated by its registered factory (see signals.Register).
+	Params yaml.Node `yaml:"params"`
+	flat   bool
 }
 
 func Load(path string) (*Config, error) {
@@ -159,7 +240,9 @@ func Load(path string) (*Config, error) {
 	if err := yaml.Unmarshal(b, &cfg); err != nil {
 		return nil, err
 	}
-	if err := cfg.normalizeAndValidate(filepath.Dir(path)); err != nil {
+	cfg.Dir = filepath.Dir(path)
+	cfg.Path = path
+	if err := cfg.normalizeAndValidate(cfg.Dir); err != nil {
 		return nil, err
 	}
 	return &cfg, nil
@@ -175,6 +258,28 @@ func (c *Config) normalizeAndValidate(baseDir string) error {
 	if c.Tushare.TimeoutSeconds <= 0 {
 		c.Tushare.TimeoutSeconds = 20
 	}
+	if c.Tushare.Cache.Dir == "" {
+		c.Tushare.Cache.Dir = filepath.Join("state", "tushare_cache")
+	}
+	if !filepath.IsAbs(c.Tushare.Cache.Dir) {
+		c.Tushare.Cache.Dir = filepath.Join(baseDir, c.Tushare.Cache.Dir)
+	}
+	for api, rl := range c.Tushare.RateLimits {
+		if rl.PerMinute < 0 || rl.Burst < 0 {
+			return errors.New("tushare.rate_limits." + api + ": per_minute and burst must be >= 0")
+		}
+	}
+	if c.Tushare.UsagePath == "" {
+		c.Tushare.UsagePath = filepath.Join("state", "tushare_usage.json")
+	}
+	if !filepath.IsAbs(c.Tushare.UsagePath) {
+		c.Tushare.UsagePath = filepath.Join(baseDir, c.Tushare.UsagePath)
+	}
+	for api, ttl := range c.Tushare.Cache.TTLSeconds {
+		if ttl < 0 {
+			return errors.New("tushare.cache.ttl_seconds." + api + " must be >= 0")
+		}
+	}
 	if c.Engine.IntervalSeconds <= 0 {
 		c.Engine.IntervalSeconds = 300
 	}
@@ -195,6 +300,21 @@ func (c *Config) normalizeAndValidate(baseDir string) error {
 	if c.Engine.MaxEventsPerRun == 0 {
 		c.Engine.MaxEventsPerRun = 50
 	}
+	if c.Engine.MaxParallelSignals < 0 {
+		return errors.New("engine.max_parallel_signals must be >= 0")
+	}
+	if c.Engine.MaxParallelSignals == 0 {
+		c.Engine.MaxParallelSignals = 4
+	}
+	if c.Engine.ShutdownTimeoutSeconds < 0 {
+		return errors.New("engine.shutdown_timeout_seconds must be >= 0")
+	}
+	if c.Engine.ShutdownTimeoutSeconds == 0 {
+		c.Engine.ShutdownTimeoutSeconds = 10
+	}
+	if c.Engine.ReloadSeconds < 0 {
+		return errors.New("engine.reload_seconds must be >= 0")
+	}
 	if c.Engine.ActionMaxEventsPerRun < 0 || c.Engine.ObserveMaxEventsPerRun < 0 {
 		return errors.New("engine.action_max_events_per_run / observe_max_events_per_run must be >= 0")
 	}
@@ -229,6 +349,9 @@ func (c *Config) normalizeAndValidate(baseDir string) error {
 	if c.Engine.FeePctByMarket == nil {
 		c.Engine.FeePctByMarket = map[string]float64{}
 	}
+	if err := c.Engine.Costs.normalize(); err != nil {
+		return err
+	}
 	if c.Engine.ActionMaxEventsPerSignalPerDay == nil {
 		c.Engine.ActionMaxEventsPerSignalPerDay = map[string]int{}
 	}
@@ -247,6 +370,37 @@ func (c *Config) normalizeAndValidate(baseDir string) error {
 		}
 		c.Engine.RecoPath = p
 	}
+	if c.Engine.Session.PostCloseDelayMinutes < 0 {
+		return errors.New("engine.session.post_close_delay_minutes must be >= 0")
+	}
+	if c.Engine.Session.PostCloseDelayMinutes == 0 {
+		c.Engine.Session.PostCloseDelayMinutes = 5
+	}
+	if c.Engine.Session.CalendarCachePath == "" {
+		c.Engine.Session.CalendarCachePath = filepath.Join("state", "trade_cal.json")
+	}
+	if !filepath.IsAbs(c.Engine.Session.CalendarCachePath) {
+		c.Engine.Session.CalendarCachePath = filepath.Join(baseDir, c.Engine.Session.CalendarCachePath)
+	}
+	if c.Engine.StateStore == "" {
+		c.Engine.StateStore = "file"
+	}
+	if c.Engine.StateStore != "file" && c.Engine.StateStore != "memory" {
+		return errors.New("engine.state_store must be file or memory")
+	}
+	if c.Engine.StatePath == "" {
+		c.Engine.StatePath = filepath.Join("state", "engine.state.json")
+	}
+	if !filepath.IsAbs(c.Engine.StatePath) {
+		c.Engine.StatePath = filepath.Join(baseDir, c.Engine.StatePath)
+	}
+	switch c.Engine.SchemaValidation {
+	case "":
+		c.Engine.SchemaValidation = "warn"
+	case "warn", "drop", "off":
+	default:
+		return errors.New("engine.schema_validation must be warn, drop or off")
+	}
 
 	// marketdata defaults (optional)
 	if c.Marketdata.TimeoutMS <= 0 {
@@ -276,11 +430,38 @@ func (c *Config) normalizeAndValidate(baseDir string) error {
 	if c.Marketdata.CooldownSec <= 0 {
 		c.Marketdata.CooldownSec = 120
 	}
+	if c.Marketdata.MaxRelDiffPct < 0 {
+		return errors.New("marketdata.max_rel_diff_pct must be >= 0")
+	}
+	if c.Marketdata.MaxRelDiffPct == 0 {
+		c.Marketdata.MaxRelDiffPct = 0.1
+	}
+	for class, r := range c.Marketdata.ValidRanges {
+		switch class {
+		case "repo":
+			return errors.New("marketdata.valid_ranges.repo: use marketdata.min_valid/max_valid")
+		case "cb", "etf", "stock", "unknown":
+		default:
+			return errors.New("marketdata.valid_ranges: unknown class " + class + " (cb | etf | stock | unknown)")
+		}
+		if r.Max <= r.Min {
+			return errors.New("marketdata.valid_ranges." + class + ": max must be > min")
+		}
+	}
+	if c.Marketdata.Record.Dir == "" {
+		c.Marketdata.Record.Dir = filepath.Join("state", "ticks")
+	}
+	if !filepath.IsAbs(c.Marketdata.Record.Dir) {
+		c.Marketdata.Record.Dir = filepath.Join(baseDir, c.Marketdata.Record.Dir)
+	}
 	for i := range c.Marketdata.Providers {
 		p := &c.Marketdata.Providers[i]
 		if p.RateDivisor == 0 {
 			p.RateDivisor = 1.0
 		}
+		if p.IOPVIndex < 0 {
+			return errors.New("marketdata.providers[].iopv_index must be >= 0")
+		}
 	}
 	if c.Engine.TradeDateMode != "latest_open" && c.Engine.TradeDateMode != "fixed" {
 		return errors.New("engine.trade_date_mode must be latest_open or fixed")
@@ -288,20 +469,19 @@ func (c *Config) normalizeAndValidate(baseDir string) error {
 	if c.Engine.TradeDateMode == "fixed" && c.Engine.FixedTradeDate == "" {
 		return errors.New("engine.fixed_trade_date required when trade_date_mode=fixed")
 	}
-	if c.RequiresTushare() && os.Getenv(c.Tushare.TokenEnv) == "" {
-		return errors.New("missing Tushare token env: " + c.Tushare.TokenEnv)
-	}
 	if len(c.Notifiers) == 0 {
 		return errors.New("at least one notifier required (e.g. stdout)")
 	}
-	for i := range c.Notifiers {
-		n := &c.Notifiers[i]
-		if n.Type == "paper_log" && n.FilePath != "" && !filepath.IsAbs(n.FilePath) {
-			n.FilePath = filepath.Join(baseDir, n.FilePath)
+	for _, n := range c.Notifiers {
+		if strings.TrimSpace(n.Type) == "" {
+			return errors.New("notifiers[].type required")
 		}
 	}
 	for i := range c.Signals {
 		s := &c.Signals[i]
+		if strings.TrimSpace(s.Type) == "" {
+			return errors.New("signals[].type required")
+		}
 		if s.Tier == "" {
 			s.Tier = "action"
 		}
@@ -311,30 +491,46 @@ func (c *Config) normalizeAndValidate(baseDir string) error {
 		if s.MinIntervalSeconds < 0 {
 			return errors.New("signals[].min_interval_seconds must be >= 0")
 		}
-		if s.ConfirmK < 0 {
-			return errors.New("signals[].confirm_k must be >= 0")
-		}
-		if s.ConfirmK == 0 {
-			s.ConfirmK = 1
+		if s.TimeoutSeconds < 0 {
+			return errors.New("signals[].timeout_seconds must be >= 0")
 		}
+		// schedule and phases are parsed by the engine, which owns exchange time.
 	}
 	return nil
 }
 
-func (c *Config) RequiresTushare() bool {
-	// Trade date resolution via trade_cal needs Tushare.
-	if c.Engine.TradeDateMode == "latest_open" {
-		return true
+// feeClasses are the instrument classes a fee schedule can be configured for.
+var feeClasses = map[string]bool{"stock": true, "etf": true, "cb": true, "repo": true}
+
+func (c *CostsConfig) normalize() error {
+	if c.TradeNotional < 0 {
+		return errors.New("engine.costs.trade_notional must be >= 0")
+	}
+	if c.TradeNotional == 0 {
+		c.TradeNotional = 100000
 	}
-	// Signal data needs Tushare for these types.
-	for _, s := range c.Signals {
-		if !s.Enabled {
-			continue
+	bySymbol := make(map[string]float64, len(c.NotionalBySymbol))
+	for k, v := range c.NotionalBySymbol {
+		if v < 0 {
+			return errors.New("engine.costs.notional_by_symbol values must be >= 0")
 		}
-		switch s.Type {
-		case "cb_premium", "cb_double_low", "fund_premium", "cn_repo_sniper":
-			return true
+		bySymbol[strings.ToUpper(strings.TrimSpace(k))] = v
+	}
+	c.NotionalBySymbol = bySymbol
+	for market, classes := range c.Fees {
+		for class, f := range classes {
+			if !feeClasses[class] {
+				return errors.New("engine.costs.fees." + market + ": unknown class " + class + " (stock | etf | cb | repo)")
+			}
+			if f.CommissionPct < 0 || f.MinCommission < 0 || f.StampDutyPct < 0 || f.ExchangeFeePct < 0 {
+				return errors.New("engine.costs.fees." + market + "." + class + ": rates must be >= 0")
+			}
+			for days, pct := range f.RepoFeePctByTenor {
+				if days <= 0 || pct < 0 {
+					return errors.New("engine.costs.fees." + market + "." + class + ".repo_fee_pct_by_tenor: tenor must be > 0 and fee >= 0")
+				}
+			}
 		}
 	}
-	return false
+	return nil
 }
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Signal and notifier entries carry their type's settings under params:.
+// Configs written before params: existed list them next to type; such
+// entries still load, with every key that is not a common one treated as a
+// param and unknown keys ignored as they always were.
+
+func (s *SignalConfig) UnmarshalYAML(n *yaml.Node) error {
+	type plain SignalConfig
+	if err := n.Decode((*plain)(s)); err != nil {
+		return err
+	}
+	if s.Params.Kind == 0 {
+		s.Params, s.flat = flatParams(n, reflect.TypeOf(plain{})), true
+	}
+	return nil
+}
+
+func (c *NotifierConfig) UnmarshalYAML(n *yaml.Node) error {
+	type plain NotifierConfig
+	if err := n.Decode((*plain)(c)); err != nil {
+		return err
+	}
+	if c.Params.Kind == 0 {
+		c.Params, c.flat = flatParams(n, reflect.TypeOf(plain{})), true
+	}
+	return nil
+}
+
+// DecodeParams decodes the entry's params into out, a pointer to the params
+// struct of its type. Keys out has no field for are errors.
+func (s SignalConfig) DecodeParams(out any) error {
+	return decodeParams(&s.Params, s.flat, out)
+}
+
+// DecodeParams decodes the entry's params into out, a pointer to the params
+// struct of its type. Keys out has no field for are errors.
+func (c NotifierConfig) DecodeParams(out any) error {
+	return decodeParams(&c.Params, c.flat, out)
+}
+
+// ParamsNode encodes v (a params struct or a map) as an entry's params, for
+// configs built in code.
+func ParamsNode(v any) yaml.Node {
+	var n yaml.Node
+	if err := n.Encode(v); err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// flatParams collects the keys of a mapping entry that are not fields of
+// the entry type itself.
+func flatParams(n *yaml.Node, entry reflect.Type) yaml.Node {
+	common := yamlKeys(entry)
+	out := yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: n.Line, Column: n.Column}
+	if n.Kind != yaml.MappingNode {
+		return out
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if !common[n.Content[i].Value] {
+			out.Content = append(out.Content, n.Content[i], n.Content[i+1])
+		}
+	}
+	return out
+}
+
+func decodeParams(n *yaml.Node, lenient bool, out any) error {
+	if n.Kind == 0 {
+		return nil
+	}
+	if n.Kind != yaml.MappingNode {
+		if n.Tag == "!!null" {
+			return nil
+		}
+		return fmt.Errorf("line %d: params must be a mapping", n.Line)
+	}
+	if !lenient {
+		known := yamlKeys(reflect.TypeOf(out).Elem())
+		var unknown []string
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k := n.Content[i]
+			if !known[k.Value] {
+				unknown = append(unknown, fmt.Sprintf("line %d: unknown key %q", k.Line, k.Value))
+			}
+		}
+		if len(unknown) > 0 {
+			return errors.New(strings.Join(unknown, "; ") + " (known: " + strings.Join(sortedKeys(known), ", ") + ")")
+		}
+	}
+	return n.Decode(out)
+}
+
+// yamlKeys lists the mapping keys struct t decodes, including inlined structs.
+func yamlKeys(t reflect.Type) map[string]bool {
+	keys := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" || !f.IsExported() {
+			continue
+		}
+		if strings.Contains(opts, "inline") && f.Type.Kind() == reflect.Struct {
+			for k := range yamlKeys(f.Type) {
+				keys[k] = true
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		keys[name] = true
+	}
+	return keys
+}
+
+func sortedKeys(m map[string]bool) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
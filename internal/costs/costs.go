@@ -0,0 +1,223 @@
+// Package costs estimates the trading fees an alert would incur if acted on,
+// so the engine's net edge policy can subtract them from the expected edge.
+package costs
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"value-sniffer-radar/internal/config"
+	"value-sniffer-radar/internal/marketdata"
+	"value-sniffer-radar/internal/repo"
+)
+
+// DefaultTradeNotional is the CNY order size fees are estimated for when
+// neither the signal nor the config names one.
+const DefaultTradeNotional = 100000.0
+
+// Schedule is the fee schedule of one instrument class in one market. Rates
+// are in pct of the traded notional.
+type Schedule struct {
+	CommissionPct  float64
+	MinCommission  float64 // CNY per order
+	StampDutyPct   float64 // charged on sells only
+	ExchangeFeePct float64 // handling + transfer fees
+
+	// Repo handling fee by tenor (days -> pct of notional). A tenor is
+	// charged at the first bucket >= its days, or the longest bucket.
+	RepoFeePctByTenor map[int]float64
+}
+
+// DefaultFees are typical CN-A retail rates. Brokers negotiate commissions,
+// so override them with your own schedule.
+var DefaultFees = map[string]map[marketdata.Class]Schedule{
+	"CN-A": {
+		marketdata.ClassStock: {CommissionPct: 0.025, MinCommission: 5, StampDutyPct: 0.05, ExchangeFeePct: 0.00441},
+		marketdata.ClassETF:   {CommissionPct: 0.025, MinCommission: 5, ExchangeFeePct: 0.004},
+		marketdata.ClassCB:    {CommissionPct: 0.01, MinCommission: 1, ExchangeFeePct: 0.001},
+		marketdata.ClassRepo: {RepoFeePctByTenor: map[int]float64{
+			1: 0.001, 2: 0.002, 3: 0.003, 4: 0.004, 7: 0.005, 14: 0.01, 28: 0.02, 91: 0.03,
+		}},
+	},
+}
+
+// Leg is one order of a trade.
+type Leg struct {
+	Symbol   string          `json:"symbol"`
+	Side     marketdata.Side `json:"side"`
+	Notional float64         `json:"notional"`
+}
+
+// LegCost is the fee estimate of one leg. Amounts are in CNY; FeePct is in pct
+// of the leg notional, or annualized rate points for repos.
+type LegCost struct {
+	Leg
+	Class       marketdata.Class `json:"class"`
+	TenorDays   int              `json:"tenor_days,omitempty"`
+	Commission  float64          `json:"commission"`
+	StampDuty   float64          `json:"stamp_duty"`
+	ExchangeFee float64          `json:"exchange_fee"`
+	RepoFee     float64          `json:"repo_fee"`
+	FeePct      float64          `json:"fee_pct"`
+}
+
+// Breakdown is written to event.Data["cost_breakdown"] so paper logs keep the
+// inputs behind net_edge_pct. Fee is the sum over legs, like spread and slippage.
+type Breakdown struct {
+	Market         string    `json:"market"`
+	Legs           []LegCost `json:"legs,omitempty"`
+	FeePct         float64   `json:"fee_pct"`
+	FeeSource      string    `json:"fee_source"` // signal | schedule | default
+	SpreadPct      float64   `json:"spread_pct"`
+	SpreadSource   string    `json:"spread_source"` // signal (book) | default
+	SlippagePct    float64   `json:"slippage_pct"`
+	SlippageSource string    `json:"slippage_source"` // signal (book depth) | default
+}
+
+// Model prices legs against per-market fee schedules and sizes orders with
+// a default notional that can be overridden per symbol.
+type Model struct {
+	fees     map[string]map[marketdata.Class]Schedule
+	notional float64
+	bySymbol map[string]float64
+}
+
+// Options configures a Model. Fees are merged over DefaultFees per
+// market and class; a configured class replaces the default one entirely.
+type Options struct {
+	Fees             map[string]map[marketdata.Class]Schedule
+	TradeNotional    float64
+	NotionalBySymbol map[string]float64
+}
+
+func New(opt Options) *Model {
+	fees := map[string]map[marketdata.Class]Schedule{}
+	for _, src := range []map[string]map[marketdata.Class]Schedule{DefaultFees, opt.Fees} {
+		for market, classes := range src {
+			if fees[market] == nil {
+				fees[market] = map[marketdata.Class]Schedule{}
+			}
+			for c, s := range classes {
+				fees[market][c] = s
+			}
+		}
+	}
+	notional := opt.TradeNotional
+	if notional <= 0 {
+		notional = DefaultTradeNotional
+	}
+	bySymbol := make(map[string]float64, len(opt.NotionalBySymbol))
+	for sym, v := range opt.NotionalBySymbol {
+		bySymbol[strings.ToUpper(strings.TrimSpace(sym))] = v
+	}
+	return &Model{fees: fees, notional: notional, bySymbol: bySymbol}
+}
+
+// FromConfig builds a Model from an engine.costs section (whether or not
+// the section is enabled).
+func FromConfig(c config.CostsConfig) *Model {
+	fees := make(map[string]map[marketdata.Class]Schedule, len(c.Fees))
+	for market, classes := range c.Fees {
+		fees[market] = make(map[marketdata.Class]Schedule, len(classes))
+		for class, f := range classes {
+			fees[market][marketdata.Class(class)] = Schedule{
+				CommissionPct:     f.CommissionPct,
+				MinCommission:     f.MinCommission,
+				StampDutyPct:      f.StampDutyPct,
+				ExchangeFeePct:    f.ExchangeFeePct,
+				RepoFeePctByTenor: f.RepoFeePctByTenor,
+			}
+		}
+	}
+	return New(Options{
+		Fees:             fees,
+		TradeNotional:    c.TradeNotional,
+		NotionalBySymbol: c.NotionalBySymbol,
+	})
+}
+
+// Notional is the order size for symbol.
+func (m *Model) Notional(symbol string) float64 {
+	if v, ok := m.bySymbol[strings.ToUpper(strings.TrimSpace(symbol))]; ok && v > 0 {
+		return v
+	}
+	return m.notional
+}
+
+// Fees prices legs in market. It reports false when any leg has no schedule
+// (unknown market or class), leaving the caller to fall back to flat fees.
+func (m *Model) Fees(market string, legs []Leg) (Breakdown, bool) {
+	b := Breakdown{Market: market, FeeSource: "schedule"}
+	classes := m.fees[market]
+	if classes == nil || len(legs) == 0 {
+		return b, false
+	}
+	for _, l := range legs {
+		if l.Notional <= 0 {
+			l.Notional = m.Notional(l.Symbol)
+		}
+		c := marketdata.ClassOf(l.Symbol)
+		s, ok := classes[c]
+		if !ok {
+			return b, false
+		}
+		lc := legCost(l, c, s)
+		b.Legs = append(b.Legs, lc)
+		b.FeePct += lc.FeePct
+	}
+	return b, true
+}
+
+func legCost(l Leg, c marketdata.Class, s Schedule) LegCost {
+	lc := LegCost{Leg: l, Class: c}
+	if l.Notional > 0 {
+		lc.Commission = math.Max(l.Notional*s.CommissionPct/100.0, s.MinCommission)
+		if l.Side == marketdata.Sell {
+			lc.StampDuty = l.Notional * s.StampDutyPct / 100.0
+		}
+		lc.ExchangeFee = l.Notional * s.ExchangeFeePct / 100.0
+	}
+	if c == marketdata.ClassRepo {
+		lc.TenorDays = RepoTenorDays(l.Symbol)
+		lc.RepoFee = l.Notional * repoFeePct(s.RepoFeePctByTenor, lc.TenorDays) / 100.0
+	}
+	if l.Notional > 0 {
+		lc.FeePct = lc.Amount() / l.Notional * 100.0
+		if c == marketdata.ClassRepo && lc.TenorDays > 0 {
+			// Repo edges are annualized rates; spread the one-off fee over the
+			// tenor. Without a trade date this is the nominal lock; cn_repo_ladder
+			// knows the settlement and reports its own fee_pct over the lock days.
+			lc.FeePct = lc.FeePct * 365.0 / float64(lc.TenorDays)
+		}
+	}
+	return lc
+}
+
+// Amount is the total fee of the leg in CNY.
+func (lc LegCost) Amount() float64 {
+	return lc.Commission + lc.StampDuty + lc.ExchangeFee + lc.RepoFee
+}
+
+func repoFeePct(byTenor map[int]float64, days int) float64 {
+	if len(byTenor) == 0 {
+		return 0
+	}
+	tenors := make([]int, 0, len(byTenor))
+	for d := range byTenor {
+		tenors = append(tenors, d)
+	}
+	sort.Ints(tenors)
+	for _, d := range tenors {
+		if days <= d {
+			return byTenor[d]
+		}
+	}
+	return byTenor[tenors[len(tenors)-1]]
+}
+
+// RepoTenorDays is the tenor of a registered repo symbol, or 0 if unknown.
+func RepoTenorDays(symbol string) int {
+	in, _ := repo.Lookup(symbol)
+	return in.TenorDays
+}
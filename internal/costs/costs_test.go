@@ -0,0 +1,57 @@
+package costs
+
+import (
+	"math"
+	"testing"
+
+	"value-sniffer-radar/internal/marketdata"
+)
+
+func approx(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+func TestFeesStockMinimumAndStampDuty(t *testing.T) {
+	m := New(Options{TradeNotional: 10000, NotionalBySymbol: map[string]float64{"600000.sh": 1000000}})
+
+	// 10k buy: 2.5 CNY commission is lifted to the 5 CNY minimum, no stamp duty.
+	b, ok := m.Fees("CN-A", []Leg{{Symbol: "600519.SH", Side: marketdata.Buy}})
+	if !ok || len(b.Legs) != 1 {
+		t.Fatalf("ok=%v b=%+v", ok, b)
+	}
+	if l := b.Legs[0]; l.Notional != 10000 || !approx(l.Commission, 5) || l.StampDuty != 0 || !approx(b.FeePct, (5+10000*0.0000441)/10000*100) {
+		t.Fatalf("buy leg=%+v fee=%v", l, b.FeePct)
+	}
+
+	// Per-symbol notional; sells pay stamp duty.
+	b, _ = m.Fees("CN-A", []Leg{{Symbol: "600000.SH", Side: marketdata.Sell}})
+	if l := b.Legs[0]; l.Notional != 1000000 || !approx(l.Commission, 250) || !approx(l.StampDuty, 500) {
+		t.Fatalf("sell leg=%+v", l)
+	}
+
+	if _, ok := m.Fees("HK", []Leg{{Symbol: "600000.SH"}}); ok {
+		t.Fatalf("unknown market priced")
+	}
+}
+
+func TestFeesRepoAnnualizedByTenor(t *testing.T) {
+	m := New(Options{})
+	b, ok := m.Fees("CN-A", []Leg{{Symbol: "204007.SH", Side: marketdata.Sell, Notional: 1000000}})
+	if !ok {
+		t.Fatal("repo not priced")
+	}
+	l := b.Legs[0]
+	// 0.005% of notional for a 7-day repo, spread over 7 days of a 365-day year.
+	if l.TenorDays != 7 || !approx(l.RepoFee, 50) || !approx(l.FeePct, 0.005*365/7) {
+		t.Fatalf("leg=%+v", l)
+	}
+
+	// A configured class replaces the default; 182 days falls into the last bucket.
+	m = New(Options{Fees: map[string]map[marketdata.Class]Schedule{"CN-A": {marketdata.ClassRepo: {RepoFeePctByTenor: map[int]float64{1: 0.002, 28: 0.01}}}}})
+	b, _ = m.Fees("CN-A", []Leg{{Symbol: "204182.SH", Side: marketdata.Sell, Notional: 100000}})
+	if !approx(b.Legs[0].RepoFee, 10) {
+		t.Fatalf("leg=%+v", b.Legs[0])
+	}
+	// Other classes keep their defaults.
+	if _, ok := m.Fees("CN-A", []Leg{{Symbol: "510300.SH"}}); !ok {
+		t.Fatal("etf default schedule dropped")
+	}
+}
@@ -5,60 +5,258 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
+	"sync"
 	"time"
 
+	"value-sniffer-radar/internal/clock"
 	"value-sniffer-radar/internal/config"
+	"value-sniffer-radar/internal/costs"
+	"value-sniffer-radar/internal/eventschema"
 	"value-sniffer-radar/internal/marketdata"
 	"value-sniffer-radar/internal/notifier"
+	"value-sniffer-radar/internal/repo"
+	"value-sniffer-radar/internal/schedule"
+	"value-sniffer-radar/internal/session"
 	"value-sniffer-radar/internal/signals"
+	"value-sniffer-radar/internal/state"
 	"value-sniffer-radar/internal/tushare"
 )
 
 type Engine struct {
+	// mu guards policy state (maps below, recoQuotas, session bookkeeping)
+	// and the reloadable cfg, sigs and costs. Lanes evaluate concurrently; the
+	// policy pipeline runs one batch at a time.
+	mu sync.Mutex
+
+	// notifyMu guards notifiers: a reload swaps them between notifies.
+	notifyMu sync.Mutex
+
 	cfg        *config.Config
 	client     *tushare.Client
 	md         marketdata.Fusion
 	notifiers  []notifier.Notifier
-	sigs       []signals.Signal
+	sigs       []sigEntry
 	sent       map[string]time.Time
 	symbolLast map[string]time.Time
 	lastEval   map[string]time.Time
 	dailySent  map[string]int
 	recoQuotas map[string]int // optional overrides (signal -> daily action quota)
+	store      state.Store    // optional; nil keeps state in memory only
+	costs      *costs.Model   // nil: flat default_fee_pct / fee_pct_by_market
+
+	cal           *session.Calendar // nil when engine.session is disabled
+	lastPhase     session.Phase
+	postCloseDone map[string]string // lane -> exchange date of its last post-close pass
+
+	cacheHits, cacheMisses int64 // tushare cache counters at the last log line
+
+	// Trade date is resolved at most once per exchange date.
+	tdMu    sync.Mutex
+	tdDay   string
+	tdValue string
+
+	semOnce sync.Once
+	sem     chan struct{} // engine-wide signal worker slots
+
+	// Signals whose Evaluate has not returned yet, by name (guarded by mu);
+	// a timed-out call that ignores ctx stays here until it returns.
+	evaluating map[string]bool
+
+	clock clock.Clock // policies, sessions and schedules; simulated in backtests
+
+	stateDir string // Deps.StateDir, reapplied to signals built on reload
+
+	// histories are the repo rate histories signals read, fed by the
+	// marketdata RepoSampler; reloads drop the ones no signal reads.
+	histories *repo.Histories
 }
 
-func New(cfg *config.Config) (*Engine, error) {
-	var client *tushare.Client
-	if cfg.RequiresTushare() {
-		token, ok := os.LookupEnv(cfg.Tushare.TokenEnv)
-		if !ok || strings.TrimSpace(token) == "" {
-			return nil, fmt.Errorf("missing Tushare token env: %s", cfg.Tushare.TokenEnv)
-		}
-		client = tushare.New(tushare.Options{
-			BaseURL:        cfg.Tushare.BaseURL,
-			Token:          token,
-			TimeoutSeconds: cfg.Tushare.TimeoutSeconds,
-			MaxRetries:     cfg.Engine.MaxAPIRetries,
+// Deps replaces parts New would build from config; zero fields keep the
+// defaults. The backtest uses it to run the live pipeline on recorded data.
+type Deps struct {
+	Client    *tushare.Client
+	Fusion    marketdata.Fusion
+	Notifiers []notifier.Notifier
+	Clock     clock.Clock
+	StateDir  string // signal state (repo history) and the trade calendar cache go here instead of their configured paths
+	// Histories replaces the repo rate histories loaded from and saved to
+	// their paths (under StateDir if set); the backtest keeps them in memory.
+	Histories *repo.Histories
+}
+
+// sigEntry pairs a built signal with the engine-side scheduling config.
+type sigEntry struct {
+	sig     signals.Signal
+	phases  map[session.Phase]bool
+	timeout time.Duration     // 0 means bounded only by the run context
+	sched   schedule.Schedule // nil: shared engine.interval_seconds lane
+
+	cfg    config.SignalConfig // the entry and its decoded params, compared on reload
+	params any
+}
+
+// entryName keys signal entries across reloads, as the policy state does.
+func entryName(c config.SignalConfig) string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return c.Type
+}
+
+// sameEntry reports whether c with decoded params builds the same signal as
+// se, so se (and whatever state the signal keeps) can be reused.
+func sameEntry(se sigEntry, c config.SignalConfig, params any) bool {
+	a := se.cfg
+	a.Params = c.Params // compared decoded, so formatting edits do not count
+	return reflect.DeepEqual(a, c) && reflect.DeepEqual(se.params, params)
+}
+
+// historyPaths are the repo rate histories the signals of sigs read.
+func historyPaths(sigs []sigEntry) map[string]bool {
+	paths := map[string]bool{}
+	for _, se := range sigs {
+		if r, ok := se.params.(signals.RepoHistoryReader); ok && r.RepoHistoryPath() != "" {
+			paths[r.RepoHistoryPath()] = true
+		}
+	}
+	return paths
+}
+
+// buildSignals builds the enabled entries of cfgs. An entry equal to one in
+// prev with the same name reuses prev's signal instead of building a new one.
+func buildSignals(cfgs []config.SignalConfig, env signals.Env, prev []sigEntry) ([]sigEntry, error) {
+	old := make(map[string]sigEntry, len(prev))
+	for _, se := range prev {
+		old[entryName(se.cfg)] = se
+	}
+	var out []sigEntry
+	for _, c := range cfgs {
+		if !c.Enabled {
+			continue
+		}
+		params, err := signals.DecodeParams(c, env)
+		if err != nil {
+			return nil, err
+		}
+		if se, ok := old[entryName(c)]; ok && sameEntry(se, c, params) {
+			out = append(out, se)
+			continue
+		}
+		sig, err := signals.New(c, params)
+		if err != nil {
+			return nil, err
+		}
+		phases := map[session.Phase]bool{}
+		for _, p := range session.DefaultSignalPhases {
+			phases[p] = true
+		}
+		if len(c.Phases) > 0 {
+			phases = map[session.Phase]bool{}
+			for _, raw := range c.Phases {
+				p, ok := session.ParsePhase(raw)
+				if !ok {
+					return nil, fmt.Errorf("signal %s: unknown phase %q (one of %v)", sig.Name(), raw, session.Phases)
+				}
+				phases[p] = true
+			}
+		}
+		var sched schedule.Schedule
+		if strings.TrimSpace(c.Schedule) != "" {
+			sched, err = schedule.Parse(c.Schedule, session.Location)
+			if err != nil {
+				return nil, fmt.Errorf("signal %s: %w", sig.Name(), err)
+			}
+		}
+		out = append(out, sigEntry{
+			sig:     sig,
+			phases:  phases,
+			timeout: time.Duration(c.TimeoutSeconds) * time.Second,
+			sched:   sched,
+			cfg:     c,
+			params:  params,
 		})
 	}
+	return out, nil
+}
 
-	notifs, err := notifier.BuildAll(cfg.Notifiers)
-	if err != nil {
-		return nil, err
+func New(cfg *config.Config) (*Engine, error) {
+	return NewWithDeps(cfg, Deps{})
+}
+
+// NewWithDeps is New with some dependencies supplied by the caller.
+func NewWithDeps(cfg *config.Config, deps Deps) (*Engine, error) {
+	histories := deps.Histories
+	if histories == nil {
+		histories = repo.NewHistories()
+	}
+	env := signals.EnvOf(cfg)
+	env.StateDir = deps.StateDir
+	env.Histories = histories
+
+	clk := clock.Or(deps.Clock)
+
+	// Trade date resolution via trade_cal needs Tushare, as do most signals.
+	client := deps.Client
+	if client == nil && (cfg.Engine.TradeDateMode == "latest_open" || signals.NeedTushare(cfg.Signals, env)) {
+		var err error
+		if client, err = NewTushareClient(cfg, clk); err != nil {
+			return nil, err
+		}
 	}
 
-	sigs, err := signals.BuildAll(cfg.Signals)
+	notifs := deps.Notifiers
+	if notifs == nil {
+		var err error
+		if notifs, err = notifier.BuildAll(cfg.Notifiers, notifier.Env{BaseDir: cfg.Dir}); err != nil {
+			return nil, err
+		}
+	}
+
+	notifier.SetClock(notifs, clk)
+
+	sigs, err := buildSignals(cfg.Signals, env, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	md, err := marketdata.Build(cfg.Marketdata)
+	md := deps.Fusion
+	if md == nil {
+		if md, err = marketdata.Build(cfg.Marketdata); err != nil {
+			return nil, err
+		}
+	}
+	if md != nil {
+		// Repo percentile thresholds learn from every fused repo rate.
+		md = marketdata.NewRepoSampler(md, histories, clk.Now)
+	}
+
+	store, err := state.Build(cfg.Engine)
 	if err != nil {
 		return nil, err
 	}
+	snap, err := store.Load()
+	if err != nil {
+		return nil, fmt.Errorf("load engine state: %w", err)
+	}
+	log.Printf("state loaded store=%s sent=%d cooldowns=%d daily_keys=%d (saved_at=%s)",
+		store.Name(), len(snap.Sent), len(snap.SymbolLast), len(snap.DailySent), snap.SavedAt.Format(time.RFC3339))
+
+	var cal *session.Calendar
+	if cfg.Engine.Session.Enabled {
+		path := cfg.Engine.Session.CalendarCachePath
+		if deps.StateDir != "" {
+			// Start from the live calendar but keep refreshes out of it.
+			cal = session.NewCalendarFrom(client, path, filepath.Join(deps.StateDir, "trade_cal.json"))
+		} else {
+			cal = session.NewCalendar(client, path)
+		}
+	}
 
 	return &Engine{
 		cfg:        cfg,
@@ -66,63 +264,148 @@ func New(cfg *config.Config) (*Engine, error) {
 		md:         md,
 		notifiers:  notifs,
 		sigs:       sigs,
-		sent:       map[string]time.Time{},
-		symbolLast: map[string]time.Time{},
-		lastEval:   map[string]time.Time{},
-		dailySent:  map[string]int{},
+		sent:       snap.Sent,
+		symbolLast: snap.SymbolLast,
+		lastEval:   snap.LastEval,
+		dailySent:  snap.DailySent,
 		recoQuotas: nil,
+		store:      store,
+		costs:      buildCostModel(cfg.Engine.Costs),
+
+		cal:           cal,
+		postCloseDone: snap.PostCloseDone,
+		clock:         clk,
+		stateDir:      deps.StateDir,
+		histories:     histories,
 	}, nil
 }
 
-func (e *Engine) Run() error {
-	ctx := context.Background()
+func buildCostModel(c config.CostsConfig) *costs.Model {
+	if !c.Enabled {
+		return nil
+	}
+	return costs.FromConfig(c)
+}
+
+// NewTushareClient builds the live Tushare client of cfg (token, cache, rate
+// limits, usage accounting) on clk (nil: wall clock). The token env var must
+// be set.
+func NewTushareClient(cfg *config.Config, clk clock.Clock) (*tushare.Client, error) {
+	token, ok := os.LookupEnv(cfg.Tushare.TokenEnv)
+	if !ok || strings.TrimSpace(token) == "" {
+		return nil, fmt.Errorf("missing Tushare token env: %s", cfg.Tushare.TokenEnv)
+	}
+	usage, err := tushare.NewUsage(cfg.Tushare.UsagePath)
+	if err != nil {
+		return nil, fmt.Errorf("load tushare usage: %w", err)
+	}
+	limits := make(map[string]tushare.RateLimit, len(cfg.Tushare.RateLimits))
+	for api, rl := range cfg.Tushare.RateLimits {
+		limits[api] = tushare.RateLimit{PerMinute: rl.PerMinute, Burst: rl.Burst}
+	}
+	return tushare.New(tushare.Options{
+		BaseURL:        cfg.Tushare.BaseURL,
+		Token:          token,
+		TimeoutSeconds: cfg.Tushare.TimeoutSeconds,
+		MaxRetries:     cfg.Engine.MaxAPIRetries,
+		Cache:          buildTushareCache(cfg.Tushare.Cache),
+		RateLimits:     limits,
+		Usage:          usage,
+		Clock:          clk,
+	}), nil
+}
 
-	e.loadRecoIfConfigured()
+func buildTushareCache(c config.TushareCacheConfig) *tushare.Cache {
+	if !c.Enabled {
+		return nil
+	}
+	ttl := make(map[string]time.Duration, len(c.TTLSeconds))
+	for api, sec := range c.TTLSeconds {
+		ttl[api] = time.Duration(sec) * time.Second
+	}
+	return tushare.NewCache(tushare.CacheOptions{Dir: c.Dir, TTL: ttl})
+}
 
-	ticker := time.NewTicker(time.Duration(e.cfg.Engine.IntervalSeconds) * time.Second)
-	defer ticker.Stop()
+// now is the engine's clock: wall time unless a Clock was injected.
+func (e *Engine) now() time.Time {
+	return clock.Or(e.clock).Now()
+}
 
-	for {
-		if err := e.runOnce(ctx); err != nil {
-			log.Printf("runOnce error: %v", err)
+// Close flushes Tushare usage counters, finishes tick recording and releases
+// notifier resources.
+func (e *Engine) Close() error {
+	if c, ok := e.md.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			log.Printf("marketdata close failed: %v", err)
+		}
+	}
+	if u := e.client.Usage(); u != nil {
+		now := e.now()
+		if err := u.Flush(now); err != nil {
+			log.Printf("tushare usage save failed: %v", err)
 		}
-		<-ticker.C
+		log.Printf("tushare_calls today %s", u.FormatDay(now.In(session.Location).Format("20060102")))
+	}
+	if err := e.histories.Flush(e.now()); err != nil {
+		log.Printf("repo history save failed: %v", err)
 	}
+	e.notifyMu.Lock()
+	defer e.notifyMu.Unlock()
+	return notifier.CloseAll(e.notifiers)
 }
 
-func (e *Engine) runOnce(ctx context.Context) error {
-	tradeDate, err := e.resolveTradeDate(ctx)
-	if err != nil {
-		return err
-	}
+// config is the current config; a reload may swap it while lanes run.
+func (e *Engine) config() *config.Config {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.cfg
+}
 
-	var allEvents []notifier.Event
-	now := time.Now()
-	for _, sig := range e.sigs {
-		if minInt := sig.MinInterval(); minInt > 0 {
-			if last, ok := e.lastEval[sig.Name()]; ok && now.Sub(last) < minInt {
-				continue
-			}
-			e.lastEval[sig.Name()] = now
+// process runs one lane batch through the shared policy pipeline
+// (dedupe -> cooldown -> net edge -> run caps -> daily caps) and notifies.
+// Batches are processed one at a time.
+func (e *Engine) process(ctx context.Context, b laneBatch) {
+	tradeDate := b.tradeDate
+	allEvents := b.events
+	stampEvents(allEvents, e.now())
+
+	e.mu.Lock()
+	allEvents = e.applyPolicies(allEvents, b.lane, tradeDate)
+	e.logCacheStats(b.lane)
+	e.mu.Unlock()
+
+	allEvents = e.validateEvents(allEvents)
+	e.notifyMu.Lock()
+	for _, n := range e.notifiers {
+		if len(allEvents) == 0 {
+			break
 		}
-		evs, err := sig.Evaluate(ctx, e.client, tradeDate, e.md)
-		if err != nil {
-			log.Printf("signal %s error: %v", sig.Name(), err)
-			continue
+		if err := n.Notify(ctx, allEvents); err != nil {
+			log.Printf("notifier %s error: %v", n.Name(), err)
 		}
-		allEvents = append(allEvents, evs...)
 	}
+	e.notifyMu.Unlock()
+
+	e.mu.Lock()
+	e.saveState(tradeDate)
+	e.mu.Unlock()
 
+	if err := e.client.Usage().Flush(e.now()); err != nil {
+		log.Printf("tushare usage save failed: %v", err)
+	}
+}
+
+func (e *Engine) applyPolicies(allEvents []notifier.Event, lane, tradeDate string) []notifier.Event {
 	if len(allEvents) == 0 {
-		log.Printf("no events (trade_date=%s)", tradeDate)
+		log.Printf("no events lane=%s (trade_date=%s)", lane, tradeDate)
 		return nil
 	}
 
 	allEvents, dropped := e.applyDedupe(allEvents)
 	if dropped > 0 {
-		log.Printf("events=%d dropped=%d (trade_date=%s)", len(allEvents), dropped, tradeDate)
+		log.Printf("events=%d dropped=%d lane=%s (trade_date=%s)", len(allEvents), dropped, lane, tradeDate)
 	} else {
-		log.Printf("events=%d (trade_date=%s)", len(allEvents), tradeDate)
+		log.Printf("events=%d lane=%s (trade_date=%s)", len(allEvents), lane, tradeDate)
 	}
 
 	allEvents, cdDropped := e.applySymbolCooldown(allEvents)
@@ -145,28 +428,91 @@ func (e *Engine) runOnce(ctx context.Context) error {
 		log.Printf("daily_cap_dropped action=%d observe=%d (trade_date=%s)", dayDroppedA, dayDroppedO, tradeDate)
 	}
 
-	if len(allEvents) == 0 {
+	return allEvents
+}
+
+// logCacheStats logs Tushare cache hits/misses since the previous log line
+// plus the running totals. Caller holds e.mu.
+func (e *Engine) logCacheStats(lane string) {
+	hits, misses := e.client.CacheStats()
+	dh, dm := hits-e.cacheHits, misses-e.cacheMisses
+	if dh == 0 && dm == 0 {
+		return
+	}
+	e.cacheHits, e.cacheMisses = hits, misses
+	log.Printf("tushare_cache hits=%d misses=%d total_hits=%d total_misses=%d lane=%s", dh, dm, hits, misses, lane)
+}
+
+// sessionInfo resolves the exchange session at now for a lane. With engine.session
+// disabled the phase is unknown and every signal runs whenever its lane fires
+// (legacy behaviour). Each lane gets its own once-per-day post-close pass.
+func (e *Engine) sessionInfo(ctx context.Context, now time.Time, lane string) session.Info {
+	info := session.Info{Now: now.In(session.Location)}
+	if e.cal == nil {
+		return info
+	}
+	if err := e.cal.Refresh(ctx, now); err != nil {
+		log.Printf("trade calendar refresh error: %v", err)
+	}
+	info.Phase = e.cal.Phase(now)
+	info.Calendar = e.cal
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if info.Phase == session.PhasePostClose {
+		due := session.CloseTime(now).Add(time.Duration(e.cfg.Engine.Session.PostCloseDelayMinutes) * time.Minute)
+		info.PostClosePass = e.postCloseDone[lane] != info.Now.Format("20060102") && !now.Before(due)
+	}
+	if info.Phase != e.lastPhase {
+		log.Printf("session phase=%s", info.Phase)
+		e.lastPhase = info.Phase
+	}
+	return info
+}
+
+// activeSignals filters a lane's signals by session phase. After the close only
+// the single post-close pass runs.
+func activeSignals(entries []sigEntry, sess session.Info) []sigEntry {
+	if !sess.Known() {
+		return entries
+	}
+	if sess.Phase == session.PhasePostClose && !sess.PostClosePass {
 		return nil
 	}
-	for _, n := range e.notifiers {
-		if err := n.Notify(ctx, allEvents); err != nil {
-			log.Printf("notifier %s error: %v", n.Name(), err)
+	var out []sigEntry
+	for _, se := range entries {
+		if se.phases[sess.Phase] {
+			out = append(out, se)
 		}
 	}
-	return nil
+	return out
 }
 
-func (e *Engine) resolveTradeDate(ctx context.Context) (string, error) {
-	switch e.cfg.Engine.TradeDateMode {
+// resolveTradeDate caches the latest_open answer per exchange date, so lanes
+// ticking every few seconds do not hit trade_cal each time.
+func (e *Engine) resolveTradeDate(ctx context.Context, now time.Time) (string, error) {
+	cfg := e.config()
+	switch cfg.Engine.TradeDateMode {
 	case "fixed":
-		return e.cfg.Engine.FixedTradeDate, nil
+		return cfg.Engine.FixedTradeDate, nil
 	case "latest_open":
 		if e.client == nil {
-			return "", fmt.Errorf("trade_date_mode=latest_open requires Tushare client (set %s)", e.cfg.Tushare.TokenEnv)
+			return "", fmt.Errorf("trade_date_mode=latest_open requires Tushare client (set %s)", cfg.Tushare.TokenEnv)
+		}
+		day := now.In(session.Location).Format("20060102")
+		e.tdMu.Lock()
+		defer e.tdMu.Unlock()
+		if e.tdDay == day && e.tdValue != "" {
+			return e.tdValue, nil
+		}
+		td, err := e.client.LatestOpenTradeDateAt(ctx, now, 45)
+		if err != nil {
+			return "", err
 		}
-		return e.client.LatestOpenTradeDate(ctx, 45)
+		e.tdDay, e.tdValue = day, td
+		return td, nil
 	default:
-		return "", fmt.Errorf("unknown trade_date_mode: %s", e.cfg.Engine.TradeDateMode)
+		return "", fmt.Errorf("unknown trade_date_mode: %s", cfg.Engine.TradeDateMode)
 	}
 }
 
@@ -179,7 +525,7 @@ func (e *Engine) applyDedupe(events []notifier.Event) ([]notifier.Event, int) {
 		return events, 0
 	}
 
-	now := time.Now()
+	now := e.now()
 	cutoff := now.Add(-ttl)
 	for k, t := range e.sent {
 		if t.Before(cutoff) {
@@ -202,7 +548,7 @@ func (e *Engine) applyDedupe(events []notifier.Event) ([]notifier.Event, int) {
 }
 
 func (e *Engine) applySymbolCooldown(events []notifier.Event) ([]notifier.Event, int) {
-	now := time.Now()
+	now := e.now()
 	out := make([]notifier.Event, 0, len(events))
 	dropped := 0
 
@@ -362,6 +708,37 @@ func (e *Engine) applyDailyCaps(events []notifier.Event, tradeDate string) ([]no
 	return out, droppedA, droppedO
 }
 
+// saveState snapshots policy state after a run. Failures are logged, not fatal:
+// losing one snapshot only widens the dedupe/cap window after a restart.
+func (e *Engine) saveState(tradeDate string) {
+	if e.store == nil {
+		return
+	}
+	snap := state.Snapshot{
+		SavedAt:    e.now(),
+		TradeDate:  tradeDate,
+		Sent:       e.sent,
+		SymbolLast: e.symbolLast,
+		LastEval:   e.lastEval,
+		DailySent:  e.dailySent,
+
+		PostCloseDone: e.postCloseDone,
+	}
+	snap.PruneDaily(tradeDate)
+
+	// lastEval only matters within a signal's min interval; drop stale entries.
+	cutoff := snap.SavedAt.Add(-24 * time.Hour)
+	for k, t := range e.lastEval {
+		if t.Before(cutoff) {
+			delete(e.lastEval, k)
+		}
+	}
+
+	if err := e.store.Save(snap); err != nil {
+		log.Printf("state save failed store=%s err=%v", e.store.Name(), err)
+	}
+}
+
 func eventTier(e notifier.Event) string {
 	if e.Tags == nil {
 		return "action"
@@ -389,6 +766,44 @@ func downgradeTier(e notifier.Event, reason string) notifier.Event {
 	return e
 }
 
+// stampEvents gives each event of a batch its ID and emission time. Events
+// that would hash alike (same signal, symbol and title in one run) get a
+// counter suffix so IDs stay unique.
+func stampEvents(events []notifier.Event, now time.Time) {
+	seen := make(map[string]int, len(events))
+	for i := range events {
+		events[i].Stamp(now)
+		id := events[i].ID
+		if n := seen[id]; n > 0 {
+			events[i].ID = fmt.Sprintf("%s_%d", id, n+1)
+		}
+		seen[id]++
+	}
+}
+
+// validateEvents checks events against eventschema right before notify, so
+// fields added by the policies are covered too. Dropped events have already
+// counted against dedupe, cooldowns and caps.
+func (e *Engine) validateEvents(events []notifier.Event) []notifier.Event {
+	mode := e.config().Engine.SchemaValidation
+	if mode == "off" {
+		return events
+	}
+	out := events[:0]
+	for _, ev := range events {
+		if err := eventschema.Validate(ev); err != nil {
+			log.Printf("schema_invalid id=%s source=%s symbol=%s mode=%s err=%v", ev.ID, ev.Source, ev.Symbol, mode, err)
+			if mode == "drop" {
+				continue
+			}
+		}
+		out = append(out, ev)
+	}
+	return out
+}
+
+// eventKey identifies an event's content for dedupe: unlike the ID it does
+// not depend on when the event was emitted.
 func eventKey(e notifier.Event) string {
 	h := sha256.New()
 	_, _ = h.Write([]byte(e.Source))
@@ -0,0 +1,369 @@
+package engine
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"value-sniffer-radar/internal/clock"
+	"value-sniffer-radar/internal/config"
+	"value-sniffer-radar/internal/marketdata"
+	"value-sniffer-radar/internal/notifier"
+	"value-sniffer-radar/internal/schedule"
+	"value-sniffer-radar/internal/session"
+	"value-sniffer-radar/internal/signals"
+	"value-sniffer-radar/internal/tushare"
+)
+
+type closingNotifier struct {
+	closed int
+}
+
+func (n *closingNotifier) Name() string { return "closing" }
+
+func (n *closingNotifier) Notify(context.Context, []notifier.Event) error { return nil }
+
+func (n *closingNotifier) Close() error {
+	n.closed++
+	return nil
+}
+
+func TestRunStopsOnCancelAndClosesNotifiers(t *testing.T) {
+	n := &closingNotifier{}
+	e := &Engine{
+		cfg: &config.Config{
+			Engine: config.EngineConfig{
+				IntervalSeconds:        3600,
+				TradeDateMode:          "fixed",
+				FixedTradeDate:         "20260101",
+				ShutdownTimeoutSeconds: 1,
+			},
+		},
+		notifiers:  []notifier.Notifier{n},
+		sent:       map[string]time.Time{},
+		symbolLast: map[string]time.Time{},
+		lastEval:   map[string]time.Time{},
+		dailySent:  map[string]int{},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- e.Run(ctx) }()
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run err=%v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Run did not return after cancel")
+	}
+	if n.closed != 1 {
+		t.Fatalf("closed=%d want=1", n.closed)
+	}
+}
+
+func TestActiveSignalsBySessionPhase(t *testing.T) {
+	entries, err := buildSignals([]config.SignalConfig{
+		{Type: "cn_repo_sniper", Name: "intraday", Enabled: true},
+		{Type: "cb_premium", Name: "daily", Enabled: true, Phases: []string{"post_close"}},
+	}, signals.Env{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := &Engine{sigs: entries}
+
+	names := func(xs []sigEntry) []string {
+		var out []string
+		for _, x := range xs {
+			out = append(out, x.sig.Name())
+		}
+		return out
+	}
+
+	if got := names(activeSignals(e.sigs, session.Info{})); len(got) != 2 {
+		t.Fatalf("session disabled: got=%v want all", got)
+	}
+	if got := names(activeSignals(e.sigs, session.Info{Phase: session.PhaseMorning})); len(got) != 1 || got[0] != "intraday" {
+		t.Fatalf("morning: got=%v", got)
+	}
+	if got := activeSignals(e.sigs, session.Info{Phase: session.PhaseLunchBreak}); len(got) != 0 {
+		t.Fatalf("lunch: expected skip, got=%v", names(got))
+	}
+	if got := activeSignals(e.sigs, session.Info{Phase: session.PhasePostClose}); len(got) != 0 {
+		t.Fatalf("post_close before pass: expected skip, got=%v", names(got))
+	}
+	if got := names(activeSignals(e.sigs, session.Info{Phase: session.PhasePostClose, PostClosePass: true})); len(got) != 1 || got[0] != "daily" {
+		t.Fatalf("post_close pass: got=%v", got)
+	}
+}
+
+func TestBuildSignalsRejectsUnknownPhaseAndBadSchedule(t *testing.T) {
+	if _, err := buildSignals([]config.SignalConfig{
+		{Type: "cb_premium", Name: "daily", Enabled: true, Phases: []string{"noon"}},
+	}, signals.Env{}, nil); err == nil || !strings.Contains(err.Error(), `unknown phase "noon"`) {
+		t.Fatalf("err=%v", err)
+	}
+	if _, err := buildSignals([]config.SignalConfig{
+		{Type: "cb_premium", Name: "daily", Enabled: true, Schedule: "@every soon"},
+	}, signals.Env{}, nil); err == nil || !strings.Contains(err.Error(), "signal daily") {
+		t.Fatalf("err=%v", err)
+	}
+}
+
+type fakeSignal struct {
+	name      string
+	delay     time.Duration
+	ignoreCtx bool // sleeps the full delay even after ctx is done
+}
+
+func (s fakeSignal) Name() string { return s.name }
+
+func (s fakeSignal) MinInterval() time.Duration { return 0 }
+
+func (s fakeSignal) Evaluate(ctx context.Context, _ *tushare.Client, tradeDate string, _ marketdata.Fusion, _ session.Info) ([]notifier.Event, error) {
+	if s.ignoreCtx {
+		time.Sleep(s.delay)
+	}
+	select {
+	case <-time.After(s.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return []notifier.Event{{Source: s.name, TradeDate: tradeDate, Title: s.name}}, nil
+}
+
+func TestEvaluateSignalsParallelDeterministicOrderAndTimeout(t *testing.T) {
+	e := &Engine{cfg: &config.Config{Engine: config.EngineConfig{MaxParallelSignals: 4}}}
+	due := []sigEntry{
+		{sig: fakeSignal{name: "slow_ok", delay: 80 * time.Millisecond}},
+		{sig: fakeSignal{name: "hung", delay: 10 * time.Second}, timeout: 50 * time.Millisecond},
+		{sig: fakeSignal{name: "fast", delay: 0}},
+	}
+
+	start := time.Now()
+	var evaluating sync.WaitGroup
+	out := e.evaluateSignals(context.Background(), due, "20260101", session.Info{}, &evaluating)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("evaluation not bounded by per-signal timeout: %s", elapsed)
+	}
+	if len(out) != 2 {
+		t.Fatalf("events=%d want=2", len(out))
+	}
+	// Config order, not completion order.
+	if out[0].Source != "slow_ok" || out[1].Source != "fast" {
+		t.Fatalf("order=%s,%s want=slow_ok,fast", out[0].Source, out[1].Source)
+	}
+}
+
+func TestEvaluateSignalsAbandonsSignalIgnoringTimeout(t *testing.T) {
+	e := &Engine{cfg: &config.Config{Engine: config.EngineConfig{MaxParallelSignals: 2}}}
+	due := []sigEntry{
+		{sig: fakeSignal{name: "stuck", delay: 300 * time.Millisecond, ignoreCtx: true}, timeout: 50 * time.Millisecond},
+		{sig: fakeSignal{name: "fast", delay: 0}},
+	}
+
+	var evaluating sync.WaitGroup
+	start := time.Now()
+	out := e.evaluateSignals(context.Background(), due, "20260101", session.Info{}, &evaluating)
+	if elapsed := time.Since(start); elapsed > 250*time.Millisecond {
+		t.Fatalf("stuck signal held up the batch: %s", elapsed)
+	}
+	if len(out) != 1 || out[0].Source != "fast" {
+		t.Fatalf("events=%+v want only fast", out)
+	}
+	// The abandoned call keeps its worker slot until it returns.
+	if n := len(e.workerSlots()); n != 1 {
+		t.Fatalf("slots in use=%d want=1", n)
+	}
+	evaluating.Wait()
+	if n := len(e.workerSlots()); n != 0 {
+		t.Fatalf("slots in use=%d after return", n)
+	}
+
+	// Waiting for a slot gives up on cancel too.
+	e.workerSlots() <- struct{}{}
+	e.workerSlots() <- struct{}{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if out := e.evaluateSignals(ctx, due[1:], "20260101", session.Info{}, &evaluating); len(out) != 0 {
+		t.Fatalf("events=%+v want none after cancel", out)
+	}
+}
+
+// countingSignal ignores ctx and writes a map, like the realtime signals'
+// streaks; overlapping calls would be caught by -race.
+type countingSignal struct {
+	delay time.Duration
+	calls map[string]int
+}
+
+func (s *countingSignal) Name() string { return "counting" }
+
+func (s *countingSignal) MinInterval() time.Duration { return 0 }
+
+func (s *countingSignal) Evaluate(_ context.Context, _ *tushare.Client, tradeDate string, _ marketdata.Fusion, _ session.Info) ([]notifier.Event, error) {
+	time.Sleep(s.delay)
+	s.calls[tradeDate]++
+	return []notifier.Event{{Source: s.Name(), TradeDate: tradeDate, Title: "counting"}}, nil
+}
+
+func TestEvaluateSignalsSkipsSignalStillRunningFromEarlierFiring(t *testing.T) {
+	e := &Engine{cfg: &config.Config{Engine: config.EngineConfig{MaxParallelSignals: 4}}}
+	sig := &countingSignal{delay: 200 * time.Millisecond, calls: map[string]int{}}
+	due := []sigEntry{{sig: sig, timeout: 20 * time.Millisecond}}
+
+	var evaluating sync.WaitGroup
+	if out := e.evaluateSignals(context.Background(), due, "20260101", session.Info{}, &evaluating); len(out) != 0 {
+		t.Fatalf("first firing: events=%+v want timeout", out)
+	}
+	// The second firing comes while the first call is still running.
+	if out := e.evaluateSignals(context.Background(), due, "20260101", session.Info{}, &evaluating); len(out) != 0 {
+		t.Fatalf("second firing: events=%+v want skip", out)
+	}
+	evaluating.Wait()
+	if sig.calls["20260101"] != 1 {
+		t.Fatalf("calls=%v want one", sig.calls)
+	}
+
+	due[0].timeout = time.Second
+	if out := e.evaluateSignals(context.Background(), due, "20260102", session.Info{}, &evaluating); len(out) != 1 {
+		t.Fatalf("after return: events=%+v want one", out)
+	}
+}
+
+func TestBuildLanesSplitsScheduledSignals(t *testing.T) {
+	entries, err := buildSignals([]config.SignalConfig{
+		{Type: "cn_repo_sniper", Name: "a", Enabled: true},
+		{Type: "cn_repo_sniper", Name: "fast", Enabled: true, Schedule: "@every 3s"},
+		{Type: "cb_premium", Name: "b", Enabled: true},
+		{Type: "cb_premium", Name: "daily", Enabled: true, Schedule: "*/15 9-15 * * 1-5"},
+	}, signals.Env{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := &Engine{cfg: &config.Config{Engine: config.EngineConfig{IntervalSeconds: 30}}, sigs: entries}
+
+	lanes := e.buildLanes()
+	if len(lanes) != 3 {
+		t.Fatalf("lanes=%d want=3", len(lanes))
+	}
+	if lanes[0].name != tickLane || len(lanes[0].entries) != 2 {
+		t.Fatalf("tick lane=%s entries=%d", lanes[0].name, len(lanes[0].entries))
+	}
+	if lanes[1].name != "fast" || lanes[2].name != "daily" {
+		t.Fatalf("scheduled lanes=%s,%s", lanes[1].name, lanes[2].name)
+	}
+
+	now := time.Date(2026, 1, 5, 10, 0, 0, 0, session.Location) // Monday
+	if next := lanes[0].sched.Next(now); !next.Equal(now.Add(30 * time.Second)) {
+		t.Fatalf("tick next=%v", next)
+	}
+	if next := lanes[2].sched.Next(now); !next.Equal(now.Add(15 * time.Minute)) {
+		t.Fatalf("daily next=%v", next)
+	}
+}
+
+type clockSignal struct{}
+
+func (clockSignal) Name() string { return "clock" }
+
+func (clockSignal) MinInterval() time.Duration { return 0 }
+
+func (clockSignal) Evaluate(_ context.Context, _ *tushare.Client, tradeDate string, _ marketdata.Fusion, sess session.Info) ([]notifier.Event, error) {
+	return []notifier.Event{{Source: "clock", TradeDate: tradeDate, Title: "same", Data: map[string]interface{}{"at": sess.Now}}}, nil
+}
+
+type recordingNotifier struct {
+	events []notifier.Event
+	closed bool
+}
+
+func (n *recordingNotifier) Name() string { return "recording" }
+
+func (n *recordingNotifier) Notify(_ context.Context, evs []notifier.Event) error {
+	n.events = append(n.events, evs...)
+	return nil
+}
+
+func (n *recordingNotifier) Close() error {
+	n.closed = true
+	return nil
+}
+
+func TestReplayRunsLanesOnSimulatedClock(t *testing.T) {
+	t0 := time.Date(2026, 1, 8, 10, 0, 0, 0, session.Location)
+	sim := clock.NewSim(t0)
+	n := &recordingNotifier{}
+	e := &Engine{
+		cfg: &config.Config{
+			Engine: config.EngineConfig{
+				TradeDateMode:  "fixed",
+				FixedTradeDate: "20260108",
+				DedupeSeconds:  25,
+			},
+		},
+		sigs:       []sigEntry{{sig: clockSignal{}, sched: schedule.Every(10 * time.Second)}},
+		notifiers:  []notifier.Notifier{n},
+		sent:       map[string]time.Time{},
+		symbolLast: map[string]time.Time{},
+		lastEval:   map[string]time.Time{},
+		dailySent:  map[string]int{},
+		clock:      sim,
+	}
+
+	if err := e.Replay(context.Background(), t0, t0.Add(time.Minute), sim); err != nil {
+		t.Fatal(err)
+	}
+	if !n.closed {
+		t.Fatal("notifiers not closed")
+	}
+	// Seven firings 10s apart; dedupe (25s of simulated time) keeps 0s, 30s, 60s.
+	var got []time.Duration
+	for _, ev := range n.events {
+		got = append(got, ev.Data["at"].(time.Time).Sub(t0))
+	}
+	want := []time.Duration{0, 30 * time.Second, 60 * time.Second}
+	if len(got) != len(want) {
+		t.Fatalf("events at %v want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("events at %v want %v", got, want)
+		}
+	}
+}
+
+func TestStampEventsAssignsStableUniqueIDs(t *testing.T) {
+	at := time.Date(2026, 1, 8, 10, 0, 0, 0, session.Location)
+	batch := func() []notifier.Event {
+		return []notifier.Event{
+			{Source: "s", Symbol: "204001.SH", TradeDate: "20260108", Title: "a"},
+			{Source: "s", Symbol: "204001.SH", TradeDate: "20260108", Title: "a"},
+			{Source: "s", Symbol: "131810.SZ", TradeDate: "20260108", Title: "a"},
+		}
+	}
+	a, b := batch(), batch()
+	stampEvents(a, at)
+	stampEvents(b, at)
+
+	seen := map[string]bool{}
+	for i := range a {
+		if a[i].ID == "" || a[i].ID != b[i].ID || !a[i].EmittedAt.Equal(at) {
+			t.Fatalf("event %d: id=%q vs %q emitted_at=%v", i, a[i].ID, b[i].ID, a[i].EmittedAt)
+		}
+		if seen[a[i].ID] {
+			t.Fatalf("duplicate id %s", a[i].ID)
+		}
+		seen[a[i].ID] = true
+	}
+
+	// Restamping keeps the identity.
+	stampEvents(a, at.Add(time.Minute))
+	if a[0].ID != b[0].ID || !a[0].EmittedAt.Equal(at) {
+		t.Fatalf("restamp changed id=%s emitted_at=%v", a[0].ID, a[0].EmittedAt)
+	}
+}
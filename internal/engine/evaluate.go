@@ -0,0 +1,147 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"value-sniffer-radar/internal/notifier"
+	"value-sniffer-radar/internal/session"
+	"value-sniffer-radar/internal/tushare"
+)
+
+type sigResult struct {
+	events  []notifier.Event
+	err     error
+	elapsed time.Duration
+}
+
+// workerSlots is the engine-wide worker pool shared by all lanes.
+func (e *Engine) workerSlots() chan struct{} {
+	e.semOnce.Do(func() {
+		workers := 1
+		if cfg := e.config(); cfg != nil && cfg.Engine.MaxParallelSignals > 0 {
+			workers = cfg.Engine.MaxParallelSignals
+		}
+		e.sem = make(chan struct{}, workers)
+	})
+	return e.sem
+}
+
+// errStillEvaluating skips a signal whose previous Evaluate has not returned.
+var errStillEvaluating = errors.New("previous evaluation still running, skipped")
+
+// evaluateSignals runs due signals on a bounded worker pool and merges their
+// events in configuration order, so the policy pipeline sees the same input
+// regardless of which signal finished first. It returns once every signal
+// finished or timed out; evaluating is done when every Evaluate call it
+// started has actually returned.
+func (e *Engine) evaluateSignals(ctx context.Context, due []sigEntry, tradeDate string, sess session.Info, evaluating *sync.WaitGroup) []notifier.Event {
+	if len(due) == 0 {
+		return nil
+	}
+	results := make([]sigResult, len(due))
+	var wg sync.WaitGroup
+	for i, se := range due {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = e.evaluateOne(ctx, se, tradeDate, sess, evaluating)
+		}()
+	}
+	wg.Wait()
+
+	var out []notifier.Event
+	for i, r := range results {
+		name := due[i].sig.Name()
+		if r.err != nil {
+			if errors.Is(r.err, context.DeadlineExceeded) && due[i].timeout > 0 {
+				log.Printf("signal %s timeout after %s", name, r.elapsed.Round(time.Millisecond))
+			} else {
+				log.Printf("signal %s error: %v", name, r.err)
+			}
+			continue
+		}
+		out = append(out, r.events...)
+	}
+	return out
+}
+
+// evaluateOne runs one signal in a worker slot. Evaluate runs on its own
+// goroutine so a signal that ignores ctx cannot stall the batch past its
+// timeout; it is abandoned instead, but keeps its worker slot and blocks
+// further runs of the signal until it returns, since signals are not safe for
+// concurrent use.
+func (e *Engine) evaluateOne(ctx context.Context, se sigEntry, tradeDate string, sess session.Info, evaluating *sync.WaitGroup) sigResult {
+	name := se.sig.Name()
+	if !e.startEvaluating(name) {
+		return sigResult{err: errStillEvaluating}
+	}
+	sem := e.workerSlots()
+	select {
+	case sem <- struct{}{}:
+	case <-ctx.Done():
+		e.doneEvaluating(name)
+		return sigResult{err: ctx.Err()}
+	}
+
+	start := time.Now()
+	if se.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, se.timeout)
+		defer cancel()
+	}
+	ctx = tushare.WithCaller(ctx, name)
+
+	done := make(chan sigResult, 1)
+	evaluating.Add(1)
+	go func() {
+		defer evaluating.Done()
+		defer e.doneEvaluating(name)
+		defer func() { <-sem }()
+		defer func() {
+			// A panicking signal must not take down the radar loop.
+			if r := recover(); r != nil {
+				done <- sigResult{err: fmt.Errorf("panic: %v", r)}
+			}
+		}()
+		evs, err := se.sig.Evaluate(ctx, e.client, tradeDate, e.md, sess)
+		done <- sigResult{events: evs, err: err}
+	}()
+
+	var res sigResult
+	select {
+	case res = <-done:
+		if res.err == nil && ctx.Err() != nil {
+			// Late results are dropped so a timeout means the same thing for every signal.
+			res = sigResult{err: ctx.Err()}
+		}
+	case <-ctx.Done():
+		res = sigResult{err: ctx.Err()}
+	}
+	res.elapsed = time.Since(start)
+	return res
+}
+
+// startEvaluating marks name as evaluating; false if it already is.
+func (e *Engine) startEvaluating(name string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.evaluating[name] {
+		return false
+	}
+	if e.evaluating == nil {
+		e.evaluating = map[string]bool{}
+	}
+	e.evaluating[name] = true
+	return true
+}
+
+func (e *Engine) doneEvaluating(name string) {
+	e.mu.Lock()
+	delete(e.evaluating, name)
+	e.mu.Unlock()
+}
@@ -0,0 +1,269 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"value-sniffer-radar/internal/clock"
+	"value-sniffer-radar/internal/notifier"
+	"value-sniffer-radar/internal/schedule"
+	"value-sniffer-radar/internal/session"
+)
+
+// tickLane is the shared lane for signals without their own `schedule`;
+// it fires every engine.interval_seconds and honours min_interval_seconds.
+const tickLane = "tick"
+
+// lane is one independent evaluation schedule. Lanes never wait for each other:
+// a slow daily scan in one lane does not delay a 3s realtime lane.
+type lane struct {
+	name    string
+	entries []sigEntry
+	sched   schedule.Schedule
+	next    time.Time
+	running *atomic.Bool // shared by the same-named lane across reloads
+
+	// evaluating counts the Evaluate calls started by this lane's runs that
+	// have not returned, including ones abandoned after a timeout.
+	evaluating sync.WaitGroup
+}
+
+// laneBatch is what a lane hands to the shared policy+notify pipeline.
+type laneBatch struct {
+	lane      string
+	tradeDate string
+	events    []notifier.Event
+}
+
+func (e *Engine) buildLanes() []*lane {
+	var lanes []*lane
+	var shared []sigEntry
+	for _, se := range e.sigs {
+		if se.sched == nil {
+			shared = append(shared, se)
+			continue
+		}
+		lanes = append(lanes, &lane{name: se.sig.Name(), entries: []sigEntry{se}, sched: se.sched, running: new(atomic.Bool)})
+	}
+	if len(shared) > 0 {
+		every := schedule.Every(time.Duration(e.cfg.Engine.IntervalSeconds) * time.Second)
+		lanes = append([]*lane{{name: tickLane, entries: shared, sched: every, running: new(atomic.Bool)}}, lanes...)
+	}
+	return lanes
+}
+
+// Run schedules every lane until ctx is cancelled. On cancellation no new lane
+// runs start; in-flight ones get engine.shutdown_timeout_seconds to finish
+// (including notify) before their context is cancelled too. Notifiers are
+// closed (flushed) before Run returns. With engine.reload_seconds set, config
+// and reco changes are applied between lane firings.
+func (e *Engine) Run(ctx context.Context) error {
+	e.loadRecoIfConfigured()
+	defer func() {
+		if err := e.Close(); err != nil {
+			log.Printf("engine close error: %v", err)
+		}
+	}()
+
+	// runCtx is detached from ctx so a shutdown signal does not abort a notify
+	// half-way; it is only cancelled once the shutdown deadline passes.
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
+	grace := time.Duration(e.cfg.Engine.ShutdownTimeoutSeconds) * time.Second
+	stop := context.AfterFunc(ctx, func() {
+		log.Printf("shutdown: waiting up to %s for in-flight runs", grace)
+		t := time.AfterFunc(grace, cancel)
+		context.AfterFunc(runCtx, func() { t.Stop() })
+	})
+	defer stop()
+
+	lanes := e.buildLanes()
+	for _, l := range lanes {
+		log.Printf("lane %s schedule=%s signals=%d", l.name, l.sched, len(l.entries))
+	}
+
+	reloads := make(chan *reload)
+	if iv := e.cfg.Engine.ReloadSeconds; iv > 0 && e.cfg.Path != "" {
+		go e.watch(ctx, time.Duration(iv)*time.Second, reloads)
+	}
+
+	batches := make(chan laneBatch, len(lanes)+1)
+	pipelineDone := make(chan struct{})
+	go func() {
+		defer close(pipelineDone)
+		for b := range batches {
+			e.process(runCtx, b)
+		}
+	}()
+
+	var inflight sync.WaitGroup
+	for {
+		now := e.now()
+		var wake time.Time
+		for _, l := range lanes {
+			if !now.Before(l.next) {
+				l.next = l.sched.Next(now)
+				if l.running.CompareAndSwap(false, true) {
+					inflight.Add(1)
+					go func(l *lane, at time.Time) {
+						// Shutdown waits for the run only; the lane stays running
+						// until Evaluate calls abandoned after a timeout return too.
+						defer l.running.Store(false)
+						defer l.evaluating.Wait()
+						defer inflight.Done()
+						if b, ok := e.runLane(runCtx, l, at); ok {
+							batches <- b
+						}
+					}(l, now)
+				} else {
+					log.Printf("lane %s still running, skipping tick", l.name)
+				}
+			}
+			if wake.IsZero() || l.next.Before(wake) {
+				wake = l.next
+			}
+		}
+
+		// Without lanes only a reload or shutdown can wake the loop.
+		var timeout <-chan time.Time
+		var t *time.Timer
+		if !wake.IsZero() {
+			t = time.NewTimer(wake.Sub(e.now()))
+			timeout = t.C
+		}
+		stopTimer := func() {
+			if t != nil {
+				t.Stop()
+			}
+		}
+		select {
+		case <-timeout:
+			continue
+		case r := <-reloads:
+			stopTimer()
+			lanes = e.applyReload(r, lanes)
+			continue
+		case <-ctx.Done():
+			stopTimer()
+		}
+		break
+	}
+
+	log.Printf("shutdown requested, stopping radar loop")
+	inflight.Wait()
+	close(batches)
+	<-pipelineDone
+	return nil
+}
+
+// Replay drives every lane over simulated time [start, end] instead of the
+// wall clock: it jumps from one lane firing to the next, moves sim (which must
+// be the engine's Deps.Clock) there and runs the firing and its policy
+// pipeline synchronously. Lanes therefore never overlap and results
+// depend only on the recorded inputs. Notifiers are closed before it returns.
+func (e *Engine) Replay(ctx context.Context, start, end time.Time, sim *clock.Sim) error {
+	e.loadRecoIfConfigured()
+	defer func() {
+		if err := e.Close(); err != nil {
+			log.Printf("engine close error: %v", err)
+		}
+	}()
+
+	lanes := e.buildLanes()
+	for _, l := range lanes {
+		l.next = start
+		log.Printf("lane %s schedule=%s signals=%d", l.name, l.sched, len(l.entries))
+	}
+	if len(lanes) == 0 {
+		return nil
+	}
+
+	runs := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		now := lanes[0].next
+		for _, l := range lanes[1:] {
+			if l.next.Before(now) {
+				now = l.next
+			}
+		}
+		if now.After(end) {
+			break
+		}
+		sim.Set(now)
+		for _, l := range lanes {
+			if now.Before(l.next) {
+				continue
+			}
+			l.next = l.sched.Next(now)
+			if !l.next.After(now) {
+				return fmt.Errorf("lane %s: schedule does not advance past %s", l.name, now.Format(time.RFC3339))
+			}
+			if b, ok := e.runLane(ctx, l, now); ok {
+				e.process(ctx, b)
+				runs++
+			}
+		}
+	}
+	log.Printf("replay done runs=%d from=%s to=%s", runs, start.Format(time.RFC3339), end.Format(time.RFC3339))
+	return nil
+}
+
+// runLane evaluates one firing of a lane and returns the batch for the pipeline.
+func (e *Engine) runLane(ctx context.Context, l *lane, now time.Time) (laneBatch, bool) {
+	sess := e.sessionInfo(ctx, now, l.name)
+	active := activeSignals(l.entries, sess)
+	if len(active) == 0 {
+		return laneBatch{}, false
+	}
+
+	tradeDate, err := e.resolveTradeDate(ctx, now)
+	if err != nil {
+		log.Printf("lane %s error: %v", l.name, err)
+		return laneBatch{}, false
+	}
+
+	if sess.PostClosePass {
+		e.mu.Lock()
+		if e.postCloseDone == nil {
+			e.postCloseDone = map[string]string{}
+		}
+		e.postCloseDone[l.name] = sess.Now.Format("20060102")
+		e.mu.Unlock()
+		log.Printf("post-close pass lane=%s signals=%d (trade_date=%s)", l.name, len(active), tradeDate)
+	}
+
+	due := e.dueSignals(active, now, sess, l.name == tickLane)
+	if len(due) == 0 {
+		return laneBatch{}, false
+	}
+	events := e.evaluateSignals(ctx, due, tradeDate, sess, &l.evaluating)
+	return laneBatch{lane: l.name, tradeDate: tradeDate, events: events}, true
+}
+
+// dueSignals applies min_interval_seconds on the shared tick lane. Scheduled
+// lanes fire exactly on their schedule, and the post-close pass runs once per
+// trade day regardless of min interval.
+func (e *Engine) dueSignals(active []sigEntry, now time.Time, sess session.Info, shared bool) []sigEntry {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var due []sigEntry
+	for _, se := range active {
+		sig := se.sig
+		if minInt := sig.MinInterval(); shared && minInt > 0 && !sess.PostClosePass {
+			if last, ok := e.lastEval[sig.Name()]; ok && now.Sub(last) < minInt {
+				continue
+			}
+		}
+		e.lastEval[sig.Name()] = now
+		due = append(due, se)
+	}
+	return due
+}
@@ -2,33 +2,34 @@ package engine
 
 import (
 	"math"
-	"strconv"
-	"strings"
 
+	"value-sniffer-radar/internal/costs"
+	"value-sniffer-radar/internal/eventschema"
+	"value-sniffer-radar/internal/marketdata"
 	"value-sniffer-radar/internal/notifier"
 )
 
 func (e *Engine) applyNetEdgePolicy(events []notifier.Event) ([]notifier.Event, int) {
-	if e.cfg.Engine.ActionNetEdgeMinPct <= 0 {
-		// Still compute net_edge_pct best-effort for paper log/analysis when possible.
-		out := make([]notifier.Event, 0, len(events))
-		for _, ev := range events {
-			out = append(out, withNetEdge(ev, e))
-		}
-		return out, 0
-	}
-
+	minNet := e.cfg.Engine.ActionNetEdgeMinPct
 	out := make([]notifier.Event, 0, len(events))
 	downgraded := 0
 	for _, ev := range events {
+		// Still compute net_edge_pct best-effort for paper log/analysis when the gate is off.
 		ev2 := withNetEdge(ev, e)
 		if eventTier(ev2) == "action" {
-			net, ok := getFloat(ev2.Data, "net_edge_pct")
-			if !ok {
-				ev2 = downgrade(ev2, "missing_net_edge_pct", e.cfg.Engine.ActionNetEdgeMinPct)
+			var c eventschema.Common
+			_ = eventschema.Decode(ev2.Data, &c)
+			switch {
+			case minNet <= 0:
+			case c.LiquidityOK != nil && !*c.LiquidityOK:
+				// The book can't absorb the trade size the edge was estimated for.
+				ev2 = downgrade(ev2, "insufficient_liquidity", minNet)
 				downgraded++
-			} else if net < e.cfg.Engine.ActionNetEdgeMinPct {
-				ev2 = downgrade(ev2, "net_edge_below_threshold", e.cfg.Engine.ActionNetEdgeMinPct)
+			case c.NetEdgePct == nil:
+				ev2 = downgrade(ev2, "missing_net_edge_pct", minNet)
+				downgraded++
+			case *c.NetEdgePct < minNet:
+				ev2 = downgrade(ev2, "net_edge_below_threshold", minNet)
 				downgraded++
 			}
 		}
@@ -40,9 +41,16 @@ func (e *Engine) applyNetEdgePolicy(events []notifier.Event) ([]notifier.Event,
 func withNetEdge(ev notifier.Event, e *Engine) notifier.Event {
 	ev = ensureMaps(ev)
 
+	var c eventschema.Common
+	if err := eventschema.Decode(ev.Data, &c); err != nil {
+		// Mistyped cost fields; the schema check reports which.
+		ev.Data["net_edge_pct"] = 0.0
+		ev.Data["net_edge_reason"] = "invalid_data"
+		return ev
+	}
+
 	// expected_edge_pct (required for meaningful net edge)
-	expected, okExpected := getFloat(ev.Data, "expected_edge_pct")
-	if !okExpected {
+	if c.ExpectedEdgePct == nil {
 		// Do not invent expected edge; record and return.
 		if _, ok := ev.Data["net_edge_pct"]; !ok {
 			ev.Data["net_edge_pct"] = 0.0
@@ -50,24 +58,38 @@ func withNetEdge(ev notifier.Event, e *Engine) notifier.Event {
 		}
 		return ev
 	}
+	expected := *c.ExpectedEdgePct
 
-	spread, okSpread := getFloat(ev.Data, "spread_pct")
-	if !okSpread {
-		spread = e.cfg.Engine.DefaultSpreadPct
+	b := costs.Breakdown{Market: ev.Market, SpreadSource: "signal", SlippageSource: "signal", FeeSource: "signal"}
+	spread := e.cfg.Engine.DefaultSpreadPct
+	if c.SpreadPct != nil {
+		spread = *c.SpreadPct
+	} else {
+		b.SpreadSource = "default"
 	}
-	slippage, okSlip := getFloat(ev.Data, "slippage_pct")
-	if !okSlip {
-		slippage = e.cfg.Engine.DefaultSlippagePct
+	slippage := e.cfg.Engine.DefaultSlippagePct
+	if c.SlippagePct != nil {
+		slippage = *c.SlippagePct
+	} else {
+		b.SlippageSource = "default"
 	}
-	fee, okFee := getFloat(ev.Data, "fee_pct")
-	if !okFee {
+	var fee float64
+	if c.FeePct != nil {
+		fee = *c.FeePct
+	} else if sched, ok := e.scheduledFees(ev, c.Legs); ok {
+		b.Legs = sched.Legs
+		fee = sched.FeePct
+		b.FeeSource = "schedule"
+	} else {
 		fee = e.cfg.Engine.DefaultFeePct
 		if e.cfg.Engine.FeePctByMarket != nil {
 			if v, ok := e.cfg.Engine.FeePctByMarket[ev.Market]; ok {
 				fee = v
 			}
 		}
+		b.FeeSource = "default"
 	}
+	b.SpreadPct, b.SlippagePct, b.FeePct = spread, slippage, fee
 
 	net := expected - spread - slippage - fee
 	if math.IsNaN(net) || math.IsInf(net, 0) {
@@ -79,9 +101,27 @@ func withNetEdge(ev notifier.Event, e *Engine) notifier.Event {
 	ev.Data["slippage_pct"] = slippage
 	ev.Data["fee_pct"] = fee
 	ev.Data["net_edge_pct"] = net
+	ev.Data["cost_breakdown"] = b
 	return ev
 }
 
+// scheduledFees prices the event's legs with the engine.costs fee schedule.
+// Signals without legs are treated as one order of the configured notional:
+// lending for repos, buying otherwise.
+func (e *Engine) scheduledFees(ev notifier.Event, legs []costs.Leg) (costs.Breakdown, bool) {
+	if e.costs == nil {
+		return costs.Breakdown{}, false
+	}
+	if len(legs) == 0 {
+		side := marketdata.Buy
+		if marketdata.ClassOf(ev.Symbol) == marketdata.ClassRepo {
+			side = marketdata.Sell
+		}
+		legs = []costs.Leg{{Symbol: ev.Symbol, Side: side, Notional: e.costs.Notional(ev.Symbol)}}
+	}
+	return e.costs.Fees(ev.Market, legs)
+}
+
 func downgrade(ev notifier.Event, reason string, minNetEdge float64) notifier.Event {
 	ev = ensureMaps(ev)
 	ev.Tags["tier"] = "observe"
@@ -100,32 +140,3 @@ func ensureMaps(ev notifier.Event) notifier.Event {
 	}
 	return ev
 }
-
-func getFloat(m map[string]interface{}, key string) (float64, bool) {
-	if m == nil {
-		return 0, false
-	}
-	v, ok := m[key]
-	if !ok {
-		return 0, false
-	}
-	switch t := v.(type) {
-	case float64:
-		return t, true
-	case float32:
-		return float64(t), true
-	case int:
-		return float64(t), true
-	case int64:
-		return float64(t), true
-	case string:
-		s := strings.TrimSpace(t)
-		if s == "" {
-			return 0, false
-		}
-		f, err := strconv.ParseFloat(s, 64)
-		return f, err == nil
-	default:
-		return 0, false
-	}
-}
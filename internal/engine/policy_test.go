@@ -2,9 +2,15 @@ package engine
 
 import (
 	"testing"
+	"time"
 
+	"value-sniffer-radar/internal/clock"
 	"value-sniffer-radar/internal/config"
+	"value-sniffer-radar/internal/costs"
+	"value-sniffer-radar/internal/marketdata"
 	"value-sniffer-radar/internal/notifier"
+	"value-sniffer-radar/internal/session"
+	"value-sniffer-radar/internal/state"
 )
 
 func TestNetEdgePolicy_DowngradesActionBelowThreshold(t *testing.T) {
@@ -51,6 +57,77 @@ func TestNetEdgePolicy_DowngradesActionBelowThreshold(t *testing.T) {
 	}
 }
 
+func TestNetEdgePolicy_UsesSignalSpreadAndLiquidity(t *testing.T) {
+	e := &Engine{
+		cfg: &config.Config{
+			Engine: config.EngineConfig{
+				ActionNetEdgeMinPct: 0.05,
+				DefaultSpreadPct:    1.0, // must not be used when the signal reports spread_pct
+			},
+		},
+		dailySent: map[string]int{},
+	}
+	ev := func(liquid bool) notifier.Event {
+		return notifier.Event{
+			Source: "sig", Symbol: "X", Tags: map[string]string{"tier": "action"},
+			Data: map[string]interface{}{"expected_edge_pct": 0.5, "spread_pct": 0.1, "slippage_pct": 0.05, "liquidity_ok": liquid},
+		}
+	}
+
+	out, downgraded := e.applyNetEdgePolicy([]notifier.Event{ev(true), ev(false)})
+	if downgraded != 1 {
+		t.Fatalf("downgraded=%d want=1", downgraded)
+	}
+	if got := out[0].Data["net_edge_pct"].(float64); got < 0.349 || got > 0.351 {
+		t.Fatalf("net_edge_pct=%v want=0.35", got)
+	}
+	if eventTier(out[0]) != "action" {
+		t.Fatalf("liquid event downgraded: %v", out[0].Data)
+	}
+	if eventTier(out[1]) != "observe" || out[1].Data["policy_downgrade_reason"] != "insufficient_liquidity" {
+		t.Fatalf("illiquid event: tier=%s data=%v", eventTier(out[1]), out[1].Data)
+	}
+
+	// With the gate off nothing is downgraded, liquidity included.
+	e.cfg.Engine.ActionNetEdgeMinPct = 0
+	if out, downgraded := e.applyNetEdgePolicy([]notifier.Event{ev(false)}); downgraded != 0 || eventTier(out[0]) != "action" {
+		t.Fatalf("gate off: downgraded=%d data=%v", downgraded, out[0].Data)
+	}
+}
+
+func TestNetEdgePolicy_FeesFromCostSchedule(t *testing.T) {
+	e := &Engine{
+		cfg: &config.Config{
+			Engine: config.EngineConfig{ActionNetEdgeMinPct: 0.05, DefaultFeePct: 5},
+		},
+		costs:     costs.New(costs.Options{}),
+		dailySent: map[string]int{},
+	}
+	legs := []costs.Leg{
+		{Symbol: "113050.SH", Side: marketdata.Buy, Notional: 100000},
+		{Symbol: "601000.SH", Side: marketdata.Sell, Notional: 100000},
+	}
+	ev := notifier.Event{
+		Source: "sig", Market: "CN-A", Symbol: "113050.SH", Tags: map[string]string{"tier": "action"},
+		Data: map[string]interface{}{"expected_edge_pct": 0.5, "spread_pct": 0.1, "slippage_pct": 0.0, "legs": legs},
+	}
+
+	out, downgraded := e.applyNetEdgePolicy([]notifier.Event{ev})
+	if downgraded != 0 {
+		t.Fatalf("downgraded=%d data=%v", downgraded, out[0].Data)
+	}
+	// cb buy: 0.01% + 0.001%; stock sell: 0.025% + 0.05% stamp duty + 0.00441%.
+	wantFee := 0.011 + 0.07941
+	fee := out[0].Data["fee_pct"].(float64)
+	if fee < wantFee-1e-9 || fee > wantFee+1e-9 {
+		t.Fatalf("fee_pct=%v want=%v", fee, wantFee)
+	}
+	b, ok := out[0].Data["cost_breakdown"].(costs.Breakdown)
+	if !ok || b.FeeSource != "schedule" || b.SpreadSource != "signal" || len(b.Legs) != 2 || b.Legs[1].StampDuty != 50 {
+		t.Fatalf("cost_breakdown=%+v", out[0].Data["cost_breakdown"])
+	}
+}
+
 func TestDailyCaps_PerSignalAndGlobalActionCaps(t *testing.T) {
 	e := &Engine{
 		cfg: &config.Config{
@@ -100,6 +177,39 @@ func TestDailyCaps_PerSignalAndGlobalActionCaps(t *testing.T) {
 	}
 }
 
+func TestSymbolCooldown_FollowsEngineClock(t *testing.T) {
+	sim := clock.NewSim(time.Date(2026, 1, 8, 10, 0, 0, 0, session.Location))
+	e := &Engine{
+		cfg: &config.Config{
+			Engine: config.EngineConfig{
+				ActionSymbolCooldownSeconds:  60,
+				ObserveSymbolCooldownSeconds: 600,
+			},
+		},
+		symbolLast: map[string]time.Time{},
+		clock:      sim,
+	}
+	ev := []notifier.Event{{Source: "sig", Symbol: "204001.SH", Title: "t"}}
+
+	for _, c := range []struct {
+		advance time.Duration
+		pass    bool
+	}{
+		{0, true},
+		{30 * time.Second, false},
+		{30 * time.Second, false}, // 60s: the cooldown window is inclusive
+		{time.Second, true},
+		{59 * time.Second, false},
+		{2 * time.Second, true},
+	} {
+		now := sim.Advance(c.advance)
+		out, _ := e.applySymbolCooldown(ev)
+		if (len(out) == 1) != c.pass {
+			t.Fatalf("at %s: pass=%v want=%v", now.Format("15:04:05"), len(out) == 1, c.pass)
+		}
+	}
+}
+
 func TestDailyCaps_ActionCapDowngradesToObserve(t *testing.T) {
 	e := &Engine{
 		cfg: &config.Config{
@@ -131,3 +241,81 @@ func TestDailyCaps_ActionCapDowngradesToObserve(t *testing.T) {
 		t.Fatalf("action=%d observe=%d want action=5 observe=5", action, observe)
 	}
 }
+
+func TestDailyCaps_SurviveRestartViaStateStore(t *testing.T) {
+	cfg := &config.Config{
+		Engine: config.EngineConfig{
+			ActionMaxEventsPerDay:  3,
+			ObserveMaxEventsPerDay: 1000,
+		},
+	}
+	store := state.NewMemoryStore()
+
+	newEngine := func() *Engine {
+		snap, err := store.Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &Engine{
+			cfg:        cfg,
+			sent:       snap.Sent,
+			symbolLast: snap.SymbolLast,
+			lastEval:   snap.LastEval,
+			dailySent:  snap.DailySent,
+			store:      store,
+		}
+	}
+
+	e1 := newEngine()
+	out, _, _ := e1.applyDailyCaps([]notifier.Event{{Source: "sig"}, {Source: "sig"}}, "20260101")
+	if len(out) != 2 {
+		t.Fatalf("out=%d", len(out))
+	}
+	e1.saveState("20260101")
+
+	// Restart: only one action slot should remain for the day.
+	e2 := newEngine()
+	out, _, _ = e2.applyDailyCaps([]notifier.Event{{Source: "sig"}, {Source: "sig"}}, "20260101")
+	action := 0
+	for _, ev := range out {
+		if eventTier(ev) == "action" {
+			action++
+		}
+	}
+	if action != 1 {
+		t.Fatalf("action=%d want=1 after restart", action)
+	}
+
+	// A new trade date starts a fresh budget.
+	e3 := newEngine()
+	out, _, _ = e3.applyDailyCaps([]notifier.Event{{Source: "sig"}, {Source: "sig"}}, "20260102")
+	for _, ev := range out {
+		if eventTier(ev) != "action" {
+			t.Fatalf("expected fresh action budget on new trade_date")
+		}
+	}
+}
+
+func TestValidateEvents_DropModeWithholdsInvalid(t *testing.T) {
+	at := time.Date(2026, 1, 8, 10, 0, 0, 0, session.Location)
+	batch := func() []notifier.Event {
+		evs := []notifier.Event{
+			{Source: "ok", TradeDate: "20260108", Symbol: "204001.SH", Title: "t",
+				Tags: map[string]string{"kind": "repo", "tier": "action"},
+				Data: map[string]interface{}{"expected_edge_pct": 0.2, "rate_pct": 2.2, "tenor_days": 1}},
+			{Source: "bad", TradeDate: "20260108", Symbol: "204001.SH", Title: "t",
+				Tags: map[string]string{"kind": "repo"},
+				Data: map[string]interface{}{"expected_edge_pct": "0.2"}},
+		}
+		stampEvents(evs, at)
+		return evs
+	}
+
+	for mode, want := range map[string]int{"drop": 1, "warn": 2, "off": 2} {
+		e := &Engine{cfg: &config.Config{Engine: config.EngineConfig{SchemaValidation: mode}}}
+		out := e.validateEvents(batch())
+		if len(out) != want || out[0].Source != "ok" {
+			t.Fatalf("mode=%s events=%d want=%d", mode, len(out), want)
+		}
+	}
+}
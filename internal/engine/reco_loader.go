@@ -1,24 +1,66 @@
 package engine
 
 import (
+	"fmt"
 	"log"
+	"sort"
+	"strings"
 
 	"value-sniffer-radar/internal/reco"
 )
 
 func (e *Engine) loadRecoIfConfigured() {
 	path := ""
-	if e.cfg != nil {
-		path = e.cfg.Engine.RecoPath
+	if cfg := e.config(); cfg != nil {
+		path = cfg.Engine.RecoPath
 	}
 	if path == "" {
 		return
 	}
-	r, err := reco.Read(path)
+	m, r, err := readRecoQuotas(path)
 	if err != nil {
 		log.Printf("reco load failed path=%s err=%v", path, err)
 		return
 	}
+	if len(m) == 0 {
+		log.Printf("reco loaded path=%s but no quotas found", path)
+		return
+	}
+	e.mu.Lock()
+	e.recoQuotas = m
+	e.mu.Unlock()
+	log.Printf("reco loaded path=%s quotas=%d window_sec=%d", path, len(m), r.PrimaryWindowSec)
+}
+
+// reloadReco swaps the quota overrides for those in path (none when path is
+// empty, i.e. reco_path was removed) and logs the per-signal changes. A file
+// that cannot be read keeps the current overrides.
+func (e *Engine) reloadReco(path string) {
+	var m map[string]int
+	if path != "" {
+		var err error
+		if m, _, err = readRecoQuotas(path); err != nil {
+			log.Printf("reco reload rejected path=%s err=%v", path, err)
+			return
+		}
+		if len(m) == 0 {
+			m = nil
+		}
+	}
+	e.mu.Lock()
+	prev := e.recoQuotas
+	e.recoQuotas = m
+	e.mu.Unlock()
+	if changes := diffQuotas(prev, m); len(changes) > 0 {
+		log.Printf("reco reloaded path=%s quotas=%d changed: %s", path, len(m), strings.Join(changes, ", "))
+	}
+}
+
+func readRecoQuotas(path string) (map[string]int, reco.Recommendation, error) {
+	r, err := reco.Read(path)
+	if err != nil {
+		return nil, r, err
+	}
 	m := map[string]int{}
 	for _, q := range r.Quotas {
 		if q.Signal == "" || q.SuggestedDailyQuota <= 0 {
@@ -26,10 +68,31 @@ func (e *Engine) loadRecoIfConfigured() {
 		}
 		m[q.Signal] = q.SuggestedDailyQuota
 	}
-	if len(m) == 0 {
-		log.Printf("reco loaded path=%s but no quotas found", path)
-		return
+	return m, r, nil
+}
+
+// diffQuotas lists "signal: old -> new" for every quota that differs; "-"
+// stands for no override.
+func diffQuotas(prev, next map[string]int) []string {
+	names := map[string]bool{}
+	for s := range prev {
+		names[s] = true
 	}
-	e.recoQuotas = m
-	log.Printf("reco loaded path=%s quotas=%d window_sec=%d", path, len(m), r.PrimaryWindowSec)
+	for s := range next {
+		names[s] = true
+	}
+	show := func(m map[string]int, s string) string {
+		if q, ok := m[s]; ok {
+			return fmt.Sprint(q)
+		}
+		return "-"
+	}
+	var out []string
+	for s := range names {
+		if a, b := show(prev, s), show(next, s); a != b {
+			out = append(out, fmt.Sprintf("%s: %s -> %s", s, a, b))
+		}
+	}
+	sort.Strings(out)
+	return out
 }
@@ -2,6 +2,7 @@ package engine
 
 import (
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"value-sniffer-radar/internal/config"
@@ -40,3 +41,35 @@ func TestEngineLoadRecoOverridesPerSignalQuotas(t *testing.T) {
 		t.Fatalf("expected recoQuotas override, got=%v", e.recoQuotas)
 	}
 }
+
+func TestEngineReloadRecoSwapsQuotas(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "optimizer.reco.json")
+	if err := reco.Write(path, reco.Recommendation{
+		Version: "reco.v1",
+		Quotas: []reco.SignalQuota{
+			{Signal: "sigA", SuggestedDailyQuota: 5},
+			{Signal: "sigB", SuggestedDailyQuota: 1},
+		},
+	}); err != nil {
+		t.Fatalf("write reco err=%v", err)
+	}
+
+	e := &Engine{recoQuotas: map[string]int{"sigA": 2, "sigC": 3}}
+	e.reloadReco(filepath.Join(filepath.Dir(path), "missing.json"))
+	if e.recoQuotas["sigA"] != 2 {
+		t.Fatalf("unreadable reco must keep quotas, got=%v", e.recoQuotas)
+	}
+	e.reloadReco(path)
+	if len(e.recoQuotas) != 2 || e.recoQuotas["sigA"] != 5 || e.recoQuotas["sigB"] != 1 {
+		t.Fatalf("quotas=%v", e.recoQuotas)
+	}
+	e.reloadReco("")
+	if e.recoQuotas != nil {
+		t.Fatalf("removed reco_path must clear quotas, got=%v", e.recoQuotas)
+	}
+
+	got := diffQuotas(map[string]int{"sigA": 2, "sigC": 3}, map[string]int{"sigA": 5, "sigB": 1})
+	if want := "sigA: 2 -> 5,sigB: - -> 1,sigC: 3 -> -"; strings.Join(got, ",") != want {
+		t.Fatalf("diff=%v want %s", got, want)
+	}
+}
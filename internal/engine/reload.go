@@ -0,0 +1,311 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"value-sniffer-radar/internal/clock"
+	"value-sniffer-radar/internal/config"
+	"value-sniffer-radar/internal/costs"
+	"value-sniffer-radar/internal/notifier"
+	"value-sniffer-radar/internal/signals"
+)
+
+// reload is a validated config change, built off the scheduler loop and
+// applied by Run between lane firings.
+type reload struct {
+	cfg       *config.Config
+	sigs      []sigEntry
+	costs     *costs.Model
+	notifiers []notifier.Notifier // nil: unchanged
+	changes   []string            // what differs from the running config, for the log
+}
+
+// restartOnly are the settings the engine built its clients, stores and
+// scheduler loop from. A reload keeps their running values and says so.
+var restartOnly = []struct {
+	key   string
+	field func(*config.Config) any // pointer to the setting
+}{
+	{"tushare", func(c *config.Config) any { return &c.Tushare }},
+	{"marketdata", func(c *config.Config) any { return &c.Marketdata }},
+	{"engine.max_api_retries", func(c *config.Config) any { return &c.Engine.MaxAPIRetries }},
+	{"engine.max_parallel_signals", func(c *config.Config) any { return &c.Engine.MaxParallelSignals }},
+	{"engine.shutdown_timeout_seconds", func(c *config.Config) any { return &c.Engine.ShutdownTimeoutSeconds }},
+	{"engine.session", func(c *config.Config) any { return &c.Engine.Session }},
+	{"engine.state_store", func(c *config.Config) any { return &c.Engine.StateStore }},
+	{"engine.state_path", func(c *config.Config) any { return &c.Engine.StatePath }},
+	{"engine.reload_seconds", func(c *config.Config) any { return &c.Engine.ReloadSeconds }},
+}
+
+// watch polls the config file and engine.reco_path every interval until ctx
+// is done. A changed config is validated and built by prepareReload and sent
+// to Run; a rejected one leaves the running config in place. Reco changes
+// only touch quota overrides and are applied here.
+func (e *Engine) watch(ctx context.Context, interval time.Duration, out chan<- *reload) {
+	cfgPath := e.config().Path
+	cfgStamp := statFile(cfgPath)
+	recoPath := e.config().Engine.RecoPath
+	recoStamp := statFile(recoPath)
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		if s := statFile(cfgPath); s != cfgStamp {
+			cfgStamp = s
+			r, err := e.prepareReload(cfgPath)
+			switch {
+			case err != nil:
+				log.Printf("config reload rejected path=%s err=%v", cfgPath, err)
+			case r == nil:
+				log.Printf("config reload path=%s: nothing changed", cfgPath)
+			default:
+				select {
+				case out <- r:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+		// Read after a possible reload: reco_path itself may have changed.
+		path := e.config().Engine.RecoPath
+		if s := statFile(path); path != recoPath || s != recoStamp {
+			recoPath, recoStamp = path, s
+			e.reloadReco(path)
+		}
+	}
+}
+
+// fileStamp changes whenever a file is rewritten, replaced or removed.
+type fileStamp struct {
+	mod  time.Time
+	size int64
+}
+
+func statFile(path string) fileStamp {
+	if path == "" {
+		return fileStamp{}
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{mod: fi.ModTime(), size: fi.Size()}
+}
+
+// prepareReload loads path (config.Load normalizes and validates it), builds
+// its signals and notifiers and diffs it against the running config. Signals
+// whose entry did not change are reused with their state; notifiers are only
+// rebuilt when their entries changed. It returns nil when nothing would change.
+func (e *Engine) prepareReload(path string) (*reload, error) {
+	next, err := config.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	e.mu.Lock()
+	cur, curSigs := e.cfg, e.sigs
+	e.mu.Unlock()
+
+	var changes []string
+	if pinned := pinRestartOnly(cur, next); len(pinned) > 0 {
+		changes = append(changes, "restart required to apply: "+strings.Join(pinned, ", "))
+	}
+
+	env := signals.EnvOf(next)
+	env.StateDir = e.stateDir
+	env.Histories = e.histories
+	if e.client == nil && (next.Engine.TradeDateMode == "latest_open" || signals.NeedTushare(next.Signals, env)) {
+		return nil, errors.New("config needs Tushare but the radar started without a client; restart to apply")
+	}
+	sigs, err := buildSignals(next.Signals, env, curSigs)
+	if err != nil {
+		return nil, err
+	}
+	changes = append(changes, diffFields("engine", reflect.ValueOf(cur.Engine), reflect.ValueOf(next.Engine))...)
+	changes = append(changes, diffSignals(curSigs, sigs)...)
+
+	r := &reload{cfg: next, sigs: sigs, costs: buildCostModel(next.Engine.Costs)}
+	nenv := notifier.Env{BaseDir: next.Dir}
+	same, err := sameNotifiers(cur.Notifiers, notifier.Env{BaseDir: cur.Dir}, next.Notifiers, nenv)
+	if err != nil {
+		return nil, err
+	}
+	if !same {
+		if r.notifiers, err = notifier.BuildAll(next.Notifiers, nenv); err != nil {
+			return nil, err
+		}
+		notifier.SetClock(r.notifiers, clock.Or(e.clock))
+		changes = append(changes, fmt.Sprintf("notifiers: %s -> %s", notifierTypes(cur.Notifiers), notifierTypes(next.Notifiers)))
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	r.changes = changes
+	return r, nil
+}
+
+// applyReload swaps in r and rebuilds the lanes. It runs on the Run loop, so
+// no lane is being scheduled meanwhile; in-flight runs finish with the
+// signals they started with. Same-named lanes keep their running flag (no
+// overlapping runs) and, with an unchanged schedule, their next firing.
+func (e *Engine) applyReload(r *reload, old []*lane) []*lane {
+	e.mu.Lock()
+	e.cfg, e.sigs, e.costs = r.cfg, r.sigs, r.costs
+	e.mu.Unlock()
+
+	// Signals dropped or switched to another history_path stop feeding theirs.
+	if err := e.histories.Retain(historyPaths(r.sigs), e.now()); err != nil {
+		log.Printf("config reload: %v", err)
+	}
+
+	if r.notifiers != nil {
+		e.notifyMu.Lock()
+		prev := e.notifiers
+		e.notifiers = r.notifiers
+		e.notifyMu.Unlock()
+		if err := notifier.CloseAll(prev); err != nil {
+			log.Printf("config reload: %v", err)
+		}
+	}
+
+	prev := make(map[string]*lane, len(old))
+	for _, l := range old {
+		prev[l.name] = l
+	}
+	lanes := e.buildLanes()
+	for _, l := range lanes {
+		if p, ok := prev[l.name]; ok {
+			l.running = p.running
+			if p.sched.String() == l.sched.String() {
+				l.next = p.next
+			}
+		}
+		log.Printf("lane %s schedule=%s signals=%d", l.name, l.sched, len(l.entries))
+	}
+	for _, c := range r.changes {
+		log.Printf("config reload: %s", c)
+	}
+	log.Printf("config reloaded path=%s signals=%d lanes=%d", r.cfg.Path, len(r.sigs), len(lanes))
+	return lanes
+}
+
+// pinRestartOnly copies the restartOnly settings of cur into next and returns
+// the keys whose value next wanted to change.
+func pinRestartOnly(cur, next *config.Config) []string {
+	var pinned []string
+	for _, s := range restartOnly {
+		was, now := reflect.ValueOf(s.field(cur)).Elem(), reflect.ValueOf(s.field(next)).Elem()
+		if !reflect.DeepEqual(was.Interface(), now.Interface()) {
+			pinned = append(pinned, s.key)
+			now.Set(was)
+		}
+	}
+	return pinned
+}
+
+// diffFields lists "key: old -> new" for every yaml field under prefix that
+// differs between the structs a and b.
+func diffFields(prefix string, a, b reflect.Value) []string {
+	if a.Kind() != reflect.Struct {
+		if reflect.DeepEqual(a.Interface(), b.Interface()) {
+			return nil
+		}
+		return []string{fmt.Sprintf("%s: %v -> %v", prefix, a.Interface(), b.Interface())}
+	}
+	var out []string
+	for i := 0; i < a.NumField(); i++ {
+		f := a.Type().Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if !f.IsExported() || name == "" || name == "-" {
+			continue
+		}
+		out = append(out, diffFields(prefix+"."+name, a.Field(i), b.Field(i))...)
+	}
+	return out
+}
+
+// diffSignals summarizes how the enabled signals changed by name: added,
+// removed, or rebuilt because their entry changed. Reused ones are counted.
+func diffSignals(prev, next []sigEntry) []string {
+	old := make(map[string]sigEntry, len(prev))
+	for _, se := range prev {
+		old[entryName(se.cfg)] = se
+	}
+	var added, rebuilt, removed []string
+	kept := 0
+	for _, se := range next {
+		name := entryName(se.cfg)
+		p, ok := old[name]
+		delete(old, name)
+		switch {
+		case !ok:
+			added = append(added, name)
+		case sameEntry(p, se.cfg, se.params):
+			kept++
+		default:
+			rebuilt = append(rebuilt, name)
+		}
+	}
+	for name := range old {
+		removed = append(removed, name)
+	}
+	sort.Strings(removed)
+	if len(added)+len(rebuilt)+len(removed) == 0 {
+		return nil
+	}
+	var out []string
+	for _, g := range []struct {
+		what  string
+		names []string
+	}{{"added", added}, {"removed", removed}, {"rebuilt", rebuilt}} {
+		if len(g.names) > 0 {
+			out = append(out, fmt.Sprintf("signals %s: %s", g.what, strings.Join(g.names, ", ")))
+		}
+	}
+	return append(out, fmt.Sprintf("signals kept: %d", kept))
+}
+
+// sameNotifiers reports whether two notifier lists decode to the same types
+// and params, in order.
+func sameNotifiers(a []config.NotifierConfig, aenv notifier.Env, b []config.NotifierConfig, benv notifier.Env) (bool, error) {
+	if len(a) != len(b) {
+		return false, nil
+	}
+	for i := range a {
+		if a[i].Type != b[i].Type {
+			return false, nil
+		}
+		pa, err := notifier.DecodeParams(a[i], aenv)
+		if err != nil {
+			return false, err
+		}
+		pb, err := notifier.DecodeParams(b[i], benv)
+		if err != nil {
+			return false, err
+		}
+		if !reflect.DeepEqual(pa, pb) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func notifierTypes(cfgs []config.NotifierConfig) string {
+	types := make([]string, len(cfgs))
+	for i, c := range cfgs {
+		types[i] = c.Type
+	}
+	return "[" + strings.Join(types, ", ") + "]"
+}
@@ -0,0 +1,176 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"value-sniffer-radar/internal/config"
+	"value-sniffer-radar/internal/repo"
+	"value-sniffer-radar/internal/session"
+)
+
+const reloadConfigV1 = `
+tushare:
+  timeout_seconds: 20
+engine:
+  trade_date_mode: fixed
+  fixed_trade_date: "20260106"
+  state_store: memory
+  dedupe_seconds: 600
+  reload_seconds: 1
+notifiers:
+  - type: stdout
+signals:
+  - type: custom_expr
+    name: a
+    enabled: true
+    params: {kind: repo, datasets: [{marketdata: ["204001.SH"]}], filter: yield_pct > 3, event: {data: {expected_edge_pct: yield_pct - 3}}}
+  - type: custom_expr
+    name: b
+    enabled: true
+    schedule: "@every 3s"
+    params: {kind: repo, datasets: [{marketdata: ["204001.SH"]}], filter: yield_pct > 2.5, event: {data: {expected_edge_pct: yield_pct - 2.5}}}
+`
+
+func writeConfig(t *testing.T, path, src string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReloadKeepsUnchangedSignalsAndDiffs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, reloadConfigV1)
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldLanes := e.buildLanes()
+	oldA, oldB := e.sigs[0], e.sigs[1]
+
+	// Comments and formatting alone change nothing.
+	writeConfig(t, path, "# edited\n"+reloadConfigV1)
+	if r, err := e.prepareReload(path); err != nil || r != nil {
+		t.Fatalf("cosmetic edit: r=%v err=%v", r, err)
+	}
+
+	v2 := strings.NewReplacer(
+		"dedupe_seconds: 600", "dedupe_seconds: 300",
+		"timeout_seconds: 20", "timeout_seconds: 5",
+		"filter: yield_pct > 2.5", "filter: yield_pct > 4",
+	).Replace(reloadConfigV1) + `  - type: custom_expr
+    name: c
+    enabled: true
+    params: {kind: repo, datasets: [{marketdata: ["204001.SH"]}], filter: "true", event: {data: {expected_edge_pct: "1"}}}
+`
+	writeConfig(t, path, v2)
+	r, err := e.prepareReload(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(r.changes, "\n")
+	for _, want := range []string{
+		"restart required to apply: tushare",
+		"engine.dedupe_seconds: 600 -> 300",
+		"signals added: c",
+		"signals rebuilt: b",
+		"signals kept: 1",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("changes missing %q:\n%s", want, got)
+		}
+	}
+	if r.cfg.Tushare.TimeoutSeconds != 20 || r.notifiers != nil {
+		t.Fatalf("tushare must stay pinned and notifiers unchanged: timeout=%d notifiers=%v", r.cfg.Tushare.TimeoutSeconds, r.notifiers)
+	}
+
+	e.sent["k"] = e.now()
+	oldLanes[1].running.Store(true)
+	lanes := e.applyReload(r, oldLanes)
+	if e.sigs[0].sig != oldA.sig || e.sigs[1].sig == oldB.sig {
+		t.Fatalf("a must be reused and b rebuilt")
+	}
+	if e.cfg.Engine.DedupeSeconds != 300 || len(e.sent) != 1 {
+		t.Fatalf("dedupe=%d sent=%v", e.cfg.Engine.DedupeSeconds, e.sent)
+	}
+	if len(lanes) != 2 || lanes[1].name != "b" || lanes[1].running != oldLanes[1].running || !lanes[1].running.Load() {
+		t.Fatalf("lane b must keep its running flag: %+v", lanes)
+	}
+
+	writeConfig(t, path, strings.Replace(v2, "filter: yield_pct > 4", "filter: yield_pct >", 1))
+	if _, err := e.prepareReload(path); err == nil || !strings.Contains(err.Error(), "signal b (custom_expr)") {
+		t.Fatalf("invalid filter must reject the reload, err=%v", err)
+	}
+	writeConfig(t, path, strings.Replace(v2, "dedupe_seconds: 300", "dedupe_seconds: -5", 1))
+	if _, err := e.prepareReload(path); err == nil || !strings.Contains(err.Error(), "engine.dedupe_seconds") {
+		t.Fatalf("invalid engine config must reject the reload, err=%v", err)
+	}
+}
+
+func TestReloadRetainsOnlyReadRepoHistories(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	const src = `
+engine:
+  trade_date_mode: fixed
+  fixed_trade_date: "20260106"
+  state_store: memory
+notifiers:
+  - type: stdout
+signals:
+  - type: cn_repo_realtime
+    name: rt
+    enabled: true
+    params: {threshold_mode: percentile, history_path: h1.json}
+`
+	writeConfig(t, path, src)
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h1, h2 := filepath.Join(dir, "h1.json"), filepath.Join(dir, "h2.json")
+	if got := historyPaths(e.sigs); len(got) != 1 || !got[h1] {
+		t.Fatalf("history paths=%v", got)
+	}
+
+	writeConfig(t, path, strings.Replace(src, "h1.json", "h2.json", 1))
+	r, err := e.prepareReload(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2026, 1, 6, 14, 50, 0, 0, session.Location)
+	_ = e.histories.Record("204001.SH", at, 2.0)
+	e.applyReload(r, nil)
+
+	// h1 is flushed on the way out and no longer fed; h2 is.
+	_ = e.histories.Record("204001.SH", at.AddDate(0, 0, 1), 3.0)
+	if err := e.histories.Flush(at.AddDate(0, 0, 1)); err != nil {
+		t.Fatal(err)
+	}
+	old, err := repo.OpenHistory(h1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := old.SameTimeOfDay("204001.SH", at.AddDate(0, 0, 2), 10); len(got) != 1 || got[0] != 2.0 {
+		t.Fatalf("h1 samples=%v want [2]", got)
+	}
+	cur, err := repo.OpenHistory(h2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cur.SameTimeOfDay("204001.SH", at.AddDate(0, 0, 2), 10); len(got) != 2 || got[0] != 3.0 {
+		t.Fatalf("h2 samples=%v want [3 2]", got)
+	}
+}
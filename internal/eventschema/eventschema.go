@@ -0,0 +1,283 @@
+// Package eventschema defines vsr.event.v2, the versioned shape of the events
+// signals emit: the envelope every event carries and the well-known
+// event.data fields of each kind (tags.kind). The Go types below are the
+// source of truth; the validator and the JSON Schema published for external
+// consumers (tools/event_schema) are both derived from them.
+package eventschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"value-sniffer-radar/internal/costs"
+)
+
+//go:generate go test -run TestJSONSchemaFileInSync -update
+
+// Version is written to event.schema by the engine. Events without it
+// predate versioning (v1: free-form data).
+const Version = "vsr.event.v2"
+
+// Envelope is the part of an event that does not depend on its kind.
+type Envelope struct {
+	ID        string         `json:"id" doc:"Stable event id assigned at emission; every downstream record refers to it." vsr:"required,pattern=^evt_[0-9a-f]{24}(_[0-9]+)?$"`
+	EmittedAt time.Time      `json:"emitted_at" doc:"When the engine emitted the event (simulated time in backtests)." vsr:"required"`
+	Schema    string         `json:"schema" doc:"Schema version of the event." vsr:"required,enum=vsr.event.v2"`
+	Source    string         `json:"source" doc:"Name of the signal (signals[].name)." vsr:"required"`
+	TradeDate string         `json:"trade_date" doc:"Exchange trade date, YYYYMMDD." vsr:"required,pattern=^[0-9]{8}$"`
+	Market    string         `json:"market,omitempty" doc:"Market of the instrument, e.g. CN-A."`
+	Symbol    string         `json:"symbol,omitempty" doc:"Instrument code, e.g. 204001.SH."`
+	Title     string         `json:"title" vsr:"required"`
+	Body      string         `json:"body"`
+	Tags      Tags           `json:"tags" vsr:"required"`
+	Data      map[string]any `json:"data" doc:"Kind-specific fields, see $defs." vsr:"required"`
+}
+
+// Tags are string labels used for routing and policies. Unknown tags are
+// allowed.
+type Tags struct {
+	Kind       string `json:"kind" doc:"Instrument family; selects the data schema." vsr:"required,enum=cb|fund|repo"`
+	Tier       string `json:"tier,omitempty" doc:"action (quality gated) or observe (broad coverage)." vsr:"enum=action|observe"`
+	Strategy   string `json:"strategy,omitempty"`
+	Underlying string `json:"underlying,omitempty" doc:"Underlying stock of a convertible bond."`
+	Confidence string `json:"confidence,omitempty" doc:"Market data confidence of realtime signals." vsr:"enum=PASS|FAIL"`
+	Policy     string `json:"policy,omitempty" doc:"Engine policy that changed the event, e.g. net_edge."`
+}
+
+// Common fields may appear in the data of any kind. Cost and net edge fields
+// are filled in by the engine when the signal did not set them.
+type Common struct {
+	ExpectedEdgePct *float64 `json:"expected_edge_pct,omitempty" doc:"Edge before costs the signal expects, pct points (rate points for repos)." vsr:"required"`
+	SpreadPct       *float64 `json:"spread_pct,omitempty" doc:"Half the bid/ask spread summed over legs, pct points."`
+	SlippagePct     *float64 `json:"slippage_pct,omitempty" doc:"Fill price vs. level one for trade_notional, pct points."`
+	FeePct          *float64 `json:"fee_pct,omitempty" doc:"Round fees of the legs, pct points."`
+	NetEdgePct      *float64 `json:"net_edge_pct,omitempty" doc:"expected_edge_pct - spread_pct - slippage_pct - fee_pct."`
+	NetEdgeReason   string   `json:"net_edge_reason,omitempty" doc:"Why net_edge_pct could not be computed."`
+
+	CostBreakdown *costs.Breakdown `json:"cost_breakdown,omitempty" doc:"Inputs behind net_edge_pct."`
+	Legs          []costs.Leg      `json:"legs,omitempty" doc:"Orders the trade consists of."`
+	TradeNotional *float64         `json:"trade_notional,omitempty" doc:"Order size the costs were estimated for, CNY."`
+	DepthNotional *float64         `json:"depth_notional,omitempty" doc:"Notional the visible book can absorb, CNY."`
+	LiquidityOK   *bool            `json:"liquidity_ok,omitempty" doc:"false when the book cannot fill trade_notional."`
+
+	Confidence string           `json:"confidence,omitempty" doc:"Fused market data confidence." vsr:"enum=PASS|FAIL"`
+	Reason     string           `json:"reason,omitempty" doc:"Why market data fusion passed or failed."`
+	Providers  []map[string]any `json:"providers,omitempty" doc:"Per-provider results behind the fused quote."`
+	Amount     *float64         `json:"amount,omitempty" doc:"Traded amount of the instrument."`
+
+	PolicyDowngradeReason     string   `json:"policy_downgrade_reason,omitempty" doc:"Why an action event was downgraded to observe."`
+	PolicyActionNetEdgeMinPct *float64 `json:"policy_action_net_edge_min_pct,omitempty"`
+}
+
+// Repo is the data of kind=repo events (exchange reverse repos).
+type Repo struct {
+	Common
+
+	RatePct          *float64 `json:"rate_pct,omitempty" doc:"Repo rate, pct."`
+	ConsensusRatePct *float64 `json:"consensus_rate_pct,omitempty" doc:"Rate agreed by the realtime providers, pct; preferred over rate_pct."`
+	Close            *float64 `json:"close,omitempty"`
+	Weight           *float64 `json:"weight,omitempty" doc:"Amount-weighted average rate of the day."`
+	Vol              *float64 `json:"vol,omitempty"`
+	AvgAmt           *float64 `json:"avg_amt,omitempty" doc:"Average daily amount over the lookback."`
+
+	TenorDays         *int     `json:"tenor_days,omitempty"`
+	AccrualDays       *int     `json:"accrual_days,omitempty" doc:"Days interest accrues for."`
+	LockDays          *int     `json:"lock_days,omitempty" doc:"Days until the cash is usable again."`
+	FirstSettleDate   string   `json:"first_settle_date,omitempty" vsr:"pattern=^[0-9]{8}$"`
+	MaturityDate      string   `json:"maturity_date,omitempty" vsr:"pattern=^[0-9]{8}$"`
+	UsableDate        string   `json:"usable_date,omitempty" vsr:"pattern=^[0-9]{8}$"`
+	GrossYieldPct     *float64 `json:"gross_yield_pct,omitempty" doc:"Rate scaled to lock days, pct."`
+	EffectiveYieldPct *float64 `json:"effective_yield_pct,omitempty" doc:"gross_yield_pct after the repo fee, pct."`
+	Ladder            []Rung   `json:"ladder,omitempty" doc:"Every tenor the ladder compared."`
+
+	ThresholdYieldPct     *float64 `json:"threshold_yield_pct,omitempty" doc:"Rate the signal alerts at, pct."`
+	ThresholdSource       string   `json:"threshold_source,omitempty" vsr:"enum=fixed|percentile|fixed_fallback"`
+	ThresholdPercentile   *float64 `json:"threshold_percentile,omitempty"`
+	ThresholdLookbackDays *int     `json:"threshold_lookback_days,omitempty"`
+	ThresholdSamples      *int     `json:"threshold_samples,omitempty"`
+}
+
+// Rung is one tenor of a repo ladder.
+type Rung struct {
+	Symbol            string  `json:"symbol"`
+	TenorDays         int     `json:"tenor_days"`
+	RatePct           float64 `json:"rate_pct"`
+	EffectiveYieldPct float64 `json:"effective_yield_pct"`
+	AccrualDays       int     `json:"accrual_days"`
+	LockDays          int     `json:"lock_days"`
+}
+
+// EntryRatePct is the rate the alert fired at, preferring the realtime
+// consensus.
+func (r Repo) EntryRatePct() float64 {
+	if r.ConsensusRatePct != nil {
+		return *r.ConsensusRatePct
+	}
+	if r.RatePct != nil {
+		return *r.RatePct
+	}
+	return 0
+}
+
+// Premium fields are shared by the kinds priced against a fair value.
+type Premium struct {
+	PremiumPct          *float64 `json:"premium_pct,omitempty" doc:"Price over fair value (conversion value, NAV or IOPV), pct." vsr:"required"`
+	ThresholdPremiumPct *float64 `json:"threshold_premium_pct,omitempty" doc:"Premium the signal alerts at, pct."`
+	Side                string   `json:"side,omitempty" doc:"premium: the instrument is rich (sell it); discount: cheap (buy it)." vsr:"enum=premium|discount"`
+}
+
+// CB is the data of kind=cb events (convertible bonds).
+type CB struct {
+	Common
+	Premium
+
+	DoubleLow          *float64 `json:"double_low,omitempty" doc:"Bond price + premium_pct."`
+	ThresholdDoubleLow *float64 `json:"threshold_double_low,omitempty"`
+
+	BondClose *float64 `json:"bond_close,omitempty"`
+	BondPrice *float64 `json:"bond_price,omitempty" doc:"Realtime mid price of the bond."`
+	BondBid1  *float64 `json:"bond_bid1,omitempty"`
+	BondAsk1  *float64 `json:"bond_ask1,omitempty"`
+	StkCode   string   `json:"stk_code,omitempty" doc:"Underlying stock code."`
+	StkClose  *float64 `json:"stk_close,omitempty"`
+	StkPrice  *float64 `json:"stk_price,omitempty" doc:"Realtime mid price of the stock."`
+	StkBid1   *float64 `json:"stk_bid1,omitempty"`
+	StkAsk1   *float64 `json:"stk_ask1,omitempty"`
+	ConvPrice *float64 `json:"conv_price,omitempty" doc:"Conversion price."`
+	ConvValue *float64 `json:"conv_value,omitempty" doc:"Stock price * 100 / conv_price."`
+}
+
+// Fund is the data of kind=fund events (listed funds and ETFs).
+type Fund struct {
+	Common
+	Premium
+
+	Close      *float64 `json:"close,omitempty"`
+	Nav        *float64 `json:"nav,omitempty" doc:"Latest published unit NAV."`
+	NavDate    string   `json:"nav_date,omitempty" vsr:"pattern=^[0-9]{8}$"`
+	NavLagDays *int     `json:"nav_lag_days,omitempty" doc:"Calendar days between nav_date and the trade date."`
+	NavStale   *bool    `json:"nav_stale,omitempty"`
+	Price      *float64 `json:"price,omitempty" doc:"Realtime last price."`
+	IOPV       *float64 `json:"iopv,omitempty" doc:"Indicative NAV published during the session."`
+}
+
+// Kinds maps tags.kind to the type of its data.
+var Kinds = map[string]any{
+	"repo": Repo{},
+	"cb":   CB{},
+	"fund": Fund{},
+}
+
+// Required lists the data fields events of kind must carry; nil for unknown
+// kinds.
+func Required(kind string) []string {
+	n, ok := dataNodes[kind]
+	if !ok {
+		return nil
+	}
+	var out []string
+	for _, p := range n.props {
+		if p.required {
+			out = append(out, p.name)
+		}
+	}
+	return out
+}
+
+// Decode reads event data into one of the types above (or a struct
+// embedding them). Unknown keys are ignored.
+func Decode(data map[string]any, v any) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// ValidationError lists every way an event deviates from the schema.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return Version + ": " + strings.Join(e.Problems, "; ")
+}
+
+// Validate checks an event (anything that marshals like notifier.Event) as
+// an external consumer would see it: the JSON encoding against the envelope
+// and the data schema of its kind. It returns a *ValidationError.
+func Validate(ev any) error {
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return &ValidationError{Problems: []string{"not JSON-encodable: " + err.Error()}}
+	}
+	var doc any
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return &ValidationError{Problems: []string{err.Error()}}
+	}
+
+	var problems []string
+	envelope.check("", doc, &problems)
+	if m, ok := doc.(map[string]any); ok {
+		tags, _ := m["tags"].(map[string]any)
+		kind, _ := tags["kind"].(string)
+		if n, ok := dataNodes[kind]; ok {
+			if data, ok := m["data"]; ok {
+				n.check("data", data, &problems)
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+var (
+	envelope  = build(reflectType(Envelope{}))
+	dataNodes = func() map[string]*node {
+		out := make(map[string]*node, len(Kinds))
+		for k, v := range Kinds {
+			out[k] = build(reflectType(v))
+		}
+		return out
+	}()
+)
+
+func kindNames() []string {
+	out := make([]string, 0, len(Kinds))
+	for k := range Kinds {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func describe(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
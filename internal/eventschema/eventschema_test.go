@@ -0,0 +1,150 @@
+package eventschema
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the generated JSON Schema file")
+
+var schemaFile = filepath.Join("..", "..", "tools", "event_schema", Version+".schema.json")
+
+func TestJSONSchemaFileInSync(t *testing.T) {
+	want, err := JSONSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(schemaFile), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(schemaFile, want, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := os.ReadFile(schemaFile)
+	if err != nil {
+		t.Fatalf("read %s: %v (run go generate ./internal/eventschema)", schemaFile, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("%s is out of date; run go generate ./internal/eventschema", schemaFile)
+	}
+}
+
+func TestKindTagEnumMatchesKinds(t *testing.T) {
+	var tags *node
+	for _, p := range envelope.props {
+		if p.name == "tags" {
+			tags = p
+		}
+	}
+	for _, p := range tags.props {
+		if p.name == "kind" && !reflect.DeepEqual(p.enum, kindNames()) {
+			t.Fatalf("tags.kind enum=%v kinds=%v", p.enum, kindNames())
+		}
+	}
+}
+
+func TestRequired(t *testing.T) {
+	if got := Required("fund"); !reflect.DeepEqual(got, []string{"expected_edge_pct", "premium_pct"}) {
+		t.Fatalf("fund required=%v", got)
+	}
+	if got := Required("fx"); got != nil {
+		t.Fatalf("unknown kind required=%v", got)
+	}
+}
+
+type event struct {
+	ID        string            `json:"id,omitempty"`
+	EmittedAt time.Time         `json:"emitted_at"`
+	Schema    string            `json:"schema,omitempty"`
+	Source    string            `json:"source"`
+	TradeDate string            `json:"trade_date"`
+	Symbol    string            `json:"symbol,omitempty"`
+	Title     string            `json:"title"`
+	Body      string            `json:"body"`
+	Tags      map[string]string `json:"tags,omitempty"`
+	Data      map[string]any    `json:"data,omitempty"`
+}
+
+func cbEvent() event {
+	return event{
+		ID:        "evt_0123456789abcdef01234567",
+		EmittedAt: time.Date(2026, 1, 8, 10, 0, 0, 0, time.UTC),
+		Schema:    Version,
+		Source:    "cb_premium",
+		TradeDate: "20260108",
+		Symbol:    "113050.SH",
+		Title:     "CB premium -3.10% (113050.SH)",
+		Tags:      map[string]string{"kind": "cb", "tier": "action", "underlying": "600000.SH"},
+		Data: map[string]any{
+			"premium_pct":       -3.1,
+			"expected_edge_pct": 1.1,
+			"side":              "discount",
+			"stk_code":          "600000.SH",
+			"conv_price":        10,
+			"legs":              []map[string]any{{"symbol": "113050.SH", "side": "buy", "notional": 100000}},
+			"extra_signal_key":  "kept",
+		},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate(cbEvent()); err != nil {
+		t.Fatalf("valid event: %v", err)
+	}
+
+	cases := []struct {
+		name  string
+		mut   func(*event)
+		wants []string
+	}{
+		{"unstamped", func(e *event) { e.ID, e.Schema = "", "" }, []string{"missing id", "missing schema"}},
+		{"bad trade date", func(e *event) { e.TradeDate = "2026-01-08" }, []string{"trade_date"}},
+		{"unknown kind", func(e *event) { e.Tags["kind"] = "fx" }, []string{"tags.kind"}},
+		{"bad tier", func(e *event) { e.Tags["tier"] = "urgent" }, []string{"tags.tier"}},
+		{"string premium", func(e *event) { e.Data["premium_pct"] = "-3.1" }, []string{"data.premium_pct: want number"}},
+		{"missing premium", func(e *event) { delete(e.Data, "premium_pct") }, []string{"data: missing premium_pct"}},
+		{"bad side", func(e *event) { e.Data["side"] = "long" }, []string{"data.side"}},
+		{"bad leg", func(e *event) { e.Data["legs"] = []map[string]any{{"notional": "1e5"}} }, []string{"data.legs[0].notional"}},
+		{"no data", func(e *event) { e.Data = nil }, []string{"missing data"}},
+	}
+	for _, tc := range cases {
+		ev := cbEvent()
+		tc.mut(&ev)
+		err := Validate(ev)
+		var ve *ValidationError
+		if !errors.As(err, &ve) {
+			t.Fatalf("%s: err=%v", tc.name, err)
+		}
+		for _, w := range tc.wants {
+			if !strings.Contains(err.Error(), w) {
+				t.Fatalf("%s: %v does not mention %q", tc.name, err, w)
+			}
+		}
+	}
+}
+
+func TestDecodeTyped(t *testing.T) {
+	var r Repo
+	if err := Decode(map[string]any{"rate_pct": 1.8, "consensus_rate_pct": 2.1, "tenor_days": 1}, &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.EntryRatePct() != 2.1 || r.TenorDays == nil || *r.TenorDays != 1 || r.ExpectedEdgePct != nil {
+		t.Fatalf("repo=%+v", r)
+	}
+	var f Fund
+	if err := Decode(cbEvent().Data, &f); err != nil {
+		t.Fatal(err)
+	}
+	if f.PremiumPct == nil || *f.PremiumPct != -3.1 || f.Side != "discount" || len(f.Legs) != 1 {
+		t.Fatalf("fund=%+v", f)
+	}
+}
@@ -0,0 +1,273 @@
+package eventschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// node is the subset of JSON Schema the event types need. It is built from
+// the Go types (json, doc and vsr struct tags) and both renders the published
+// schema and validates decoded JSON, so the two cannot disagree.
+type node struct {
+	name     string
+	typ      string // number | integer | string | boolean | array | object; empty accepts anything
+	format   string
+	doc      string
+	required bool
+	enum     []string
+	pattern  *regexp.Regexp
+	items    *node   // array elements
+	props    []*node // object properties, in declaration order
+	extra    *node   // object values not listed in props (maps)
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func reflectType(v any) reflect.Type { return reflect.TypeOf(v) }
+
+func build(t reflect.Type) *node {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return &node{typ: "string", format: "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return &node{typ: "string"}
+	case reflect.Bool:
+		return &node{typ: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &node{typ: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &node{typ: "number"}
+	case reflect.Slice, reflect.Array:
+		return &node{typ: "array", items: build(t.Elem())}
+	case reflect.Map:
+		n := &node{typ: "object"}
+		if t.Elem().Kind() != reflect.Interface {
+			n.extra = build(t.Elem())
+		}
+		return n
+	case reflect.Struct:
+		n := &node{typ: "object"}
+		addFields(n, t)
+		return n
+	default:
+		return &node{}
+	}
+}
+
+// addFields lists the JSON properties of struct t, flattening embedded
+// structs the way encoding/json does.
+func addFields(n *node, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" || (!f.IsExported() && !f.Anonymous) {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				addFields(n, ft)
+				continue
+			}
+		}
+		if name == "" {
+			name = f.Name
+		}
+		p := build(f.Type)
+		p.name = name
+		p.doc = f.Tag.Get("doc")
+		for _, opt := range strings.Split(f.Tag.Get("vsr"), ",") {
+			k, v, _ := strings.Cut(opt, "=")
+			switch k {
+			case "required":
+				p.required = true
+			case "enum":
+				p.enum = strings.Split(v, "|")
+			case "pattern":
+				p.pattern = regexp.MustCompile(v)
+			}
+		}
+		n.props = append(n.props, p)
+	}
+}
+
+// check appends a problem for every way v (decoded JSON) violates n.
+func (n *node) check(path string, v any, problems *[]string) {
+	if n.typ == "" {
+		return
+	}
+	fail := func(format string, args ...any) {
+		at := path
+		if at == "" {
+			at = "event"
+		}
+		*problems = append(*problems, at+": "+fmt.Sprintf(format, args...))
+	}
+
+	switch n.typ {
+	case "number":
+		if _, ok := v.(float64); !ok {
+			fail("want number, got %s", describe(v))
+		}
+	case "integer":
+		f, ok := v.(float64)
+		if !ok || f != math.Trunc(f) {
+			fail("want integer, got %s", describe(v))
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			fail("want boolean, got %s", describe(v))
+		}
+	case "string":
+		s, ok := v.(string)
+		if !ok {
+			fail("want string, got %s", describe(v))
+			return
+		}
+		if n.required && s == "" {
+			fail("must not be empty")
+			return
+		}
+		if len(n.enum) > 0 && !contains(n.enum, s) {
+			fail("%q not one of %s", s, strings.Join(n.enum, "|"))
+		}
+		if n.pattern != nil && s != "" && !n.pattern.MatchString(s) {
+			fail("%q does not match %s", s, n.pattern)
+		}
+	case "array":
+		a, ok := v.([]any)
+		if !ok {
+			fail("want array, got %s", describe(v))
+			return
+		}
+		for i, x := range a {
+			n.items.check(fmt.Sprintf("%s[%d]", path, i), x, problems)
+		}
+	case "object":
+		m, ok := v.(map[string]any)
+		if !ok {
+			fail("want object, got %s", describe(v))
+			return
+		}
+		known := make(map[string]bool, len(n.props))
+		for _, p := range n.props {
+			known[p.name] = true
+			x, ok := m[p.name]
+			if !ok {
+				if p.required {
+					fail("missing %s", p.name)
+				}
+				continue
+			}
+			p.check(joinPath(path, p.name), x, problems)
+		}
+		if n.extra != nil {
+			for k, x := range m {
+				if !known[k] {
+					n.extra.check(joinPath(path, k), x, problems)
+				}
+			}
+		}
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *node) schema() map[string]any {
+	out := map[string]any{}
+	if n.typ != "" {
+		out["type"] = n.typ
+	}
+	if n.format != "" {
+		out["format"] = n.format
+	}
+	if n.doc != "" {
+		out["description"] = n.doc
+	}
+	if len(n.enum) > 0 {
+		out["enum"] = n.enum
+	}
+	if n.pattern != nil {
+		out["pattern"] = n.pattern.String()
+	}
+	if n.typ == "string" && n.required && len(n.enum) == 0 {
+		out["minLength"] = 1
+	}
+	if n.items != nil {
+		out["items"] = n.items.schema()
+	}
+	if n.typ == "object" && len(n.props) > 0 {
+		props := map[string]any{}
+		var required []string
+		for _, p := range n.props {
+			props[p.name] = p.schema()
+			if p.required {
+				required = append(required, p.name)
+			}
+		}
+		out["properties"] = props
+		if len(required) > 0 {
+			out["required"] = required
+		}
+	}
+	if n.extra != nil {
+		out["additionalProperties"] = n.extra.schema()
+	}
+	return out
+}
+
+// JSONSchema renders vsr.event.v2 as a JSON Schema (draft 2020-12) document.
+// tools/event_schema holds the generated copy.
+func JSONSchema() ([]byte, error) {
+	doc := envelope.schema()
+	doc["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	doc["$id"] = Version
+	doc["title"] = Version
+	doc["description"] = "Event emitted by value-sniffer-radar. data carries the fields of the event's tags.kind; keys not listed are allowed."
+
+	defs := map[string]any{}
+	var branches []any
+	for _, kind := range kindNames() {
+		defs[kind] = dataNodes[kind].schema()
+		branches = append(branches, map[string]any{
+			"if": map[string]any{
+				"properties": map[string]any{
+					"tags": map[string]any{
+						"properties": map[string]any{"kind": map[string]any{"const": kind}},
+					},
+				},
+			},
+			"then": map[string]any{
+				"properties": map[string]any{"data": map[string]any{"$ref": "#/$defs/" + kind}},
+			},
+		})
+	}
+	doc["$defs"] = defs
+	doc["allOf"] = branches
+
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
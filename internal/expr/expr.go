@@ -0,0 +1,375 @@
+// Package expr evaluates the small expression language custom signals are
+// written in, e.g.
+//
+//	(close - unit_nav) / unit_nav * 100 > 3 && amount > 1e7
+//
+// Values are numbers (float64), strings, booleans and null. Operators, from
+// loosest to tightest: c ? a : b, ||, &&, == !=, < <= > >=, + -, * / %, and
+// the prefix - and !. + also joins strings. Identifiers name columns of the
+// row being evaluated; they may contain dots (nav.unit_nav).
+//
+// null stands for a missing value (a field Tushare left empty, a row a left
+// join found nothing for). It propagates: arithmetic and comparisons with
+// null give null, so do division and % by zero, and && / || follow
+// three-valued logic (false && null is false, true || null is true). Only
+// == and != compare with null directly. Callers treat a null condition as
+// not true.
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Expr is a compiled expression.
+type Expr struct {
+	src  string
+	root node
+}
+
+// Compile parses src. Errors give the column they occur at.
+func Compile(src string) (*Expr, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	if toks[0].kind == tokEOF {
+		return nil, errors.New("empty expression")
+	}
+	p := &parser{toks: toks}
+	root, err := p.parse(0)
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, errorAt(t.pos, "unexpected %s", describeTok(t))
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+// MustCompile is Compile for expressions known to be valid; it panics on
+// errors.
+func MustCompile(src string) *Expr {
+	e, err := Compile(src)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+func (e *Expr) String() string { return e.src }
+
+// Vars resolves identifiers.
+type Vars interface {
+	Lookup(name string) (any, bool)
+}
+
+// Map is Vars backed by a map. Values should be float64, string, bool or
+// nil; other numeric types are converted.
+type Map map[string]any
+
+func (m Map) Lookup(name string) (any, bool) {
+	v, ok := m[name]
+	return v, ok
+}
+
+// Eval evaluates e against vars. Identifiers vars does not know are errors,
+// as are operands of the wrong type.
+func (e *Expr) Eval(vars Vars) (any, error) {
+	v, err := e.root.eval(vars)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", e.src, err)
+	}
+	return v, nil
+}
+
+// Bool evaluates e as a condition: true only when it evaluates to true.
+func (e *Expr) Bool(vars Vars) (bool, error) {
+	v, err := e.Eval(vars)
+	if err != nil {
+		return false, err
+	}
+	switch b := v.(type) {
+	case bool:
+		return b, nil
+	case nil:
+		return false, nil
+	default:
+		return false, fmt.Errorf("%s: want boolean, got %s", e.src, typeName(v))
+	}
+}
+
+// Number evaluates e as a number; ok is false when it is null.
+func (e *Expr) Number(vars Vars) (f float64, ok bool, err error) {
+	v, err := e.Eval(vars)
+	if err != nil || v == nil {
+		return 0, false, err
+	}
+	f, isNum := v.(float64)
+	if !isNum {
+		return 0, false, fmt.Errorf("%s: want number, got %s", e.src, typeName(v))
+	}
+	return f, true, nil
+}
+
+func errorAt(pos int, format string, args ...any) error {
+	return fmt.Errorf("col %d: %s", pos+1, fmt.Sprintf(format, args...))
+}
+
+type node interface {
+	eval(vars Vars) (any, error)
+}
+
+type litNode struct{ v any }
+
+func (n *litNode) eval(Vars) (any, error) { return n.v, nil }
+
+type identNode struct{ name string }
+
+func (n *identNode) eval(vars Vars) (any, error) {
+	v, ok := vars.Lookup(n.name)
+	if !ok {
+		return nil, fmt.Errorf("unknown identifier %s", n.name)
+	}
+	return normalize(v), nil
+}
+
+type unaryNode struct {
+	op  string
+	pos int
+	x   node
+}
+
+func (n *unaryNode) eval(vars Vars) (any, error) {
+	v, err := n.x.eval(vars)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	switch n.op {
+	case "-":
+		if f, ok := v.(float64); ok {
+			return -f, nil
+		}
+	case "!":
+		if b, ok := v.(bool); ok {
+			return !b, nil
+		}
+	}
+	return nil, errorAt(n.pos, "%s %s", n.op, typeName(v))
+}
+
+type condNode struct{ cond, yes, no node }
+
+func (n *condNode) eval(vars Vars) (any, error) {
+	c, err := n.cond.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	switch c {
+	case true:
+		return n.yes.eval(vars)
+	case false:
+		return n.no.eval(vars)
+	case nil:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("?: want boolean condition, got %s", typeName(c))
+}
+
+type binaryNode struct {
+	op   string
+	pos  int
+	l, r node
+}
+
+func (n *binaryNode) eval(vars Vars) (any, error) {
+	l, err := n.l.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "&&" || n.op == "||" {
+		return n.logic(l, vars)
+	}
+	r, err := n.r.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==", "!=":
+		eq := l == nil && r == nil
+		if l != nil && r != nil {
+			if typeName(l) != typeName(r) {
+				return nil, n.mismatch(l, r)
+			}
+			eq = l == r
+		}
+		return eq == (n.op == "=="), nil
+	}
+	if l == nil || r == nil {
+		return nil, nil
+	}
+
+	if ls, ok := l.(string); ok {
+		rs, ok := r.(string)
+		if !ok {
+			return nil, n.mismatch(l, r)
+		}
+		switch n.op {
+		case "+":
+			return ls + rs, nil
+		case "<":
+			return ls < rs, nil
+		case "<=":
+			return ls <= rs, nil
+		case ">":
+			return ls > rs, nil
+		case ">=":
+			return ls >= rs, nil
+		}
+		return nil, n.mismatch(l, r)
+	}
+
+	lf, lok := l.(float64)
+	rf, rok := r.(float64)
+	if !lok || !rok {
+		return nil, n.mismatch(l, r)
+	}
+	switch n.op {
+	case "+":
+		return lf + rf, nil
+	case "-":
+		return lf - rf, nil
+	case "*":
+		return lf * rf, nil
+	case "/":
+		if rf == 0 {
+			return nil, nil
+		}
+		return lf / rf, nil
+	case "%":
+		if rf == 0 {
+			return nil, nil
+		}
+		return math.Mod(lf, rf), nil
+	case "<":
+		return lf < rf, nil
+	case "<=":
+		return lf <= rf, nil
+	case ">":
+		return lf > rf, nil
+	case ">=":
+		return lf >= rf, nil
+	}
+	return nil, n.mismatch(l, r)
+}
+
+// logic is && and || in three-valued logic; the right side is evaluated
+// only when the left does not decide.
+func (n *binaryNode) logic(l any, vars Vars) (any, error) {
+	decides := n.op == "||" // true decides ||, false decides &&
+	if l != nil {
+		lb, ok := l.(bool)
+		if !ok {
+			return nil, errorAt(n.pos, "%s wants booleans, got %s", n.op, typeName(l))
+		}
+		if lb == decides {
+			return decides, nil
+		}
+	}
+	r, err := n.r.eval(vars)
+	if err != nil || r == nil {
+		return nil, err
+	}
+	rb, ok := r.(bool)
+	if !ok {
+		return nil, errorAt(n.pos, "%s wants booleans, got %s", n.op, typeName(r))
+	}
+	if rb == decides {
+		return decides, nil
+	}
+	if l == nil {
+		return nil, nil
+	}
+	return rb, nil
+}
+
+func (n *binaryNode) mismatch(l, r any) error {
+	return errorAt(n.pos, "%s %s %s", typeName(l), n.op, typeName(r))
+}
+
+type callNode struct {
+	name string
+	pos  int
+	fn   function
+	args []node
+}
+
+func (n *callNode) eval(vars Vars) (any, error) {
+	args := make([]any, len(n.args))
+	for i, a := range n.args {
+		v, err := a.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	v, err := n.fn.call(args)
+	if err != nil {
+		return nil, errorAt(n.pos, "%s: %v", n.name, err)
+	}
+	return v, nil
+}
+
+// normalize maps the values rows carry onto the four value types.
+func normalize(v any) any {
+	switch x := v.(type) {
+	case nil, float64, string, bool:
+		return v
+	case float32:
+		return float64(x)
+	case int:
+		return float64(x)
+	case int64:
+		return float64(x)
+	case int32:
+		return float64(x)
+	case uint64:
+		return float64(x)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func typeName(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// Format renders a value for messages: numbers without trailing zeros,
+// null as "null".
+func Format(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return "null"
+	case float64:
+		if x == math.Trunc(x) && math.Abs(x) < 1e15 {
+			return fmt.Sprintf("%.0f", x)
+		}
+		return strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.6f", x), "0"), ".")
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,126 @@
+package expr
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEval(t *testing.T) {
+	row := Map{
+		"close": 1.05, "unit_nav": 1.0, "amount": 2e7, "ts_code": "510300.SH",
+		"nav.unit_nav": 1.0, "missing": nil, "zero": 0.0, "lots": 3,
+	}
+	cases := []struct {
+		src  string
+		want any
+	}{
+		{"(close - unit_nav)/unit_nav*100 > 3 && amount > 1e7", true},
+		{"1 + 2 * 3 - 4 / 2", 5.0},
+		{"-2 * -3", 6.0},
+		{"10 % 4", 2.0},
+		{"2 * (3 + 4)", 14.0},
+		{"1 < 2 == true", true},
+		{"close - nav.unit_nav", 1.05 - 1.0},
+		{"lots * 2", 6.0},
+		{`ts_code + "!"`, "510300.SH!"},
+		{`starts_with(ts_code, "51") && !contains(ts_code, "SZ")`, true},
+		{"abs(-3) + max(1, 4, 2) + min(5, 2) + round(1.256, 2)", 3 + 4 + 2 + 1.26},
+		{"close > 1 ? 'premium' : 'discount'", "premium"},
+		{"false ? 1 : true ? 2 : 3", 2.0},
+
+		// null propagates; division by zero is null.
+		{"missing + 1", nil},
+		{"missing > 1", nil},
+		{"close / zero", nil},
+		{"missing == null", true},
+		{"close != null", true},
+		{"coalesce(missing, close)", 1.05},
+		{"abs(missing)", nil},
+
+		// Three-valued logic.
+		{"false && missing > 1", false},
+		{"true && missing > 1", nil},
+		{"missing > 1 || true", true},
+		{"missing > 1 || false", nil},
+		{"missing > 1 ? 1 : 2", nil},
+	}
+	for _, tc := range cases {
+		x, err := Compile(tc.src)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.src, err)
+		}
+		got, err := x.Eval(row)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.src, err)
+		}
+		if f, ok := got.(float64); ok {
+			if w, ok := tc.want.(float64); ok && f-w < 1e-9 && w-f < 1e-9 {
+				continue
+			}
+		}
+		if got != tc.want {
+			t.Fatalf("%s = %v (%T), want %v", tc.src, got, got, tc.want)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	compile := []struct{ src, want string }{
+		{"", "empty expression"},
+		{"1 +", "col 4: unexpected end of expression"},
+		{"(1 + 2", `want ")"`},
+		{"1 2", "col 3: unexpected 2"},
+		{"a ? b", `want ":"`},
+		{"nope(1)", "unknown function nope"},
+		{"abs(1, 2)", "wrong number of arguments"},
+		{"'open", "unterminated string"},
+		{"a # b", `col 3: unexpected '#'`},
+	}
+	for _, tc := range compile {
+		_, err := Compile(tc.src)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("Compile(%q) err=%v, want %q", tc.src, err, tc.want)
+		}
+	}
+
+	row := Map{"s": "x", "n": 1.0}
+	eval := []struct{ src, want string }{
+		{"typo > 1", "unknown identifier typo"},
+		{"s + 1", "string + number"},
+		{"s == 1", "string == number"},
+		{"n && true", "&& wants booleans"},
+		{"-s", "- string"},
+		{"abs(s)", "want number"},
+	}
+	for _, tc := range eval {
+		_, err := MustCompile(tc.src).Eval(row)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("Eval(%q) err=%v, want %q", tc.src, err, tc.want)
+		}
+	}
+	if _, err := MustCompile("n").Bool(row); err == nil {
+		t.Fatalf("Bool on a number must fail")
+	}
+	if ok, err := MustCompile("n > null").Bool(row); ok || err != nil {
+		t.Fatalf("null condition: ok=%v err=%v", ok, err)
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	tpl, err := CompileTemplate("Fund premium {(close - nav) / nav * 100:%.2f}% ({ts_code}) {{x}} nav={missing}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := tpl.Render(Map{"close": 1.0312, "nav": 1.0, "ts_code": "510300.SH", "missing": nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Fund premium 3.12% (510300.SH) {x} nav=null"; got != want {
+		t.Fatalf("got %q want %q", got, want)
+	}
+	for _, bad := range []string{"{close", "close}", "{1 +}"} {
+		if _, err := CompileTemplate(bad); err == nil {
+			t.Fatalf("CompileTemplate(%q) must fail", bad)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+type function struct {
+	minArgs, maxArgs int // maxArgs < 0: variadic
+	call             func(args []any) (any, error)
+}
+
+// funcs are the built-in functions. Apart from coalesce they return null
+// when an argument is null.
+var funcs = map[string]function{
+	"abs":   numeric(1, 1, func(x []float64) float64 { return math.Abs(x[0]) }),
+	"floor": numeric(1, 1, func(x []float64) float64 { return math.Floor(x[0]) }),
+	"ceil":  numeric(1, 1, func(x []float64) float64 { return math.Ceil(x[0]) }),
+	"sqrt":  numeric(1, 1, func(x []float64) float64 { return math.Sqrt(x[0]) }),
+	"round": numeric(1, 2, func(x []float64) float64 { // round(x[, digits])
+		if len(x) == 1 {
+			return math.Round(x[0])
+		}
+		p := math.Pow(10, x[1])
+		return math.Round(x[0]*p) / p
+	}),
+	"min": numeric(1, -1, func(x []float64) float64 {
+		m := x[0]
+		for _, v := range x[1:] {
+			m = math.Min(m, v)
+		}
+		return m
+	}),
+	"max": numeric(1, -1, func(x []float64) float64 {
+		m := x[0]
+		for _, v := range x[1:] {
+			m = math.Max(m, v)
+		}
+		return m
+	}),
+	"coalesce": {minArgs: 1, maxArgs: -1, call: func(args []any) (any, error) {
+		for _, a := range args {
+			if a != nil {
+				return a, nil
+			}
+		}
+		return nil, nil
+	}},
+	"contains":    strings2(strings.Contains),
+	"starts_with": strings2(strings.HasPrefix),
+	"ends_with":   strings2(strings.HasSuffix),
+}
+
+func numeric(minArgs, maxArgs int, f func([]float64) float64) function {
+	return function{minArgs: minArgs, maxArgs: maxArgs, call: func(args []any) (any, error) {
+		xs := make([]float64, len(args))
+		for i, a := range args {
+			if a == nil {
+				return nil, nil
+			}
+			x, ok := a.(float64)
+			if !ok {
+				return nil, fmt.Errorf("want number, got %s", typeName(a))
+			}
+			xs[i] = x
+		}
+		v := f(xs)
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, nil
+		}
+		return v, nil
+	}}
+}
+
+func strings2(f func(s, sub string) bool) function {
+	return function{minArgs: 2, maxArgs: 2, call: func(args []any) (any, error) {
+		if args[0] == nil || args[1] == nil {
+			return nil, nil
+		}
+		s, ok1 := args[0].(string)
+		sub, ok2 := args[1].(string)
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("want strings, got %s, %s", typeName(args[0]), typeName(args[1]))
+		}
+		return f(s, sub), nil
+	}}
+}
+
+func funcNames() []string {
+	out := make([]string, 0, len(funcs))
+	for n := range funcs {
+		out = append(out, n)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,267 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokNum
+	tokStr
+	tokIdent
+	tokOp // operators and punctuation
+)
+
+type token struct {
+	kind tokKind
+	text string // operator, identifier or the decoded string literal
+	num  float64
+	pos  int // byte offset in the source, for errors
+}
+
+// lex splits src into tokens. Identifiers may contain dots (daily.close) so
+// columns of joined datasets can be named by their dataset.
+func lex(src string) ([]token, error) {
+	var out []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case isDigit(c) || (c == '.' && i+1 < len(src) && isDigit(src[i+1])):
+			j := i
+			for j < len(src) && (isDigit(src[j]) || src[j] == '.') {
+				j++
+			}
+			if j < len(src) && (src[j] == 'e' || src[j] == 'E') {
+				k := j + 1
+				if k < len(src) && (src[k] == '+' || src[k] == '-') {
+					k++
+				}
+				if k < len(src) && isDigit(src[k]) {
+					for j = k; j < len(src) && isDigit(src[j]); j++ {
+					}
+				}
+			}
+			f, err := strconv.ParseFloat(src[i:j], 64)
+			if err != nil {
+				return nil, errorAt(i, "bad number %q", src[i:j])
+			}
+			out = append(out, token{kind: tokNum, num: f, text: src[i:j], pos: i})
+			i = j
+		case c == '"' || c == '\'':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(src) && src[j] != c; j++ {
+				if src[j] == '\\' && j+1 < len(src) {
+					j++
+					switch src[j] {
+					case 'n':
+						b.WriteByte('\n')
+					case 't':
+						b.WriteByte('\t')
+					default:
+						b.WriteByte(src[j])
+					}
+					continue
+				}
+				b.WriteByte(src[j])
+			}
+			if j >= len(src) {
+				return nil, errorAt(i, "unterminated string")
+			}
+			out = append(out, token{kind: tokStr, text: b.String(), pos: i})
+			i = j + 1
+		case isIdentStart(c):
+			j := i
+			for j < len(src) && (isIdentStart(src[j]) || isDigit(src[j]) || (src[j] == '.' && j+1 < len(src) && isIdentStart(src[j+1]))) {
+				j++
+			}
+			out = append(out, token{kind: tokIdent, text: src[i:j], pos: i})
+			i = j
+		default:
+			op := ""
+			for _, o := range []string{"&&", "||", "==", "!=", "<=", ">="} {
+				if strings.HasPrefix(src[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" && strings.IndexByte("+-*/%<>!?:(),", c) >= 0 {
+				op = string(c)
+			}
+			if op == "" {
+				return nil, errorAt(i, "unexpected %q", c)
+			}
+			out = append(out, token{kind: tokOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(out, token{kind: tokEOF, pos: len(src)}), nil
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// Binding powers, loosest first. The ternary is right-associative; the
+// binary operators are left-associative.
+var infixPower = map[string]int{
+	"?":  1,
+	"||": 2,
+	"&&": 3,
+	"==": 4, "!=": 4,
+	"<": 5, "<=": 5, ">": 5, ">=": 5,
+	"+": 6, "-": 6,
+	"*": 7, "/": 7, "%": 7,
+}
+
+const prefixPower = 8
+
+// parser is a Pratt parser: every token has a prefix (nud) and possibly an
+// infix (led) meaning, and infixPower decides how far an operand extends.
+type parser struct {
+	toks []token
+	i    int
+}
+
+func (p *parser) peek() token { return p.toks[p.i] }
+
+func (p *parser) next() token {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) expect(op string) error {
+	t := p.next()
+	if t.kind != tokOp || t.text != op {
+		return errorAt(t.pos, "want %q, got %s", op, describeTok(t))
+	}
+	return nil
+}
+
+func (p *parser) parse(rbp int) (node, error) {
+	left, err := p.prefix(p.next())
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		lbp, ok := infixPower[t.text]
+		if t.kind != tokOp || !ok || lbp <= rbp {
+			return left, nil
+		}
+		p.next()
+		if t.text == "?" {
+			yes, err := p.parse(0)
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			no, err := p.parse(lbp - 1)
+			if err != nil {
+				return nil, err
+			}
+			left = &condNode{cond: left, yes: yes, no: no}
+			continue
+		}
+		right, err := p.parse(lbp)
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: t.text, pos: t.pos, l: left, r: right}
+	}
+}
+
+func (p *parser) prefix(t token) (node, error) {
+	switch t.kind {
+	case tokNum:
+		return &litNode{v: t.num}, nil
+	case tokStr:
+		return &litNode{v: t.text}, nil
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return &litNode{v: true}, nil
+		case "false":
+			return &litNode{v: false}, nil
+		case "null":
+			return &litNode{v: nil}, nil
+		}
+		if n := p.peek(); n.kind == tokOp && n.text == "(" {
+			p.next()
+			return p.call(t)
+		}
+		return &identNode{name: t.text}, nil
+	case tokOp:
+		switch t.text {
+		case "(":
+			x, err := p.parse(0)
+			if err != nil {
+				return nil, err
+			}
+			return x, p.expect(")")
+		case "-", "!":
+			x, err := p.parse(prefixPower)
+			if err != nil {
+				return nil, err
+			}
+			return &unaryNode{op: t.text, pos: t.pos, x: x}, nil
+		}
+	}
+	return nil, errorAt(t.pos, "unexpected %s", describeTok(t))
+}
+
+func (p *parser) call(name token) (node, error) {
+	fn, ok := funcs[name.text]
+	if !ok {
+		return nil, errorAt(name.pos, "unknown function %s (known: %s)", name.text, strings.Join(funcNames(), ", "))
+	}
+	var args []node
+	if t := p.peek(); t.kind == tokOp && t.text == ")" {
+		p.next()
+	} else {
+		for {
+			a, err := p.parse(0)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, a)
+			t := p.next()
+			if t.kind == tokOp && t.text == ")" {
+				break
+			}
+			if t.kind != tokOp || t.text != "," {
+				return nil, errorAt(t.pos, "want \",\" or \")\", got %s", describeTok(t))
+			}
+		}
+	}
+	if len(args) < fn.minArgs || (fn.maxArgs >= 0 && len(args) > fn.maxArgs) {
+		return nil, errorAt(name.pos, "%s: wrong number of arguments (%d)", name.text, len(args))
+	}
+	return &callNode{name: name.text, pos: name.pos, fn: fn, args: args}, nil
+}
+
+func describeTok(t token) string {
+	switch t.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokStr:
+		return strconv.Quote(t.text)
+	case tokNum:
+		return t.text
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
@@ -0,0 +1,87 @@
+package expr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Template is text with embedded expressions: {expr} or {expr:%verb}, e.g.
+// "premium {premium_pct:%.2f}% ({ts_code})". {{ and }} are literal braces.
+// Without a verb values print as Format does.
+type Template struct {
+	src   string
+	parts []part
+}
+
+type part struct {
+	text string
+	x    *Expr // nil: literal text
+	verb string
+}
+
+// CompileTemplate parses src.
+func CompileTemplate(src string) (*Template, error) {
+	t := &Template{src: src}
+	var lit strings.Builder
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		if (c == '{' || c == '}') && i+1 < len(src) && src[i+1] == c {
+			lit.WriteByte(c)
+			i++
+			continue
+		}
+		if c == '}' {
+			return nil, fmt.Errorf("col %d: unmatched }", i+1)
+		}
+		if c != '{' {
+			lit.WriteByte(c)
+			continue
+		}
+		end := strings.IndexByte(src[i:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("col %d: unterminated {", i+1)
+		}
+		body := src[i+1 : i+end]
+		verb := ""
+		if k := strings.LastIndex(body, ":%"); k >= 0 {
+			body, verb = body[:k], body[k+1:]
+		}
+		x, err := Compile(body)
+		if err != nil {
+			return nil, fmt.Errorf("{%s}: %w", body, err)
+		}
+		if lit.Len() > 0 {
+			t.parts = append(t.parts, part{text: lit.String()})
+			lit.Reset()
+		}
+		t.parts = append(t.parts, part{x: x, verb: verb})
+		i += end
+	}
+	if lit.Len() > 0 {
+		t.parts = append(t.parts, part{text: lit.String()})
+	}
+	return t, nil
+}
+
+func (t *Template) String() string { return t.src }
+
+// Render evaluates the embedded expressions against vars.
+func (t *Template) Render(vars Vars) (string, error) {
+	var b strings.Builder
+	for _, p := range t.parts {
+		if p.x == nil {
+			b.WriteString(p.text)
+			continue
+		}
+		v, err := p.x.Eval(vars)
+		if err != nil {
+			return "", err
+		}
+		if p.verb == "" || v == nil {
+			b.WriteString(Format(v))
+		} else {
+			b.WriteString(fmt.Sprintf(p.verb, v))
+		}
+	}
+	return b.String(), nil
+}
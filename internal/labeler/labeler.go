@@ -12,28 +12,51 @@ import (
 	"time"
 
 	"value-sniffer-radar/internal/config"
+	"value-sniffer-radar/internal/costs"
+	"value-sniffer-radar/internal/eventschema"
 	"value-sniffer-radar/internal/marketdata"
 	"value-sniffer-radar/internal/optimizer"
+	"value-sniffer-radar/internal/signals"
+	"value-sniffer-radar/internal/tushare"
 )
 
 type Config struct {
-	Windows []time.Duration
-	Grace   time.Duration
+	Windows    []time.Duration // intraday rewards (repo)
+	DayWindows []int           // trade-day rewards (cb, fund)
+	Grace      time.Duration
 
-	// Repo-only for this ticket:
-	OnlyKind string // "repo"
+	OnlyKind string // label only this Tags["kind"]; empty labels every kind with a reward
+
+	// Rewards picks the reward function per kind (see RegisterReward); nil
+	// uses DefaultRewards.
+	Rewards map[string]string
 
 	MaxPerRun int
 	Now       func() time.Time
+
+	// Tushare serves the daily bars trade-day rewards need; without it those
+	// kinds are not labelled.
+	Tushare *tushare.Client
+
+	// History, when set, labels offline: the exit value is the recorded tick at
+	// exactly event_ts + window instead of a live fetch, so Grace does not
+	// apply and labels can be (re)computed any time after the fact.
+	History TickHistory
+}
+
+// TickHistory serves recorded fusion snapshots; *marketdata.Replay implements it.
+type TickHistory interface {
+	At(symbol string, t time.Time) (marketdata.TickRecord, bool)
+	Covers(t time.Time) bool
 }
 
 func DefaultConfig() Config {
 	return Config{
-		Windows:   []time.Duration{10 * time.Second, 30 * time.Second, 5 * time.Minute},
-		Grace:     30 * time.Second,
-		OnlyKind:  "repo",
-		MaxPerRun: 200,
-		Now:       time.Now,
+		Windows:    []time.Duration{10 * time.Second, 30 * time.Second, 5 * time.Minute},
+		DayWindows: []int{1, 3, 5},
+		Grace:      30 * time.Second,
+		MaxPerRun:  200,
+		Now:        time.Now,
 	}
 }
 
@@ -64,27 +87,88 @@ func ParseWindows(s string) ([]time.Duration, error) {
 	return out, nil
 }
 
+// ParseDayWindows parses "1,3,5" (trade days).
+func ParseDayWindows(s string) ([]int, error) {
+	var out []int
+	for _, p := range strings.Split(s, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, err
+		}
+		if n <= 0 {
+			return nil, fmt.Errorf("day window must be >0: %s", p)
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+// ParseRewards parses "cb=price_return,fund=premium_convergence" over DefaultRewards.
+func ParseRewards(s string) (map[string]string, error) {
+	out := map[string]string{}
+	for k, v := range DefaultRewards {
+		out[k] = v
+	}
+	for _, p := range strings.Split(s, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		kind, name, ok := strings.Cut(p, "=")
+		kind, name = strings.ToLower(strings.TrimSpace(kind)), strings.TrimSpace(name)
+		if !ok || kind == "" {
+			return nil, fmt.Errorf("bad reward mapping %q (want kind=name)", p)
+		}
+		if _, ok := lookupReward(name); !ok {
+			return nil, fmt.Errorf("unknown reward %q for kind %s", name, kind)
+		}
+		out[kind] = name
+	}
+	return out, nil
+}
+
 type Runner struct {
 	cfg       *config.Config
 	md        marketdata.Fusion
 	labelCfg  Config
 	threshold map[string]float64 // source -> min_yield_pct
+	rewards   map[string]Reward  // kind -> reward
+	costs     *costs.Model
+
+	bars map[string][]dailyBar // per run: api|ts_code|from -> bars
 }
 
 func New(cfg *config.Config, md marketdata.Fusion, labelCfg Config) *Runner {
 	if labelCfg.Now == nil {
 		labelCfg.Now = time.Now
 	}
+	byKind := labelCfg.Rewards
+	if byKind == nil {
+		byKind = DefaultRewards
+	}
+	rewards := map[string]Reward{}
+	for kind, name := range byKind {
+		rw, ok := lookupReward(name)
+		if !ok || (rw.Horizon == HorizonTradeDays && labelCfg.Tushare == nil) {
+			continue
+		}
+		rewards[strings.ToLower(kind)] = rw
+	}
 	th := map[string]float64{}
 	for _, s := range cfg.Signals {
 		if s.Name == "" {
 			continue
 		}
-		switch s.Type {
-		case "cn_repo_realtime", "cn_repo_sniper":
-			if s.MinYieldPct > 0 {
-				th[s.Name] = s.MinYieldPct
-			}
+		p, err := signals.DecodeParams(s, signals.Env{})
+		if err != nil {
+			continue
+		}
+		if t, ok := p.(signals.YieldThresholder); ok && t.YieldThreshold() > 0 {
+			th[s.Name] = t.YieldThreshold()
 		}
 	}
 	return &Runner{
@@ -92,6 +176,8 @@ func New(cfg *config.Config, md marketdata.Fusion, labelCfg Config) *Runner {
 		md:        md,
 		labelCfg:  labelCfg,
 		threshold: th,
+		rewards:   rewards,
+		costs:     costs.FromConfig(cfg.Engine.Costs),
 	}
 }
 
@@ -160,86 +246,62 @@ func (r *Runner) RunOnce(ctx context.Context, paperPath string, labelsPath strin
 	}
 	defer out.Close()
 
+	r.bars = map[string][]dailyBar{}
 	wrote := 0
 	skipped := 0
+	var firstErr error
 	for _, pr := range rows {
 		if wrote >= r.labelCfg.MaxPerRun {
 			break
 		}
 		ev := pr.Event
-		if r.labelCfg.OnlyKind != "" {
-			if ev.Tags == nil || strings.ToLower(strings.TrimSpace(ev.Tags["kind"])) != r.labelCfg.OnlyKind {
-				skipped++
-				continue
-			}
+		kind := ""
+		if ev.Tags != nil {
+			kind = strings.ToLower(strings.TrimSpace(ev.Tags["kind"]))
 		}
-		if ev.Source == "" || ev.Symbol == "" {
+		rw, ok := r.rewards[kind]
+		if !ok || (r.labelCfg.OnlyKind != "" && kind != r.labelCfg.OnlyKind) {
 			skipped++
 			continue
 		}
-		eventTS, err := time.Parse(time.RFC3339, strings.TrimSpace(pr.TS))
-		if err != nil {
+		if ev.Source == "" || ev.Symbol == "" {
 			skipped++
 			continue
 		}
-
-		thr := r.threshold[ev.Source]
-		if thr <= 0 {
-			// If we can't resolve threshold from config, we still can write labels with reason.
-			thr = 0
+		eventTS := ev.EmittedAt
+		if eventTS.IsZero() {
+			// Paper logs from before events were stamped: the write time.
+			if eventTS, err = time.Parse(time.RFC3339, strings.TrimSpace(pr.TS)); err != nil {
+				skipped++
+				continue
+			}
 		}
 
-		entry := entryRate(ev)
-
-		for _, w := range r.labelCfg.Windows {
-			windowSec := int(w.Seconds())
-			key := optimizer.EventID(pr) + "|" + strconv.Itoa(windowSec)
+		for _, t := range r.targets(rw, pr, eventTS, now) {
+			key := optimizer.EventID(pr) + "|" + strconv.Itoa(t.windowSec())
 			if labeled[key] {
 				continue
 			}
-			due := eventTS.Add(w)
-			if now.Before(due) {
-				continue
-			}
-			lateBy := now.Sub(due)
-			if r.labelCfg.Grace > 0 && lateBy > r.labelCfg.Grace {
-				// Missed the label window; skip instead of lying.
-				continue
+			l := Label{
+				EventID:    optimizer.EventID(pr),
+				EventTS:    eventTS,
+				Source:     ev.Source,
+				Symbol:     ev.Symbol,
+				TradeDate:  ev.TradeDate,
+				Kind:       kind,
+				RewardFn:   rw.Name,
+				WindowSec:  t.windowSec(),
+				WindowDays: t.Days,
 			}
-
-			exit, conf, reason, err := r.fetchExit(ctx, ev.Symbol)
+			ready, err := rw.Fn(ctx, r, t, &l)
 			if err != nil {
-				reason = "fetch_error"
-				conf = "FAIL"
-				exit = 0
-			}
-
-			reward := 0
-			if thr > 0 && conf == "PASS" && exit >= thr {
-				reward = 1
-				reason = "hold_above_threshold"
-			} else if thr > 0 && conf == "PASS" && exit < thr {
-				reward = 0
-				reason = "dropped_below_threshold"
-			} else if thr <= 0 {
-				reason = "missing_threshold"
+				if firstErr == nil {
+					firstErr = fmt.Errorf("%s %s window=%d: %w", rw.Name, ev.Symbol, l.WindowSec, err)
+				}
+				continue
 			}
-
-			l := Label{
-				EventID:      optimizer.EventID(pr),
-				EventTS:      eventTS,
-				Source:       ev.Source,
-				Symbol:       ev.Symbol,
-				TradeDate:    ev.TradeDate,
-				WindowSec:    windowSec,
-				GraceSec:     int(r.labelCfg.Grace.Seconds()),
-				LateBySec:    int(lateBy.Seconds()),
-				Threshold:    thr,
-				EntryRatePct: entry,
-				ExitRatePct:  exit,
-				Confidence:   conf,
-				Reward:       reward,
-				Reason:       reason,
+			if !ready {
+				continue
 			}
 			b, _ := json.Marshal(l)
 			if _, err := out.Write(append(b, '\n')); err != nil {
@@ -249,7 +311,7 @@ func (r *Runner) RunOnce(ctx context.Context, paperPath string, labelsPath strin
 			wrote++
 		}
 	}
-	return wrote, skipped, nil
+	return wrote, skipped, firstErr
 }
 
 func (r *Runner) fetchExit(ctx context.Context, symbol string) (float64, string, string, error) {
@@ -263,41 +325,23 @@ func (r *Runner) fetchExit(ctx context.Context, symbol string) (float64, string,
 	return fs.ConsensusRatePct, string(fs.Confidence), fs.Reason, nil
 }
 
-func entryRate(ev optimizer.PaperLogEvent) float64 {
-	if ev.Data == nil {
-		return 0
-	}
-	// Prefer realtime consensus if present.
-	if v, ok := ev.Data["consensus_rate_pct"]; ok {
-		if f, ok := toFloat(v); ok {
-			return f
-		}
+// historyExit reads the recorded fusion result in effect at due.
+func (r *Runner) historyExit(symbol string, due time.Time) (float64, string, string, time.Time) {
+	rec, ok := r.labelCfg.History.At(symbol, due)
+	if !ok {
+		return 0, "FAIL", "no_recorded_tick", time.Time{}
 	}
-	if v, ok := ev.Data["rate_pct"]; ok {
-		if f, ok := toFloat(v); ok {
-			return f
-		}
+	if rec.Error != "" {
+		return 0, "FAIL", "fetch_error", rec.TS
 	}
-	return 0
+	return rec.Snapshot.ConsensusRatePct, string(rec.Snapshot.Confidence), rec.Snapshot.Reason, rec.TS
 }
 
-func toFloat(v any) (float64, bool) {
-	switch t := v.(type) {
-	case float64:
-		return t, true
-	case float32:
-		return float64(t), true
-	case int:
-		return float64(t), true
-	case int64:
-		return float64(t), true
-	case json.Number:
-		f, err := t.Float64()
-		return f, err == nil
-	case string:
-		f, err := strconv.ParseFloat(strings.TrimSpace(t), 64)
-		return f, err == nil
-	default:
-		return 0, false
+func entryRate(ev optimizer.PaperLogEvent) float64 {
+	var d eventschema.Repo
+	if err := eventschema.Decode(ev.Data, &d); err != nil {
+		return 0
 	}
+	// Prefers the realtime consensus if present.
+	return d.EntryRatePct()
 }
@@ -2,6 +2,7 @@ package labeler
 
 import (
 	"context"
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"strings"
@@ -44,7 +45,7 @@ func TestRunOnceWritesLabel(t *testing.T) {
 	cfg := &config.Config{
 		Engine: config.EngineConfig{TradeDateMode: "fixed", FixedTradeDate: "20260129"},
 		Signals: []config.SignalConfig{
-			{Type: "cn_repo_realtime", Name: "cn_repo_realtime_action", Enabled: true, MinYieldPct: 4.0},
+			{Type: "cn_repo_realtime", Name: "cn_repo_realtime_action", Enabled: true, Params: config.ParamsNode(map[string]any{"min_yield_pct": 4.0})},
 		},
 	}
 
@@ -69,3 +70,91 @@ func TestRunOnceWritesLabel(t *testing.T) {
 	}
 }
 
+type fakeHistory struct {
+	recs []marketdata.TickRecord // ascending TS
+	end  time.Time
+}
+
+func (h fakeHistory) At(symbol string, t time.Time) (marketdata.TickRecord, bool) {
+	var out marketdata.TickRecord
+	ok := false
+	for _, r := range h.recs {
+		if r.Symbol == symbol && !r.TS.After(t) {
+			out, ok = r, true
+		}
+	}
+	return out, ok
+}
+
+func (h fakeHistory) Covers(t time.Time) bool { return !h.end.Before(t) }
+
+func TestRunOnceOfflineFromTickHistory(t *testing.T) {
+	tmp := t.TempDir()
+	paper := filepath.Join(tmp, "paper.jsonl")
+	labels := filepath.Join(tmp, "labels.jsonl")
+
+	eventTS := time.Date(2026, 1, 29, 10, 0, 0, 0, time.UTC)
+	content := `{"ts":"` + eventTS.Format(time.RFC3339) + `","event":{"source":"cn_repo_realtime_action","trade_date":"20260129","symbol":"204001.SH","title":"demo","tags":{"kind":"repo"},"data":{"consensus_rate_pct":5.0}}}` + "\n"
+	if err := os.WriteFile(paper, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{Signals: []config.SignalConfig{
+		{Type: "cn_repo_realtime", Name: "cn_repo_realtime_action", Enabled: true, Params: config.ParamsNode(map[string]any{"min_yield_pct": 4.0})},
+	}}
+	tick := func(at time.Duration, rate float64) marketdata.TickRecord {
+		return marketdata.TickRecord{TS: eventTS.Add(at), Symbol: "204001.SH", Snapshot: marketdata.FusionSnapshot{
+			ConsensusRatePct: rate, Confidence: marketdata.ConfidencePass, Reason: "consensus_pass",
+		}}
+	}
+
+	lcfg := DefaultConfig()
+	lcfg.Windows = []time.Duration{10 * time.Second, 30 * time.Second, 5 * time.Minute}
+	lcfg.Now = func() time.Time { return eventTS.Add(24 * time.Hour) } // long past any grace
+	lcfg.History = fakeHistory{
+		recs: []marketdata.TickRecord{tick(8*time.Second, 4.5), tick(28*time.Second, 3.5), tick(299*time.Second, 4.2)},
+		end:  eventTS.Add(time.Minute),
+	}
+
+	wrote, _, err := New(cfg, nil, lcfg).RunOnce(context.Background(), paper, labels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wrote != 2 {
+		t.Fatalf("wrote=%d want=2 (5m window not recorded yet)", wrote)
+	}
+	got := readLabels(t, labels)
+	if got[0].WindowSec != 10 || got[0].ExitRatePct != 4.5 || got[0].Reward != 1 || !got[0].ExitTS.Equal(eventTS.Add(8*time.Second)) {
+		t.Fatalf("10s label=%+v", got[0])
+	}
+	if got[1].WindowSec != 30 || got[1].ExitRatePct != 3.5 || got[1].Reward != 0 {
+		t.Fatalf("30s label=%+v", got[1])
+	}
+
+	// Once the recording reaches the 5m due time, a rerun fills only that window.
+	lcfg.History = fakeHistory{recs: lcfg.History.(fakeHistory).recs, end: eventTS.Add(10 * time.Minute)}
+	wrote, _, err = New(cfg, nil, lcfg).RunOnce(context.Background(), paper, labels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = readLabels(t, labels)
+	if wrote != 1 || len(got) != 3 || got[2].WindowSec != 300 || got[2].ExitRatePct != 4.2 {
+		t.Fatalf("wrote=%d labels=%+v", wrote, got)
+	}
+}
+
+func readLabels(t *testing.T, path string) []Label {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []Label
+	for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		var l Label
+		if err := json.Unmarshal([]byte(line), &l); err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, l)
+	}
+	return out
+}
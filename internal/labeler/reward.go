@@ -0,0 +1,165 @@
+package labeler
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"value-sniffer-radar/internal/optimizer"
+)
+
+// Horizon is how a reward measures its windows.
+type Horizon int
+
+const (
+	// HorizonClock windows are wall-clock durations after the event (Config.Windows).
+	HorizonClock Horizon = iota
+	// HorizonTradeDays windows count trade days after the event's trade date
+	// (Config.DayWindows), using daily bars.
+	HorizonTradeDays
+)
+
+// Target is one (event, window) pair to label.
+type Target struct {
+	Row     optimizer.PaperRow
+	EventTS time.Time
+	Now     time.Time
+
+	Window time.Duration // HorizonClock
+	Days   int           // HorizonTradeDays
+}
+
+// windowSec keys labels; trade-day windows count as whole days.
+func (t Target) windowSec() int {
+	if t.Days > 0 {
+		return t.Days * 86400
+	}
+	return int(t.Window.Seconds())
+}
+
+// RewardFunc fills the outcome fields of l (metric, entry/exit values,
+// confidence, reward, reason). ready=false means the window cannot be judged
+// yet (not due, data not published); the pair is retried on the next run.
+// Errors are transient too: nothing is written for the pair.
+type RewardFunc func(ctx context.Context, r *Runner, t Target, l *Label) (ready bool, err error)
+
+// Reward is a named, registered reward function.
+type Reward struct {
+	Name    string
+	Horizon Horizon
+	Fn      RewardFunc
+}
+
+var rewardRegistry = map[string]Reward{}
+
+// RegisterReward makes a reward selectable by name (Config.Rewards).
+func RegisterReward(rw Reward) { rewardRegistry[rw.Name] = rw }
+
+func lookupReward(name string) (Reward, bool) {
+	rw, ok := rewardRegistry[name]
+	return rw, ok
+}
+
+// RewardNames lists the registered rewards.
+func RewardNames() []string {
+	out := make([]string, 0, len(rewardRegistry))
+	for n := range rewardRegistry {
+		out = append(out, n)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// DefaultRewards maps event kinds (Tags["kind"]) to the reward they are
+// labelled with.
+var DefaultRewards = map[string]string{
+	"repo": "repo_hold_threshold",
+	"cb":   "premium_convergence",
+	"fund": "premium_convergence",
+}
+
+func init() {
+	RegisterReward(Reward{Name: "repo_hold_threshold", Horizon: HorizonClock, Fn: repoHoldThreshold})
+	RegisterReward(Reward{Name: "premium_convergence", Horizon: HorizonTradeDays, Fn: premiumConvergence})
+	RegisterReward(Reward{Name: "price_return", Horizon: HorizonTradeDays, Fn: priceReturn})
+}
+
+func (r *Runner) targets(rw Reward, pr optimizer.PaperRow, eventTS, now time.Time) []Target {
+	var out []Target
+	switch rw.Horizon {
+	case HorizonTradeDays:
+		for _, d := range r.labelCfg.DayWindows {
+			out = append(out, Target{Row: pr, EventTS: eventTS, Now: now, Days: d})
+		}
+	default:
+		for _, w := range r.labelCfg.Windows {
+			out = append(out, Target{Row: pr, EventTS: eventTS, Now: now, Window: w})
+		}
+	}
+	return out
+}
+
+// repoHoldThreshold rewards a repo alert whose rate is still at or above the
+// signal's min_yield_pct once the window has passed. Live, the exit rate is
+// fetched within Grace of the due time; with Config.History it is the
+// recorded tick at exactly event_ts + window.
+func repoHoldThreshold(ctx context.Context, r *Runner, t Target, l *Label) (bool, error) {
+	ev := t.Row.Event
+	due := t.EventTS.Add(t.Window)
+	if t.Now.Before(due) {
+		return false, nil
+	}
+
+	var exit float64
+	var conf, reason string
+	var exitTS time.Time
+	var lateBy time.Duration
+	if r.labelCfg.History != nil {
+		if !r.labelCfg.History.Covers(due) {
+			// Not recorded yet; a later run will get it.
+			return false, nil
+		}
+		exit, conf, reason, exitTS = r.historyExit(ev.Symbol, due)
+	} else {
+		lateBy = t.Now.Sub(due)
+		if r.labelCfg.Grace > 0 && lateBy > r.labelCfg.Grace {
+			// Missed the label window; skip instead of lying.
+			return false, nil
+		}
+		var err error
+		exit, conf, reason, err = r.fetchExit(ctx, ev.Symbol)
+		if err != nil {
+			reason = "fetch_error"
+			conf = "FAIL"
+			exit = 0
+		}
+		exitTS = t.Now
+	}
+
+	// Without a threshold from config the label is still written, with a reason.
+	thr := r.threshold[ev.Source]
+	reward := 0
+	if thr > 0 && conf == "PASS" && exit >= thr {
+		reward = 1
+		reason = "hold_above_threshold"
+	} else if thr > 0 && conf == "PASS" && exit < thr {
+		reason = "dropped_below_threshold"
+	} else if thr <= 0 {
+		reason = "missing_threshold"
+	}
+
+	entry := entryRate(ev)
+	l.GraceSec = int(r.labelCfg.Grace.Seconds())
+	l.LateBySec = int(lateBy.Seconds())
+	l.Threshold = thr
+	l.EntryRatePct = entry
+	l.ExitRatePct = exit
+	l.ExitTS = exitTS
+	l.Metric = "rate_pct"
+	l.EntryValue = entry
+	l.ExitValue = exit
+	l.Confidence = conf
+	l.Reward = reward
+	l.Reason = reason
+	return true, nil
+}
@@ -0,0 +1,296 @@
+package labeler
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"value-sniffer-radar/internal/costs"
+	"value-sniffer-radar/internal/eventschema"
+	"value-sniffer-radar/internal/marketdata"
+	"value-sniffer-radar/internal/session"
+	"value-sniffer-radar/internal/tushare"
+)
+
+type dailyBar struct {
+	date  string // YYYYMMDD
+	close float64
+}
+
+// barsAPI is the Tushare daily bar endpoint for an event's instrument.
+func barsAPI(kind, symbol string) string {
+	if kind == "fund" {
+		return "fund_daily"
+	}
+	switch marketdata.ClassOf(symbol) {
+	case marketdata.ClassCB:
+		return "cb_daily"
+	case marketdata.ClassETF:
+		return "fund_daily"
+	default:
+		return "daily"
+	}
+}
+
+// dailyBars returns closes of tsCode from tradeDate on, ascending, far enough
+// out to cover the longest day window. Results are reused within a run.
+func (r *Runner) dailyBars(ctx context.Context, api, tsCode, tradeDate string, now time.Time) ([]dailyBar, error) {
+	key := api + "|" + tsCode + "|" + tradeDate
+	if bars, ok := r.bars[key]; ok {
+		return bars, nil
+	}
+	if r.labelCfg.Tushare == nil {
+		return nil, errors.New("daily bars need a Tushare client")
+	}
+	start, err := time.ParseInLocation("20060102", tradeDate, session.Location)
+	if err != nil {
+		return nil, err
+	}
+	maxDays := 1
+	for _, d := range r.labelCfg.DayWindows {
+		if d > maxDays {
+			maxDays = d
+		}
+	}
+	// Trade days are ~5/7 of calendar days; leave room for a long holiday.
+	end := start.AddDate(0, 0, maxDays*2+14).Format("20060102")
+	if today := now.In(session.Location).Format("20060102"); end > today {
+		end = today
+	}
+	rows, err := r.labelCfg.Tushare.Query(ctx, api, map[string]any{
+		"ts_code":    tsCode,
+		"start_date": tradeDate,
+		"end_date":   end,
+	}, []string{"ts_code", "trade_date", "close"})
+	if err != nil {
+		return nil, err
+	}
+	bars := make([]dailyBar, 0, len(rows))
+	for _, row := range rows {
+		if c := tushare.GetFloat(row, "close"); c > 0 {
+			bars = append(bars, dailyBar{date: tushare.GetString(row, "trade_date"), close: c})
+		}
+	}
+	sort.Slice(bars, func(i, j int) bool { return bars[i].date < bars[j].date })
+	r.bars[key] = bars
+	return bars, nil
+}
+
+// entryExit finds the bar of tradeDate and the one days trade days later.
+// ready is false while the exit bar is not published; reason is set when the
+// entry bar is missing for good.
+func entryExit(bars []dailyBar, tradeDate string, days int) (entry, exit dailyBar, ready bool, reason string) {
+	i := sort.Search(len(bars), func(i int) bool { return bars[i].date >= tradeDate })
+	if i == len(bars) {
+		return entry, exit, false, ""
+	}
+	if bars[i].date != tradeDate {
+		return entry, exit, true, "missing_entry_bar"
+	}
+	if i+days >= len(bars) {
+		return bars[i], exit, false, ""
+	}
+	return bars[i], bars[i+days], true, ""
+}
+
+func closeTS(date string) time.Time {
+	d, err := time.ParseInLocation("20060102", date, session.Location)
+	if err != nil {
+		return time.Time{}
+	}
+	return session.CloseTime(d)
+}
+
+func failLabel(l *Label, reason string) (bool, error) {
+	l.Confidence = "FAIL"
+	l.Reason = reason
+	return true, nil
+}
+
+// premiumConvergence rewards a CB/fund premium alert when the premium is
+// closer to zero after the window (|exit| < |entry|), i.e. the mispricing the
+// alert pointed at has at least partly closed. CB premiums are recomputed
+// from bond and stock closes with the event's conversion price; fund premiums
+// from the close and the NAV dated the exit date (or within the lag the event
+// recorded for that fund).
+func premiumConvergence(ctx context.Context, r *Runner, t Target, l *Label) (bool, error) {
+	ev := t.Row.Event
+	l.Metric = "premium_pct"
+	var p eventschema.Premium
+	if err := eventschema.Decode(ev.Data, &p); err != nil {
+		return failLabel(l, "invalid_event_data")
+	}
+	if p.ThresholdPremiumPct != nil {
+		l.Threshold = *p.ThresholdPremiumPct
+	}
+	if p.PremiumPct == nil {
+		return failLabel(l, "missing_entry_premium")
+	}
+	entryPrem := *p.PremiumPct
+	l.EntryValue = entryPrem
+
+	bars, err := r.dailyBars(ctx, barsAPI(l.Kind, ev.Symbol), ev.Symbol, ev.TradeDate, t.Now)
+	if err != nil {
+		return false, err
+	}
+	_, exit, ready, reason := entryExit(bars, ev.TradeDate, t.Days)
+	if !ready {
+		return false, nil
+	}
+	if reason != "" {
+		return failLabel(l, reason)
+	}
+	l.ExitTS = closeTS(exit.date)
+
+	var exitPrem float64
+	if l.Kind == "fund" {
+		var f eventschema.Fund
+		if err := eventschema.Decode(ev.Data, &f); err != nil {
+			return failLabel(l, "invalid_event_data")
+		}
+		lag := 0
+		if f.NavLagDays != nil && *f.NavLagDays > 0 {
+			lag = *f.NavLagDays
+		}
+		entryNav := f.NavDate
+		if entryNav == "" {
+			entryNav = ev.TradeDate
+		}
+		nav, navDate, err := r.navOn(ctx, ev.Symbol, entryNav, exit.date, lag)
+		if err != nil || navDate == "" {
+			return false, err // NAVs publish after the close
+		}
+		l.NavDate = navDate
+		exitPrem = (exit.close - nav) / nav * 100.0
+	} else {
+		var cb eventschema.CB
+		if err := eventschema.Decode(ev.Data, &cb); err != nil {
+			return failLabel(l, "invalid_event_data")
+		}
+		stk := cb.StkCode
+		if stk == "" && ev.Tags != nil {
+			stk = ev.Tags["underlying"]
+		}
+		if stk == "" || cb.ConvPrice == nil || *cb.ConvPrice <= 0 {
+			return failLabel(l, "missing_conversion_terms")
+		}
+		convPrice := *cb.ConvPrice
+		stkBars, err := r.dailyBars(ctx, "daily", stk, ev.TradeDate, t.Now)
+		if err != nil {
+			return false, err
+		}
+		i := sort.Search(len(stkBars), func(i int) bool { return stkBars[i].date >= exit.date })
+		if i == len(stkBars) || stkBars[i].date != exit.date {
+			return failLabel(l, "missing_underlying_bar")
+		}
+		convValue := stkBars[i].close * 100.0 / convPrice
+		exitPrem = (exit.close - convValue) / convValue * 100.0
+	}
+
+	l.ExitValue = exitPrem
+	l.Confidence = "PASS"
+	if math.Abs(exitPrem) < math.Abs(entryPrem) {
+		l.Reward = 1
+		l.Reason = "premium_converged"
+	} else {
+		l.Reason = "premium_diverged"
+	}
+	return true, nil
+}
+
+// navOn is the latest unit NAV of a fund dated date, or up to lagDays calendar
+// days earlier for funds whose event already ran on a lagging NAV (QDII/LOF),
+// and after the entry NAV's date so the exit is priced on a newer one. No
+// such NAV yet leaves navDate empty: the window is not ready.
+func (r *Runner) navOn(ctx context.Context, tsCode, entryNav, date string, lagDays int) (nav float64, navDate string, err error) {
+	d, err := time.ParseInLocation("20060102", date, session.Location)
+	if err != nil {
+		return 0, "", err
+	}
+	earliest := d.AddDate(0, 0, -lagDays).Format("20060102")
+	rows, err := r.labelCfg.Tushare.Query(ctx, "fund_nav", map[string]any{
+		"ts_code":    tsCode,
+		"start_date": earliest,
+		"end_date":   date,
+	}, []string{"ts_code", "nav_date", "unit_nav"})
+	if err != nil {
+		return 0, "", err
+	}
+	for _, row := range rows {
+		nd := tushare.GetString(row, "nav_date")
+		v := tushare.GetFloat(row, "unit_nav")
+		if v > 0 && nd >= earliest && nd <= date && nd > entryNav && nd > navDate {
+			navDate, nav = nd, v
+		}
+	}
+	return nav, navDate, nil
+}
+
+// priceReturn rewards an alert whose instrument returned more than its
+// round-trip costs between the event's trade date close and the close days
+// trade days later. Premium-side alerts are scored short (the rich leg is the
+// one to sell). Costs are the fee schedule (engine.costs) for a buy and a sell
+// plus the spread/slippage the event recorded.
+func priceReturn(ctx context.Context, r *Runner, t Target, l *Label) (bool, error) {
+	ev := t.Row.Event
+	l.Metric = "close"
+	var d struct {
+		eventschema.Common
+		eventschema.Premium
+	}
+	if err := eventschema.Decode(ev.Data, &d); err != nil {
+		return failLabel(l, "invalid_event_data")
+	}
+	bars, err := r.dailyBars(ctx, barsAPI(l.Kind, ev.Symbol), ev.Symbol, ev.TradeDate, t.Now)
+	if err != nil {
+		return false, err
+	}
+	entry, exit, ready, reason := entryExit(bars, ev.TradeDate, t.Days)
+	if !ready {
+		return false, nil
+	}
+	if reason != "" {
+		return failLabel(l, reason)
+	}
+
+	dir := 1.0
+	if strings.EqualFold(d.Side, "premium") {
+		dir = -1
+	}
+	ret := dir * (exit.close - entry.close) / entry.close * 100.0
+
+	market := ev.Market
+	if market == "" {
+		market = "CN-A"
+	}
+	var cost float64
+	if b, ok := r.costs.Fees(market, []costs.Leg{
+		{Symbol: ev.Symbol, Side: marketdata.Buy},
+		{Symbol: ev.Symbol, Side: marketdata.Sell},
+	}); ok {
+		cost = b.FeePct
+	}
+	for _, v := range []*float64{d.SpreadPct, d.SlippagePct} {
+		if v != nil {
+			cost += *v
+		}
+	}
+
+	l.EntryValue = entry.close
+	l.ExitValue = exit.close
+	l.ExitTS = closeTS(exit.date)
+	l.ReturnPct = ret
+	l.CostPct = cost
+	l.NetReturnPct = ret - cost
+	l.Confidence = "PASS"
+	if l.NetReturnPct > 0 {
+		l.Reward = 1
+		l.Reason = "net_gain"
+	} else {
+		l.Reason = "net_loss"
+	}
+	return true, nil
+}
@@ -0,0 +1,159 @@
+package labeler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"value-sniffer-radar/internal/config"
+	"value-sniffer-radar/internal/tushare"
+)
+
+func TestRunOnceLabelsCBAndFundFromDailyBars(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			APIName string         `json:"api_name"`
+			Params  map[string]any `json:"params"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		data := map[string]any{"fields": []string{"ts_code", "trade_date", "close"}, "items": [][]any{}}
+		switch req.APIName + "|" + req.Params["ts_code"].(string) {
+		case "cb_daily|113001.SH": // premium 20% -> 10% by day 2
+			data["items"] = [][]any{{"113001.SH", "20260107", 113.0}, {"113001.SH", "20260105", 120.0}, {"113001.SH", "20260106", 118.0}}
+		case "daily|600001.SH":
+			data["items"] = [][]any{{"600001.SH", "20260105", 10.0}, {"600001.SH", "20260106", 10.0}, {"600001.SH", "20260107", 10.27}}
+		case "fund_daily|510300.SH": // 2% premium, price flat
+			data["items"] = [][]any{{"510300.SH", "20260105", 1.02}, {"510300.SH", "20260106", 1.02}}
+		case "fund_nav|510300.SH":
+			data = map[string]any{"fields": []string{"ts_code", "nav_date", "unit_nav"}, "items": [][]any{{"510300.SH", "20260105", 1.0}, {"510300.SH", "20260106", 0.99}}}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"code": 0, "data": data})
+	}))
+	defer srv.Close()
+
+	tmp := t.TempDir()
+	paper := filepath.Join(tmp, "paper.jsonl")
+	labelsPath := filepath.Join(tmp, "labels.jsonl")
+	content := `{"ts":"2026-01-05T15:30:00+08:00","event":{"source":"cb_premium","trade_date":"20260105","market":"CN-A","symbol":"113001.SH","title":"cb","tags":{"kind":"cb","underlying":"600001.SH"},"data":{"premium_pct":20.0,"threshold_premium_pct":15.0,"side":"premium","stk_code":"600001.SH","conv_price":10.0}}}
+{"ts":"2026-01-05T15:30:00+08:00","event":{"source":"fund_premium","trade_date":"20260105","market":"CN-A","symbol":"510300.SH","title":"fund","tags":{"kind":"fund"},"data":{"premium_pct":2.0,"side":"premium"}}}
+{"ts":"2026-01-05T15:30:00+08:00","event":{"source":"other","trade_date":"20260105","symbol":"000001.SZ","title":"x","tags":{"kind":"stock"}}}
+`
+	if err := os.WriteFile(paper, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	lcfg := DefaultConfig()
+	lcfg.DayWindows = []int{1, 2}
+	lcfg.Rewards = map[string]string{"cb": "premium_convergence", "fund": "price_return"}
+	lcfg.Now = func() time.Time { return time.Date(2026, 1, 8, 9, 0, 0, 0, time.UTC) }
+	lcfg.Tushare = tushare.New(tushare.Options{BaseURL: srv.URL, Token: "t"})
+
+	wrote, skipped, err := New(&config.Config{}, nil, lcfg).RunOnce(context.Background(), paper, labelsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// cb: days 1 and 2; fund: day 1 only (no bar for day 2 yet); stock: no reward.
+	if wrote != 3 || skipped != 1 {
+		t.Fatalf("wrote=%d skipped=%d", wrote, skipped)
+	}
+	got := readLabels(t, labelsPath)
+	byKey := map[string]Label{}
+	for _, l := range got {
+		byKey[fmt.Sprintf("%s/%d", l.Kind, l.WindowDays)] = l
+	}
+
+	cb1, cb2 := byKey["cb/1"], byKey["cb/2"]
+	if cb1.RewardFn != "premium_convergence" || cb1.WindowSec != 86400 || cb1.Metric != "premium_pct" {
+		t.Fatalf("cb1=%+v", cb1)
+	}
+	// Day 1: 118 vs conv value 100 -> 18% premium, converged from 20%.
+	if math.Abs(cb1.ExitValue-18) > 1e-9 || cb1.Reward != 1 || cb1.Reason != "premium_converged" {
+		t.Fatalf("cb1=%+v", cb1)
+	}
+	// Day 2: 113 vs 102.7 -> ~10.03%.
+	if math.Abs(cb2.ExitValue-(113-102.7)/102.7*100) > 1e-9 || cb2.Reward != 1 || !cb2.ExitTS.Equal(time.Date(2026, 1, 7, 15, 0, 0, 0, time.FixedZone("CST", 8*3600))) {
+		t.Fatalf("cb2=%+v", cb2)
+	}
+
+	f1 := byKey["fund/1"]
+	// Flat price scored short: zero return, minus round-trip ETF fees -> loss.
+	if f1.RewardFn != "price_return" || f1.ReturnPct != 0 || f1.CostPct <= 0 || f1.NetReturnPct >= 0 || f1.Reward != 0 || f1.Reason != "net_loss" {
+		t.Fatalf("fund1=%+v", f1)
+	}
+
+	// Default rewards score funds on premium convergence against the NAV.
+	lcfg.Rewards = nil
+	navLabels := filepath.Join(tmp, "labels.nav.jsonl")
+	if _, _, err := New(&config.Config{}, nil, lcfg).RunOnce(context.Background(), paper, navLabels); err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range readLabels(t, navLabels) {
+		if l.Kind != "fund" {
+			continue
+		}
+		// 1.02 over NAV 0.99 (dated 20260106) widened the 2% premium.
+		if l.WindowDays != 1 || math.Abs(l.ExitValue-(1.02-0.99)/0.99*100) > 1e-9 || l.NavDate != "20260106" || l.Reward != 0 || l.Reason != "premium_diverged" {
+			t.Fatalf("fund nav label=%+v", l)
+		}
+	}
+}
+
+func TestPremiumConvergenceWaitsForExitDateNAV(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			APIName string         `json:"api_name"`
+			Params  map[string]any `json:"params"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		data := map[string]any{"fields": []string{"ts_code", "trade_date", "close"}, "items": [][]any{}}
+		switch req.APIName {
+		case "fund_daily":
+			data["items"] = [][]any{{req.Params["ts_code"], "20260105", 1.02}, {req.Params["ts_code"], "20260106", 1.02}, {req.Params["ts_code"], "20260107", 1.02}}
+		case "fund_nav": // no NAV for the exit date 20260107 yet
+			data = map[string]any{"fields": []string{"ts_code", "nav_date", "unit_nav"}, "items": [][]any{
+				{req.Params["ts_code"], "20260102", 1.0},
+				{req.Params["ts_code"], "20260105", 1.0},
+				{req.Params["ts_code"], "20260106", 1.0},
+			}}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"code": 0, "data": data})
+	}))
+	defer srv.Close()
+
+	tmp := t.TempDir()
+	paper := filepath.Join(tmp, "paper.jsonl")
+	labelsPath := filepath.Join(tmp, "labels.jsonl")
+	// 510300 priced on a same-day NAV; 513100 (QDII) ran on a NAV 3 days old.
+	content := `{"ts":"2026-01-05T15:30:00+08:00","event":{"source":"fund_premium","trade_date":"20260105","market":"CN-A","symbol":"510300.SH","title":"fund","tags":{"kind":"fund"},"data":{"premium_pct":2.0,"side":"premium","nav_date":"20260105","nav_lag_days":0}}}
+{"ts":"2026-01-05T15:30:00+08:00","event":{"source":"fund_premium","trade_date":"20260105","market":"CN-A","symbol":"513100.SH","title":"fund","tags":{"kind":"fund"},"data":{"premium_pct":2.0,"side":"premium","nav_date":"20260102","nav_lag_days":3}}}
+`
+	if err := os.WriteFile(paper, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	lcfg := DefaultConfig()
+	lcfg.DayWindows = []int{2}
+	lcfg.Now = func() time.Time { return time.Date(2026, 1, 8, 9, 0, 0, 0, time.UTC) }
+	lcfg.Tushare = tushare.New(tushare.Options{BaseURL: srv.URL, Token: "t"})
+
+	wrote, _, err := New(&config.Config{}, nil, lcfg).RunOnce(context.Background(), paper, labelsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 510300 has no NAV dated 20260107 and no lag allowance: not ready, even
+	// though the 20260106 NAV is newer than the event's.
+	if wrote != 1 {
+		t.Fatalf("wrote=%d want=1", wrote)
+	}
+	l := readLabels(t, labelsPath)[0]
+	if l.Symbol != "513100.SH" || l.NavDate != "20260106" || math.Abs(l.ExitValue-2) > 1e-9 {
+		t.Fatalf("lagging fund label=%+v", l)
+	}
+}
@@ -8,14 +8,41 @@ type Label struct {
 	Source    string    `json:"source"`
 	Symbol    string    `json:"symbol"`
 	TradeDate string    `json:"trade_date"`
+	Kind      string    `json:"kind"`      // event Tags["kind"]
+	RewardFn  string    `json:"reward_fn"` // registered reward that produced the label
 
-	WindowSec int `json:"window_sec"`
-	GraceSec  int `json:"grace_sec"`
-	LateBySec int `json:"late_by_sec"`
+	// WindowSec keys labels per event. Trade-day windows set WindowDays and
+	// use WindowDays*86400.
+	WindowSec  int `json:"window_sec"`
+	WindowDays int `json:"window_days,omitempty"`
+	GraceSec   int `json:"grace_sec"`
+	LateBySec  int `json:"late_by_sec"`
 
-	Threshold    float64 `json:"threshold"`
-	EntryRatePct float64 `json:"entry_rate_pct"`
-	ExitRatePct  float64 `json:"exit_rate_pct"`
+	Threshold float64 `json:"threshold"`
+
+	// Metric names what EntryValue/ExitValue measure: rate_pct (repo),
+	// premium_pct (premium_convergence) or close (price_return).
+	Metric     string  `json:"metric"`
+	EntryValue float64 `json:"entry_value"`
+	ExitValue  float64 `json:"exit_value"`
+
+	// Price return rewards only: direction-adjusted return, round-trip costs
+	// and their difference, in pct.
+	ReturnPct    float64 `json:"return_pct,omitempty"`
+	CostPct      float64 `json:"cost_pct,omitempty"`
+	NetReturnPct float64 `json:"net_return_pct,omitempty"`
+
+	// Repo rewards also keep their original field names.
+	EntryRatePct float64 `json:"entry_rate_pct,omitempty"`
+	ExitRatePct  float64 `json:"exit_rate_pct,omitempty"`
+
+	// ExitTS is when the exit value was observed: the fetch time live, the
+	// recorded tick's time offline (at or before event_ts + window), the
+	// exit day's close for trade-day windows.
+	ExitTS time.Time `json:"exit_ts"`
+
+	// NavDate is the NAV a fund premium_convergence exit was priced on.
+	NavDate string `json:"nav_date,omitempty"`
 
 	Confidence string `json:"confidence"`
 	Reward     int    `json:"reward"`
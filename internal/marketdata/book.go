@@ -0,0 +1,116 @@
+package marketdata
+
+import (
+	"math"
+
+	"value-sniffer-radar/internal/repo"
+)
+
+// Side is the direction of a hypothetical trade.
+type Side string
+
+const (
+	Buy  Side = "buy"  // lifts the asks
+	Sell Side = "sell" // hits the bids
+)
+
+// LotValue is the CNY notional of one lot of symbol at price: 100 shares for
+// stocks and funds, 10 bonds for convertibles, and the registered lot face for
+// repos (whose "price" is a rate): 1000 CNY on SSE, 100 CNY on SZSE.
+func (c Class) LotValue(symbol string, price float64) float64 {
+	switch c {
+	case ClassRepo:
+		if in, ok := repo.Lookup(symbol); ok {
+			return in.LotNotional
+		}
+		return 1000
+	case ClassCB:
+		return price * 10
+	default:
+		return price * 100
+	}
+}
+
+// Execution estimates what trading notional against a fused book would cost.
+// Costs are in pct of the mid price, except for repos where they are in rate
+// points (the unit repo edges are measured in).
+type Execution struct {
+	Symbol   string
+	Side     Side
+	Notional float64
+
+	Mid   float64
+	Touch float64 // best ask (buy) / best bid (sell)
+	VWAP  float64 // average price over the levels needed to fill Notional
+
+	SpreadPct     float64 // mid -> touch, i.e. half the quoted spread
+	SlippagePct   float64 // touch -> VWAP when the touch is too thin
+	DepthNotional float64 // CNY available on the trade side across the book
+
+	HasBook     bool
+	LiquidityOK bool // book present and deep enough to fill Notional
+}
+
+// EstimateExecution walks the trade side of fs.Book for notional CNY. Without a
+// book it falls back to bid1/ask1 and their sizes from the quote, so depth and
+// LiquidityOK only count the touch.
+func EstimateExecution(fs FusionSnapshot, side Side, notional float64) Execution {
+	ex := Execution{Symbol: fs.Symbol, Side: side, Notional: notional}
+	bids, asks := fs.Book.Bids, fs.Book.Asks
+	if len(bids) == 0 && fs.Quote.Bid1 > 0 {
+		bids = []Level{{Price: fs.Quote.Bid1, Size: fs.Quote.Bid1Size}}
+	}
+	if len(asks) == 0 && fs.Quote.Ask1 > 0 {
+		asks = []Level{{Price: fs.Quote.Ask1, Size: fs.Quote.Ask1Size}}
+	}
+	if len(bids) == 0 || len(asks) == 0 || asks[0].Price < bids[0].Price {
+		return ex
+	}
+	ex.HasBook = true
+	ex.Mid = (bids[0].Price + asks[0].Price) / 2
+
+	levels := asks
+	if side == Sell {
+		levels = bids
+	}
+	ex.Touch = levels[0].Price
+
+	remaining := notional
+	var cost, filled float64 // price-weighted notional, notional
+	for _, l := range levels {
+		avail := l.Size * fs.Class.LotValue(fs.Symbol, l.Price)
+		ex.DepthNotional += avail
+		if remaining <= 0 {
+			continue
+		}
+		take := math.Min(avail, remaining)
+		cost += take * l.Price
+		filled += take
+		remaining -= take
+	}
+	ex.VWAP = ex.Touch
+	if filled > 0 {
+		ex.VWAP = cost / filled
+	}
+	ex.LiquidityOK = notional <= 0 || remaining <= 1e-9
+	if in, ok := repo.Lookup(fs.Symbol); ok && fs.Class == ClassRepo && notional > 0 && notional < in.MinNotional {
+		// The exchange rejects repo orders below the minimum.
+		ex.LiquidityOK = false
+	}
+
+	ex.SpreadPct = costPct(fs.Class, ex.Touch, ex.Mid, ex.Mid)
+	ex.SlippagePct = costPct(fs.Class, ex.VWAP, ex.Touch, ex.Mid)
+	return ex
+}
+
+// costPct is the adverse distance from ref to px, relative to mid (rate points for repos).
+func costPct(c Class, px, ref, mid float64) float64 {
+	d := math.Abs(px - ref)
+	if c == ClassRepo {
+		return d
+	}
+	if mid <= 0 {
+		return 0
+	}
+	return d / mid * 100.0
+}
@@ -0,0 +1,66 @@
+package marketdata
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEstimateExecutionWalksBook(t *testing.T) {
+	fs := FusionSnapshot{
+		Class: ClassETF,
+		Book: Book{
+			Bids: []Level{{Price: 3.999, Size: 500}},
+			Asks: []Level{{Price: 4.001, Size: 100}, {Price: 4.002, Size: 200}, {Price: 4.004, Size: 1000}},
+		},
+	}
+	// 100 lots at 4.001 = 40,010 CNY; the next 200 lots at 4.002 cover the rest of 100k.
+	ex := EstimateExecution(fs, Buy, 100000)
+	if !ex.HasBook || !ex.LiquidityOK {
+		t.Fatalf("ex=%+v", ex)
+	}
+	approx := func(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+	if !approx(ex.Mid, 4.0) || !approx(ex.SpreadPct, 0.001/4.0*100) {
+		t.Fatalf("mid=%v spread=%v", ex.Mid, ex.SpreadPct)
+	}
+	wantVWAP := (40010*4.001 + 59990*4.002) / 100000
+	if !approx(ex.VWAP, wantVWAP) || !approx(ex.SlippagePct, (wantVWAP-4.001)/4.0*100) {
+		t.Fatalf("vwap=%v slippage=%v", ex.VWAP, ex.SlippagePct)
+	}
+
+	// Bids hold ~200k CNY; a 500k sell cannot be filled.
+	if ex := EstimateExecution(fs, Sell, 500000); ex.LiquidityOK || !approx(ex.DepthNotional, 500*3.999*100) {
+		t.Fatalf("sell ex=%+v", ex)
+	}
+
+	// Repo costs are in rate points, sized by 1000 CNY lots on SSE.
+	repo := FusionSnapshot{Symbol: "204001.SH", Class: ClassRepo, Quote: Quote{Bid1: 2.10, Bid1Size: 5000, Ask1: 2.14, Ask1Size: 5000}}
+	if ex := EstimateExecution(repo, Sell, 1e6); !ex.LiquidityOK || !approx(ex.SpreadPct, 0.02) || ex.SlippagePct != 0 {
+		t.Fatalf("repo ex=%+v", ex)
+	}
+	// SZSE lots are 100 CNY: the same 5000 lots hold 500k only.
+	repo.Symbol = "131810.SZ"
+	if ex := EstimateExecution(repo, Sell, 1e6); ex.LiquidityOK || !approx(ex.DepthNotional, 5e5) {
+		t.Fatalf("sz repo ex=%+v", ex)
+	}
+	// Below the 1000 CNY minimum order nothing can be placed.
+	if ex := EstimateExecution(repo, Sell, 500); ex.LiquidityOK {
+		t.Fatalf("below min notional ex=%+v", ex)
+	}
+
+	if ex := EstimateExecution(FusionSnapshot{Quote: Quote{Last: 1}}, Buy, 1); ex.HasBook || ex.LiquidityOK {
+		t.Fatalf("no book: %+v", ex)
+	}
+}
+
+func TestTencentBook(t *testing.T) {
+	parts := make([]string, 30)
+	for i := range parts {
+		parts[i] = "0"
+	}
+	parts[9], parts[10], parts[11], parts[12] = "4.011", "800", "4.010", "300"
+	parts[19], parts[20] = "4.013", "900"
+	b := tencentBook(parts)
+	if len(b.Bids) != 2 || len(b.Asks) != 1 || b.Bids[1] != (Level{Price: 4.010, Size: 300}) {
+		t.Fatalf("book=%+v", b)
+	}
+}
@@ -21,13 +21,15 @@ func Build(cfg config.MarketdataConfig) (Fusion, error) {
 				BaseURL:     pc.BaseURL,
 				Fields:      pc.Fields,
 				RateDivisor: pc.RateDivisor,
+				IOPVField:   pc.IOPVField,
 				Timeout:     time.Duration(cfg.TimeoutMS) * time.Millisecond,
 			}))
 		case "tencent_repo":
 			providers = append(providers, NewTencentRepo(TencentRepoOptions{
-				Name:     pc.Name,
-				QuoteURL: pc.QuoteURL,
-				Timeout:  time.Duration(cfg.TimeoutMS) * time.Millisecond,
+				Name:      pc.Name,
+				QuoteURL:  pc.QuoteURL,
+				IOPVIndex: pc.IOPVIndex,
+				Timeout:   time.Duration(cfg.TimeoutMS) * time.Millisecond,
 			}))
 		default:
 			return nil, fmt.Errorf("marketdata unknown provider type: %s", pc.Type)
@@ -40,6 +42,8 @@ func Build(cfg config.MarketdataConfig) (Fusion, error) {
 		Staleness:        time.Duration(cfg.StalenessSec) * time.Second,
 		MinValid:         cfg.MinValid,
 		MaxValid:         cfg.MaxValid,
+		MaxRelDiffPct:    cfg.MaxRelDiffPct,
+		ValidRanges:      validRanges(cfg.ValidRanges),
 		FailThreshold:    cfg.FailThreshold,
 		OutlierThreshold: cfg.OutlierThreshold,
 		Cooldown:         time.Duration(cfg.CooldownSec) * time.Second,
@@ -47,6 +51,16 @@ func Build(cfg config.MarketdataConfig) (Fusion, error) {
 	if err != nil {
 		return nil, err
 	}
+	if cfg.Record.Enabled {
+		return NewRecorder(f, cfg.Record.Dir), nil
+	}
 	return f, nil
 }
 
+func validRanges(in map[string]config.MarketdataValidRange) map[Class]ValidRange {
+	out := make(map[Class]ValidRange, len(in))
+	for k, r := range in {
+		out[Class(k)] = ValidRange{Min: r.Min, Max: r.Max}
+	}
+	return out
+}
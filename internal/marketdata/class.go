@@ -0,0 +1,76 @@
+package marketdata
+
+import (
+	"math"
+	"strings"
+)
+
+// Class is the instrument class of a symbol; it selects validity ranges and
+// the consensus tolerance used by fusion.
+type Class string
+
+const (
+	ClassUnknown Class = "unknown"
+	ClassRepo    Class = "repo"  // exchange reverse repo (SH 204xxx, SZ 1318xx); last is a rate in pct
+	ClassCB      Class = "cb"    // convertible bonds (SH 110/111/113/118, SZ 123/127/128)
+	ClassETF     Class = "etf"   // exchange-traded funds and LOFs (SH 5xxxxx, SZ 15/16xxxx)
+	ClassStock   Class = "stock" // A-shares (SH 60/68, SZ 00/30, BJ)
+)
+
+// Classes lists the configurable classes.
+var Classes = []Class{ClassRepo, ClassCB, ClassETF, ClassStock, ClassUnknown}
+
+// ValidRange bounds the price-like fields of a quote.
+type ValidRange struct {
+	Min float64
+	Max float64
+}
+
+func (r ValidRange) contains(v float64) bool {
+	return v > r.Min && v <= r.Max && !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+// DefaultValidRanges are deliberately wide; they reject unit mistakes (scaled
+// ints, rates vs prices), not market moves. Repo uses FusionConfig.MinValid/MaxValid.
+var DefaultValidRanges = map[Class]ValidRange{
+	ClassCB:      {Min: 10, Max: 5000},
+	ClassETF:     {Min: 0.001, Max: 1000},
+	ClassStock:   {Min: 0.01, Max: 10000},
+	ClassUnknown: {Min: 0, Max: math.MaxFloat64},
+}
+
+// ClassOf derives the instrument class from a "123456.SH" style symbol.
+func ClassOf(symbol string) Class {
+	s := strings.ToUpper(strings.TrimSpace(symbol))
+	code, suffix, ok := strings.Cut(s, ".")
+	if !ok || len(code) != 6 {
+		return ClassUnknown
+	}
+	switch suffix {
+	case "SH":
+		switch {
+		case strings.HasPrefix(code, "204"):
+			return ClassRepo
+		case strings.HasPrefix(code, "110"), strings.HasPrefix(code, "111"), strings.HasPrefix(code, "113"), strings.HasPrefix(code, "118"):
+			return ClassCB
+		case strings.HasPrefix(code, "5"):
+			return ClassETF
+		case strings.HasPrefix(code, "60"), strings.HasPrefix(code, "68"):
+			return ClassStock
+		}
+	case "SZ":
+		switch {
+		case strings.HasPrefix(code, "1318"):
+			return ClassRepo
+		case strings.HasPrefix(code, "123"), strings.HasPrefix(code, "127"), strings.HasPrefix(code, "128"):
+			return ClassCB
+		case strings.HasPrefix(code, "15"), strings.HasPrefix(code, "16"):
+			return ClassETF
+		case strings.HasPrefix(code, "00"), strings.HasPrefix(code, "30"):
+			return ClassStock
+		}
+	case "BJ":
+		return ClassStock
+	}
+	return ClassUnknown
+}
@@ -15,9 +15,14 @@ type FusionConfig struct {
 	RequiredSources int
 	MaxAbsDiff      float64
 	Staleness       time.Duration
-	MinValid        float64
+	MinValid        float64 // repo rate range (pct points)
 	MaxValid        float64
 
+	// Non-repo classes: inlier tolerance relative to the median (pct), default 0.1;
+	// and price validity ranges per class (defaults: DefaultValidRanges).
+	MaxRelDiffPct float64
+	ValidRanges   map[Class]ValidRange
+
 	FailThreshold    int
 	OutlierThreshold int
 	Cooldown         time.Duration
@@ -56,6 +61,18 @@ func NewFusion(providers []Provider, cfg FusionConfig) (*FusionEngine, error) {
 	if cfg.MaxValid <= 0 {
 		cfg.MaxValid = 20
 	}
+	if cfg.MaxRelDiffPct <= 0 {
+		cfg.MaxRelDiffPct = 0.1
+	}
+	ranges := make(map[Class]ValidRange, len(DefaultValidRanges)+1)
+	for k, v := range DefaultValidRanges {
+		ranges[k] = v
+	}
+	ranges[ClassRepo] = ValidRange{Min: cfg.MinValid, Max: cfg.MaxValid}
+	for k, v := range cfg.ValidRanges {
+		ranges[k] = v
+	}
+	cfg.ValidRanges = ranges
 	if cfg.FailThreshold <= 0 {
 		cfg.FailThreshold = 3
 	}
@@ -121,9 +138,11 @@ func (f *FusionEngine) FetchFusion(ctx context.Context, symbol string) (FusionSn
 	wg.Wait()
 	close(outs)
 
-	// Collect, then do quality filtering.
-	var candidates []float64
-	candidateByProvider := map[string]float64{}
+	// Collect, then do quality filtering. Last is required; other price fields
+	// outside the class range are dropped individually.
+	class := ClassOf(symbol)
+	valid := f.cfg.ValidRanges[class]
+	var quotes []Quote
 	for o := range outs {
 		if o.err != nil {
 			results = append(results, ProviderResult{
@@ -137,9 +156,7 @@ func (f *FusionEngine) FetchFusion(ctx context.Context, symbol string) (FusionSn
 		if !o.snap.TS.IsZero() && now.Sub(o.snap.TS) > f.cfg.Staleness {
 			stale = true
 		}
-		r := o.snap.RatePct
-		invalid := (r < f.cfg.MinValid) || (r > f.cfg.MaxValid) || math.IsNaN(r) || math.IsInf(r, 0)
-		if stale || invalid {
+		if stale || !valid.contains(o.snap.Quote.Last) {
 			results = append(results, ProviderResult{
 				Provider: o.name,
 				Snapshot: o.snap,
@@ -148,18 +165,20 @@ func (f *FusionEngine) FetchFusion(ctx context.Context, symbol string) (FusionSn
 			})
 			continue
 		}
-		candidates = append(candidates, r)
-		candidateByProvider[o.name] = r
+		o.snap.Quote = sanitizeQuote(o.snap.Quote, valid)
+		o.snap.Book = sanitizeBook(o.snap.Book, valid)
+		quotes = append(quotes, o.snap.Quote)
 		results = append(results, ProviderResult{
 			Provider: o.name,
 			Snapshot: o.snap,
 		})
 	}
 
-	if len(candidates) == 0 {
+	if len(quotes) == 0 {
 		f.updateStates(now, results, nil)
 		return FusionSnapshot{
 			Symbol:     symbol,
+			Class:      class,
 			TS:         now,
 			Confidence: ConfidenceFail,
 			Reason:     "no_valid_sources",
@@ -167,11 +186,18 @@ func (f *FusionEngine) FetchFusion(ctx context.Context, symbol string) (FusionSn
 		}, nil
 	}
 
-	consensus := median(candidates)
+	consensus := fuseQuotes(quotes)
 	inliers := map[string]bool{}
-	for name, val := range candidateByProvider {
-		if math.Abs(val-consensus) <= f.cfg.MaxAbsDiff {
-			inliers[name] = true
+	for i := range results {
+		pr := &results[i]
+		if pr.Error != "" {
+			continue
+		}
+		if f.agrees(class, pr.Snapshot.Quote, consensus) {
+			pr.Inlier = true
+			inliers[pr.Provider] = true
+		} else {
+			pr.Outlier = true
 		}
 	}
 
@@ -182,31 +208,132 @@ func (f *FusionEngine) FetchFusion(ctx context.Context, symbol string) (FusionSn
 		reason = "consensus_pass"
 	}
 
-	// Mark inlier/outlier in ProviderResult.
-	for i := range results {
-		pr := &results[i]
-		if pr.Error != "" {
-			continue
+	// agrees skips fields a provider does not report, so inliers vouch for
+	// Last (which decides Confidence) but not necessarily for the rest: any
+	// other consensus field fewer than RequiredSources inliers reported is
+	// unconfirmed and dropped.
+	sources := make(map[Field]int, len(consensusFields))
+	for _, fld := range consensusFields {
+		for _, pr := range results {
+			if pr.Inlier && pr.Snapshot.Quote.Get(fld) != 0 {
+				sources[fld]++
+			}
 		}
-		if inliers[pr.Provider] {
-			pr.Inlier = true
-		} else {
-			pr.Outlier = true
+		if fld != FieldLast && sources[fld] < f.cfg.RequiredSources {
+			*consensus.ptr(fld) = 0
 		}
 	}
 
+	book, bookProvider := f.pickBook(results)
 	f.updateStates(now, results, inliers)
 
 	return FusionSnapshot{
 		Symbol:           symbol,
+		Class:            class,
 		TS:               now,
-		ConsensusRatePct: consensus,
+		Quote:            consensus,
+		Book:             book,
+		BookProvider:     bookProvider,
+		ConsensusRatePct: consensus.Last,
 		Confidence:       conf,
 		Reason:           reason,
+		Sources:          sources,
 		Providers:        results,
 	}, nil
 }
 
+// consensusFields decide inlier/outlier status; the rest are fused but informational.
+var consensusFields = []Field{FieldLast, FieldIOPV}
+
+// agrees reports whether q is within tolerance of the consensus on every
+// consensus field it reports. Repo rates use an absolute tolerance (pct points),
+// other classes a relative one.
+func (f *FusionEngine) agrees(class Class, q, consensus Quote) bool {
+	for _, fld := range consensusFields {
+		v, c := q.Get(fld), consensus.Get(fld)
+		if v == 0 || c == 0 {
+			continue
+		}
+		if class == ClassRepo {
+			if math.Abs(v-c) > f.cfg.MaxAbsDiff {
+				return false
+			}
+		} else if math.Abs(v-c)/math.Abs(c)*100.0 > f.cfg.MaxRelDiffPct {
+			return false
+		}
+	}
+	return true
+}
+
+// pickBook returns the book of the highest-scoring inlier that reported one
+// (ties broken by provider name for determinism).
+func (f *FusionEngine) pickBook(results []ProviderResult) (Book, string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	best := -1
+	bestScore := 0.0
+	for i, pr := range results {
+		if !pr.Inlier || len(pr.Snapshot.Book.Bids)+len(pr.Snapshot.Book.Asks) == 0 {
+			continue
+		}
+		score := 0.0
+		if st := f.state[pr.Provider]; st != nil {
+			score = st.score
+		}
+		if best < 0 || score > bestScore || (score == bestScore && pr.Provider < results[best].Provider) {
+			best, bestScore = i, score
+		}
+	}
+	if best < 0 {
+		return Book{}, ""
+	}
+	return results[best].Snapshot.Book, results[best].Provider
+}
+
+// sanitizeBook drops levels with invalid prices or non-positive sizes.
+func sanitizeBook(b Book, r ValidRange) Book {
+	keep := func(ls []Level) []Level {
+		var out []Level
+		for _, l := range ls {
+			if r.contains(l.Price) && l.Size > 0 && !math.IsInf(l.Size, 0) {
+				out = append(out, l)
+			}
+		}
+		return out
+	}
+	return Book{Bids: keep(b.Bids), Asks: keep(b.Asks)}
+}
+
+// sanitizeQuote zeroes (drops) price fields outside r and any NaN/Inf/negative field.
+func sanitizeQuote(q Quote, r ValidRange) Quote {
+	for _, fld := range Fields {
+		p := q.ptr(fld)
+		v := *p
+		if v == 0 {
+			continue
+		}
+		if math.IsNaN(v) || math.IsInf(v, 0) || v < 0 || (fld.IsPrice() && !r.contains(v)) {
+			*p = 0
+		}
+	}
+	return q
+}
+
+// fuseQuotes takes the per-field median over the quotes that report each field.
+func fuseQuotes(qs []Quote) Quote {
+	var out Quote
+	for _, fld := range Fields {
+		var xs []float64
+		for _, q := range qs {
+			if v := q.Get(fld); v != 0 {
+				xs = append(xs, v)
+			}
+		}
+		*out.ptr(fld) = median(xs)
+	}
+	return out
+}
+
 func (f *FusionEngine) isDisabled(provider string, now time.Time) bool {
 	f.mu.Lock()
 	defer f.mu.Unlock()
@@ -22,7 +22,7 @@ func (p fakeProvider) Fetch(ctx context.Context, symbol string) (Snapshot, error
 		Provider: p.name,
 		Symbol:   symbol,
 		TS:       p.ts,
-		RatePct:  p.rate,
+		Quote:    Quote{Last: p.rate},
 	}, p.err
 }
 
@@ -5,13 +5,102 @@ import (
 	"time"
 )
 
+// Quote is a realtime top-of-book quote. Zero means the provider did not report
+// the field. Prices (Last, Bid1, Ask1, IOPV, PrevClose) are in quote units; for
+// repos Last is the annualized rate in percentage points (1.85 means 1.85%).
+type Quote struct {
+	Last      float64
+	Bid1      float64
+	Ask1      float64
+	Bid1Size  float64 // lots
+	Ask1Size  float64 // lots
+	Volume    float64 // lots
+	Amount    float64 // CNY
+	IOPV      float64 // ETF indicative NAV
+	PrevClose float64
+}
+
+// Field names a Quote field for per-field fusion.
+type Field string
+
+const (
+	FieldLast      Field = "last"
+	FieldBid1      Field = "bid1"
+	FieldAsk1      Field = "ask1"
+	FieldBid1Size  Field = "bid1_size"
+	FieldAsk1Size  Field = "ask1_size"
+	FieldVolume    Field = "volume"
+	FieldAmount    Field = "amount"
+	FieldIOPV      Field = "iopv"
+	FieldPrevClose Field = "prev_close"
+)
+
+// Fields lists every Quote field in a stable order.
+var Fields = []Field{FieldLast, FieldBid1, FieldAsk1, FieldBid1Size, FieldAsk1Size, FieldVolume, FieldAmount, FieldIOPV, FieldPrevClose}
+
+// ptr returns the address of field f, or nil for an unknown field.
+func (q *Quote) ptr(f Field) *float64 {
+	switch f {
+	case FieldLast:
+		return &q.Last
+	case FieldBid1:
+		return &q.Bid1
+	case FieldAsk1:
+		return &q.Ask1
+	case FieldBid1Size:
+		return &q.Bid1Size
+	case FieldAsk1Size:
+		return &q.Ask1Size
+	case FieldVolume:
+		return &q.Volume
+	case FieldAmount:
+		return &q.Amount
+	case FieldIOPV:
+		return &q.IOPV
+	case FieldPrevClose:
+		return &q.PrevClose
+	default:
+		return nil
+	}
+}
+
+// Get returns field f (0 if unknown or not reported).
+func (q Quote) Get(f Field) float64 {
+	if p := q.ptr(f); p != nil {
+		return *p
+	}
+	return 0
+}
+
+// IsPrice reports whether f is quoted in price units (subject to validity ranges).
+func (f Field) IsPrice() bool {
+	switch f {
+	case FieldLast, FieldBid1, FieldAsk1, FieldIOPV, FieldPrevClose:
+		return true
+	default:
+		return false
+	}
+}
+
+// Level is one order book level; Size is in lots.
+type Level struct {
+	Price float64
+	Size  float64
+}
+
+// Book holds up to five levels per side, best first.
+type Book struct {
+	Bids []Level
+	Asks []Level
+}
+
 type Snapshot struct {
 	Provider string
 	Symbol   string
 	TS       time.Time
 
-	// For VS_0005 we start with repo only: rate in percentage points (e.g. 1.85 means 1.85%).
-	RatePct float64
+	Quote Quote
+	Book  Book
 
 	Raw map[string]any
 }
@@ -30,12 +119,24 @@ const (
 
 type FusionSnapshot struct {
 	Symbol string
+	Class  Class
 	TS     time.Time
 
+	// Quote holds the per-field consensus (median of valid sources reporting the field).
+	Quote Quote
+	// Book is taken whole from the healthiest inlier that reported depth;
+	// order books from different sources cannot be mixed level by level.
+	Book         Book
+	BookProvider string
+	// ConsensusRatePct is Quote.Last, kept for repo signals where last is the rate.
 	ConsensusRatePct float64
 	Confidence       Confidence
 	Reason           string
 
+	// Sources counts the inliers reporting each consensus field; Quote leaves
+	// a consensus field zero when fewer than RequiredSources reported it.
+	Sources map[Field]int
+
 	Providers []ProviderResult
 }
 
@@ -52,4 +153,3 @@ type ProviderResult struct {
 type Fusion interface {
 	FetchFusion(ctx context.Context, symbol string) (FusionSnapshot, error)
 }
-
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"net/url"
 	"strings"
@@ -16,6 +17,7 @@ type EastmoneyRepoProvider struct {
 	baseURL     string
 	fields      string
 	rateDivisor float64
+	iopvField   string
 	httpClient  *http.Client
 }
 
@@ -24,6 +26,7 @@ type EastmoneyRepoOptions struct {
 	BaseURL     string
 	Fields      string
 	RateDivisor float64
+	IOPVField   string // quote field id carrying ETF IOPV (optional)
 	Timeout     time.Duration
 }
 
@@ -39,6 +42,7 @@ func NewEastmoneyRepo(opt EastmoneyRepoOptions) *EastmoneyRepoProvider {
 	fields := strings.TrimSpace(opt.Fields)
 	if fields == "" {
 		// f43 is "latest price" in Eastmoney quote responses; for repo we treat it as rate (%).
+		// Quote fields (bid/ask/volume/...) are appended per request.
 		fields = "f43,f57,f58,f59"
 	}
 	div := opt.RateDivisor
@@ -54,6 +58,7 @@ func NewEastmoneyRepo(opt EastmoneyRepoOptions) *EastmoneyRepoProvider {
 		baseURL:     baseURL,
 		fields:      fields,
 		rateDivisor: div,
+		iopvField:   strings.TrimSpace(opt.IOPVField),
 		httpClient: &http.Client{
 			Timeout: to,
 		},
@@ -62,63 +67,158 @@ func NewEastmoneyRepo(opt EastmoneyRepoOptions) *EastmoneyRepoProvider {
 
 func (p *EastmoneyRepoProvider) Name() string { return p.name }
 
+// eastmoneyQuoteFields maps quote fields to Eastmoney field ids (f43 last,
+// f19/f20 bid1 price/size, f39/f40 ask1 price/size, f47 volume, f48 amount,
+// f60 previous close). f59 is the price decimals of the scaled ints.
+var eastmoneyQuoteFields = map[Field]string{
+	FieldLast:      "f43",
+	FieldBid1:      "f19",
+	FieldBid1Size:  "f20",
+	FieldAsk1:      "f39",
+	FieldAsk1Size:  "f40",
+	FieldVolume:    "f47",
+	FieldAmount:    "f48",
+	FieldPrevClose: "f60",
+}
+
 func (p *EastmoneyRepoProvider) Fetch(ctx context.Context, symbol string) (Snapshot, error) {
-	secid, err := eastmoneySecID(symbol)
+	data, err := p.get(ctx, symbol, p.requestFields())
 	if err != nil {
 		return Snapshot{Provider: p.name, Symbol: symbol, TS: time.Now()}, err
 	}
 
+	rawLast, ok := data["f43"]
+	if !ok {
+		return Snapshot{Provider: p.name, Symbol: symbol, TS: time.Now(), Raw: data}, errors.New("eastmoney missing f43")
+	}
+	if _, ok := anyToFloat(rawLast); !ok {
+		return Snapshot{Provider: p.name, Symbol: symbol, TS: time.Now(), Raw: data}, errors.New("eastmoney invalid f43")
+	}
+
+	// Prices come as scaled ints. An explicit rate_divisor wins (legacy repo
+	// configs); otherwise scale by the f59 decimals the response carries.
+	div := p.rateDivisor
+	if div == 1 {
+		if dec, ok := anyToFloat(data["f59"]); ok && dec > 0 && dec < 10 {
+			div = math.Pow(10, dec)
+		}
+	}
+
+	var q Quote
+	fields := eastmoneyQuoteFields
+	if p.iopvField != "" {
+		fields = make(map[Field]string, len(eastmoneyQuoteFields)+1)
+		for k, v := range eastmoneyQuoteFields {
+			fields[k] = v
+		}
+		fields[FieldIOPV] = p.iopvField
+	}
+	for fld, id := range fields {
+		v, ok := anyToFloat(data[id]) // "-" (no value) fails to parse and stays 0
+		if !ok {
+			continue
+		}
+		if fld.IsPrice() && div != 0 {
+			v = v / div
+		}
+		*q.ptr(fld) = v
+	}
+
+	return Snapshot{
+		Provider: p.name,
+		Symbol:   symbol,
+		TS:       time.Now(),
+		Quote:    q,
+		Book:     eastmoneyBook(data, div),
+		Raw:      data,
+	}, nil
+}
+
+// Five-level depth, best first, as (price, size) field id pairs.
+var (
+	eastmoneyBidLevels = [][2]string{{"f19", "f20"}, {"f17", "f18"}, {"f15", "f16"}, {"f13", "f14"}, {"f11", "f12"}}
+	eastmoneyAskLevels = [][2]string{{"f39", "f40"}, {"f37", "f38"}, {"f35", "f36"}, {"f33", "f34"}, {"f31", "f32"}}
+)
+
+func eastmoneyBook(data map[string]any, div float64) Book {
+	side := func(ids [][2]string) []Level {
+		var out []Level
+		for _, id := range ids {
+			px, ok1 := anyToFloat(data[id[0]])
+			sz, ok2 := anyToFloat(data[id[1]])
+			if !ok1 || !ok2 || px <= 0 || sz <= 0 {
+				break
+			}
+			if div != 0 {
+				px = px / div
+			}
+			out = append(out, Level{Price: px, Size: sz})
+		}
+		return out
+	}
+	return Book{Bids: side(eastmoneyBidLevels), Asks: side(eastmoneyAskLevels)}
+}
+
+// requestFields is the configured field list plus the ids needed for a full quote.
+func (p *EastmoneyRepoProvider) requestFields() string {
+	ids := strings.Split(p.fields, ",")
+	seen := map[string]bool{}
+	for _, id := range ids {
+		seen[strings.TrimSpace(id)] = true
+	}
+	extra := []string{"f43", "f59", "f47", "f48", "f60"}
+	for i := range eastmoneyBidLevels {
+		extra = append(extra, eastmoneyBidLevels[i][0], eastmoneyBidLevels[i][1], eastmoneyAskLevels[i][0], eastmoneyAskLevels[i][1])
+	}
+	if p.iopvField != "" {
+		extra = append(extra, p.iopvField)
+	}
+	for _, id := range extra {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return strings.Join(ids, ",")
+}
+
+func (p *EastmoneyRepoProvider) get(ctx context.Context, symbol, fields string) (map[string]any, error) {
+	secid, err := eastmoneySecID(symbol)
+	if err != nil {
+		return nil, err
+	}
 	u, err := url.Parse(p.baseURL)
 	if err != nil {
-		return Snapshot{Provider: p.name, Symbol: symbol, TS: time.Now()}, err
+		return nil, err
 	}
 	q := u.Query()
 	q.Set("secid", secid)
-	q.Set("fields", p.fields)
+	q.Set("fields", fields)
 	u.RawQuery = q.Encode()
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
 	if err != nil {
-		return Snapshot{Provider: p.name, Symbol: symbol, TS: time.Now()}, err
+		return nil, err
 	}
 	resp, err := p.httpClient.Do(req)
 	if err != nil {
-		return Snapshot{Provider: p.name, Symbol: symbol, TS: time.Now()}, err
+		return nil, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return Snapshot{Provider: p.name, Symbol: symbol, TS: time.Now()}, fmt.Errorf("eastmoney http status=%s", resp.Status)
+		return nil, fmt.Errorf("eastmoney http status=%s", resp.Status)
 	}
 
 	var payload struct {
 		Data map[string]any `json:"data"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
-		return Snapshot{Provider: p.name, Symbol: symbol, TS: time.Now()}, err
+		return nil, err
 	}
 	if payload.Data == nil {
-		return Snapshot{Provider: p.name, Symbol: symbol, TS: time.Now()}, errors.New("eastmoney missing data")
+		return nil, errors.New("eastmoney missing data")
 	}
-
-	rawRate, ok := payload.Data["f43"]
-	if !ok {
-		return Snapshot{Provider: p.name, Symbol: symbol, TS: time.Now(), Raw: payload.Data}, errors.New("eastmoney missing f43")
-	}
-	rate, ok := anyToFloat(rawRate)
-	if !ok {
-		return Snapshot{Provider: p.name, Symbol: symbol, TS: time.Now(), Raw: payload.Data}, errors.New("eastmoney invalid f43")
-	}
-	if p.rateDivisor != 0 {
-		rate = rate / p.rateDivisor
-	}
-
-	return Snapshot{
-		Provider: p.name,
-		Symbol:   symbol,
-		TS:       time.Now(),
-		RatePct:  rate,
-		Raw:      payload.Data,
-	}, nil
+	return payload.Data, nil
 }
 
 func eastmoneySecID(symbol string) (string, error) {
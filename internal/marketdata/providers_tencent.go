@@ -14,13 +14,15 @@ import (
 type TencentRepoProvider struct {
 	name       string
 	quoteURL   string
+	iopvIndex  int
 	httpClient *http.Client
 }
 
 type TencentRepoOptions struct {
-	Name     string
-	QuoteURL string
-	Timeout  time.Duration
+	Name      string
+	QuoteURL  string
+	IOPVIndex int // "~"-separated field index carrying ETF IOPV (optional)
+	Timeout   time.Duration
 }
 
 func NewTencentRepo(opt TencentRepoOptions) *TencentRepoProvider {
@@ -37,8 +39,9 @@ func NewTencentRepo(opt TencentRepoOptions) *TencentRepoProvider {
 		to = 1500 * time.Millisecond
 	}
 	return &TencentRepoProvider{
-		name:     name,
-		quoteURL: quoteURL,
+		name:      name,
+		quoteURL:  quoteURL,
+		iopvIndex: opt.IOPVIndex,
 		httpClient: &http.Client{
 			Timeout: to,
 		},
@@ -97,15 +100,18 @@ func (p *TencentRepoProvider) Fetch(ctx context.Context, symbol string) (Snapsho
 		if line == "" {
 			continue
 		}
-		rate, raw, err := parseTencentLine(line)
+		last, raw, err := parseTencentLine(line)
 		if err != nil {
 			continue
 		}
+		q := tencentQuote(raw, p.iopvIndex)
+		q.Last = last
 		return Snapshot{
 			Provider: p.name,
 			Symbol:   symbol,
 			TS:       time.Now(),
-			RatePct:  rate,
+			Quote:    q,
+			Book:     tencentBook(raw),
 			Raw: map[string]any{
 				"line":   line,
 				"fields": raw,
@@ -159,3 +165,48 @@ func parseTencentLine(line string) (float64, []string, error) {
 	}
 	return rate, parts, nil
 }
+
+// tencentQuote reads the optional quote fields: [4] previous close, [6] volume
+// (lots), [9]/[10] bid1 price/size, [19]/[20] ask1 price/size, [37] amount (10k CNY).
+// Missing or unparsable fields stay 0.
+func tencentQuote(parts []string, iopvIndex int) Quote {
+	at := func(i int) float64 {
+		if i <= 0 || i >= len(parts) {
+			return 0
+		}
+		v, _ := anyToFloat(parts[i])
+		return v
+	}
+	return Quote{
+		PrevClose: at(4),
+		Volume:    at(6),
+		Bid1:      at(9),
+		Bid1Size:  at(10),
+		Ask1:      at(19),
+		Ask1Size:  at(20),
+		Amount:    at(37) * 10000,
+		IOPV:      at(iopvIndex),
+	}
+}
+
+// tencentBook reads five-level depth: bids at [9..18], asks at [19..28] as
+// (price, size) pairs, best first.
+func tencentBook(parts []string) Book {
+	side := func(start int) []Level {
+		var out []Level
+		for i := 0; i < 5; i++ {
+			pi, si := start+2*i, start+2*i+1
+			if si >= len(parts) {
+				break
+			}
+			px, ok1 := anyToFloat(parts[pi])
+			sz, ok2 := anyToFloat(parts[si])
+			if !ok1 || !ok2 || px <= 0 || sz <= 0 {
+				break
+			}
+			out = append(out, Level{Price: px, Size: sz})
+		}
+		return out
+	}
+	return Book{Bids: side(9), Asks: side(19)}
+}
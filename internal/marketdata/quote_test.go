@@ -0,0 +1,170 @@
+package marketdata
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type fakeQuoteProvider struct {
+	name string
+	q    Quote
+	ts   time.Time
+}
+
+func (p fakeQuoteProvider) Name() string { return p.name }
+
+func (p fakeQuoteProvider) Fetch(_ context.Context, symbol string) (Snapshot, error) {
+	return Snapshot{Provider: p.name, Symbol: symbol, TS: p.ts, Quote: p.q}, nil
+}
+
+func TestClassOf(t *testing.T) {
+	cases := map[string]Class{
+		"204001.SH": ClassRepo,
+		"131810.SZ": ClassRepo,
+		"113050.SH": ClassCB,
+		"123100.SZ": ClassCB,
+		"510300.SH": ClassETF,
+		"159915.SZ": ClassETF,
+		"600000.SH": ClassStock,
+		"300750.SZ": ClassStock,
+		"x":         ClassUnknown,
+	}
+	for sym, want := range cases {
+		if got := ClassOf(sym); got != want {
+			t.Fatalf("ClassOf(%s)=%s want=%s", sym, got, want)
+		}
+	}
+}
+
+func TestFusionPerFieldConsensusForCB(t *testing.T) {
+	now := time.Date(2026, 1, 29, 10, 0, 0, 0, time.FixedZone("CST", 8*3600))
+	f, err := NewFusion([]Provider{
+		fakeQuoteProvider{name: "a", ts: now, q: Quote{Last: 120.10, Bid1: 120.05, Ask1: 120.12, Bid1Size: 30, Volume: 1000}},
+		fakeQuoteProvider{name: "b", ts: now, q: Quote{Last: 120.12, Bid1: 120.07, Ask1: 120.14, PrevClose: 119.5}},
+		fakeQuoteProvider{name: "c", ts: now, q: Quote{Last: 121.50}},                // outlier (>0.1%)
+		fakeQuoteProvider{name: "d", ts: now, q: Quote{Last: 1.2011}},                // scaled wrong: outside cb range
+		fakeQuoteProvider{name: "e", ts: now, q: Quote{Last: 120.11, Ask1: 99999.0}}, // bad ask dropped, last kept
+	}, FusionConfig{RequiredSources: 2, Now: func() time.Time { return now }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs, err := f.FetchFusion(context.Background(), "113050.SH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fs.Class != ClassCB || fs.Confidence != ConfidencePass {
+		t.Fatalf("class=%s confidence=%s reason=%s", fs.Class, fs.Confidence, fs.Reason)
+	}
+	q := fs.Quote
+	approx := func(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+	if !approx(q.Last, 120.115) || !approx(q.Bid1, 120.06) || !approx(q.Ask1, 120.13) || q.Bid1Size != 30 || q.PrevClose != 119.5 {
+		t.Fatalf("consensus quote=%+v", q)
+	}
+	status := map[string]ProviderResult{}
+	for _, pr := range fs.Providers {
+		status[pr.Provider] = pr
+	}
+	if !status["c"].Outlier || status["d"].Error != "invalid_or_stale" || !status["e"].Inlier {
+		t.Fatalf("provider status=%+v", status)
+	}
+}
+
+func TestFusionETFPriceAndIOPV(t *testing.T) {
+	now := time.Date(2026, 1, 29, 10, 0, 0, 0, time.FixedZone("CST", 8*3600))
+	f, err := NewFusion([]Provider{
+		fakeQuoteProvider{name: "a", ts: now, q: Quote{Last: 4.010, IOPV: 3.970}},
+		fakeQuoteProvider{name: "b", ts: now, q: Quote{Last: 4.011, IOPV: 3.971}},
+		fakeQuoteProvider{name: "c", ts: now, q: Quote{Last: 4.200, IOPV: 3.970}}, // price outlier (>0.1%)
+		fakeQuoteProvider{name: "d", ts: now, q: Quote{Last: 4011}},               // scaled wrong: outside etf range
+		fakeQuoteProvider{name: "e", ts: now, q: Quote{Last: 4.011}},              // no IOPV: counts for price only
+	}, FusionConfig{RequiredSources: 2, Now: func() time.Time { return now }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs, err := f.FetchFusion(context.Background(), "510300.SH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fs.Class != ClassETF || fs.Confidence != ConfidencePass {
+		t.Fatalf("class=%s confidence=%s reason=%s", fs.Class, fs.Confidence, fs.Reason)
+	}
+	if math.Abs(fs.Quote.Last-4.011) > 1e-9 || math.Abs(fs.Quote.IOPV-3.970) > 1e-9 {
+		t.Fatalf("consensus quote=%+v", fs.Quote)
+	}
+	status := map[string]ProviderResult{}
+	for _, pr := range fs.Providers {
+		status[pr.Provider] = pr
+	}
+	if !status["c"].Outlier || status["d"].Error != "invalid_or_stale" || !status["a"].Inlier || !status["e"].Inlier {
+		t.Fatalf("provider status=%+v", status)
+	}
+	if fs.Sources[FieldLast] != 3 || fs.Sources[FieldIOPV] != 2 {
+		t.Fatalf("sources=%v", fs.Sources)
+	}
+}
+
+func TestFusionDropsSingleSourceIOPV(t *testing.T) {
+	now := time.Date(2026, 1, 29, 10, 0, 0, 0, time.FixedZone("CST", 8*3600))
+	f, err := NewFusion([]Provider{
+		fakeQuoteProvider{name: "a", ts: now, q: Quote{Last: 4.010, IOPV: 3.500}},
+		fakeQuoteProvider{name: "b", ts: now, q: Quote{Last: 4.011}},
+		fakeQuoteProvider{name: "c", ts: now, q: Quote{Last: 4.011}},
+	}, FusionConfig{RequiredSources: 2, Now: func() time.Time { return now }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs, err := f.FetchFusion(context.Background(), "510300.SH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The price passes on three sources; the IOPV only one reported is not
+	// consensus and must not reach signals.
+	if fs.Confidence != ConfidencePass || math.Abs(fs.Quote.Last-4.011) > 1e-9 {
+		t.Fatalf("confidence=%s quote=%+v", fs.Confidence, fs.Quote)
+	}
+	if fs.Quote.IOPV != 0 || fs.Sources[FieldIOPV] != 1 {
+		t.Fatalf("single-source iopv kept: quote=%+v sources=%v", fs.Quote, fs.Sources)
+	}
+}
+
+func TestTencentQuoteFields(t *testing.T) {
+	parts := make([]string, 40)
+	for i := range parts {
+		parts[i] = "0"
+	}
+	parts[3], parts[4], parts[6] = "4.012", "4.000", "12345"
+	parts[9], parts[10], parts[19], parts[20] = "4.011", "800", "4.013", "900"
+	parts[37], parts[38] = "4951.2", "3.9876"
+	line := fmt.Sprintf(`v_sh510300="%s";`, joinTilde(parts))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, line)
+	}))
+	defer srv.Close()
+
+	p := NewTencentRepo(TencentRepoOptions{QuoteURL: srv.URL + "/q=", IOPVIndex: 38})
+	snap, err := p.Fetch(context.Background(), "510300.SH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Quote{Last: 4.012, PrevClose: 4.0, Volume: 12345, Bid1: 4.011, Bid1Size: 800, Ask1: 4.013, Ask1Size: 900, Amount: 4951.2 * 10000, IOPV: 3.9876}
+	if snap.Quote != want {
+		t.Fatalf("quote=%+v want=%+v", snap.Quote, want)
+	}
+}
+
+func joinTilde(parts []string) string {
+	s := ""
+	for i, p := range parts {
+		if i > 0 {
+			s += "~"
+		}
+		s += p
+	}
+	return s
+}
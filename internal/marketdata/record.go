@@ -0,0 +1,232 @@
+package marketdata
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tickLocation is exchange time (CST); tick files are partitioned by exchange date.
+var tickLocation = time.FixedZone("CST", 8*3600)
+
+// TickRecord is one line of a tick file: a fusion result as signals saw it.
+// Provider payloads (Snapshot.Raw) are dropped to keep files small.
+type TickRecord struct {
+	TS       time.Time      `json:"ts"` // when FetchFusion returned
+	Symbol   string         `json:"symbol"`
+	Error    string         `json:"error,omitempty"`
+	Snapshot FusionSnapshot `json:"snapshot"`
+}
+
+// Recorder wraps a Fusion and appends every result, including failures, to
+// <dir>/<YYYYMMDD>/ticks-<start>-<pid>.jsonl.gz. Each process start opens a new
+// part file, and every record is flushed so a crash loses at most a partial
+// last line (which the reader tolerates). Recording errors are logged and
+// never fail FetchFusion.
+type Recorder struct {
+	inner Fusion
+	dir   string
+	now   func() time.Time
+
+	mu     sync.Mutex
+	day    string
+	f      *os.File
+	gz     *gzip.Writer
+	w      *bufio.Writer
+	failed bool // last write failed; logged once until a write succeeds
+}
+
+func NewRecorder(inner Fusion, dir string) *Recorder {
+	return &Recorder{inner: inner, dir: dir, now: time.Now}
+}
+
+func (r *Recorder) FetchFusion(ctx context.Context, symbol string) (FusionSnapshot, error) {
+	fs, err := r.inner.FetchFusion(ctx, symbol)
+	rec := TickRecord{TS: r.now(), Symbol: symbol, Snapshot: withoutRaw(fs)}
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	if werr := r.write(rec); werr != nil {
+		r.mu.Lock()
+		if !r.failed {
+			log.Printf("tick recorder write failed dir=%s err=%v", r.dir, werr)
+		}
+		r.failed = true
+		r.mu.Unlock()
+	}
+	return fs, err
+}
+
+func withoutRaw(fs FusionSnapshot) FusionSnapshot {
+	if len(fs.Providers) == 0 {
+		return fs
+	}
+	prs := make([]ProviderResult, len(fs.Providers))
+	copy(prs, fs.Providers)
+	for i := range prs {
+		prs[i].Snapshot.Raw = nil
+	}
+	fs.Providers = prs
+	return fs
+}
+
+func (r *Recorder) write(rec TickRecord) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	day := rec.TS.In(tickLocation).Format("20060102")
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.day != day || r.gz == nil {
+		if err := r.closeLocked(); err != nil {
+			log.Printf("tick recorder close failed day=%s err=%v", r.day, err)
+		}
+		if err := r.openLocked(day, rec.TS); err != nil {
+			return err
+		}
+	}
+	if _, err := r.w.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	if err := r.w.Flush(); err != nil {
+		return err
+	}
+	if err := r.gz.Flush(); err != nil {
+		return err
+	}
+	r.failed = false
+	return nil
+}
+
+func (r *Recorder) openLocked(day string, ts time.Time) error {
+	dir := filepath.Join(r.dir, day)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("ticks-%s-%d.jsonl.gz", ts.In(tickLocation).Format("150405"), os.Getpid())
+	f, err := os.OpenFile(filepath.Join(dir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	r.f = f
+	r.gz = gzip.NewWriter(f)
+	r.w = bufio.NewWriter(r.gz)
+	r.day = day
+	return nil
+}
+
+func (r *Recorder) closeLocked() error {
+	if r.gz == nil {
+		return nil
+	}
+	err := r.w.Flush()
+	if cerr := r.gz.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := r.f.Close(); err == nil {
+		err = cerr
+	}
+	r.f, r.gz, r.w = nil, nil, nil
+	return err
+}
+
+// Close finishes the current part file (writing the gzip trailer).
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.closeLocked()
+}
+
+// TickDates lists the exchange dates (YYYYMMDD) recorded under dir, ascending.
+func TickDates(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var out []string
+	for _, e := range entries {
+		if e.IsDir() && len(e.Name()) == 8 && strings.Trim(e.Name(), "0123456789") == "" {
+			out = append(out, e.Name())
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// ReadTicks calls fn for every record of date (YYYYMMDD) under dir, in
+// timestamp order across part files. A part cut short by a crash is read up to
+// its last complete line. Returning an error from fn stops the walk.
+func ReadTicks(dir, date string, fn func(TickRecord) error) error {
+	parts, err := filepath.Glob(filepath.Join(dir, date, "ticks-*.jsonl.gz"))
+	if err != nil {
+		return err
+	}
+	var recs []TickRecord
+	for _, p := range parts {
+		if err := readTickFile(p, func(rec TickRecord) error {
+			recs = append(recs, rec)
+			return nil
+		}); err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+	}
+	sort.SliceStable(recs, func(i, j int) bool { return recs[i].TS.Before(recs[j].TS) })
+	for _, rec := range recs {
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readTickFile(path string, fn func(TickRecord) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil // created but nothing flushed yet
+		}
+		return err
+	}
+	defer gz.Close()
+
+	sc := bufio.NewScanner(gz)
+	sc.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for sc.Scan() {
+		var rec TickRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			// Only the torn last line of a crashed writer can be malformed.
+			break
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+	// A writer killed mid-flush leaves a truncated or garbled deflate tail.
+	var corrupt flate.CorruptInputError
+	if err := sc.Err(); err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.As(err, &corrupt) {
+		return err
+	}
+	return nil
+}
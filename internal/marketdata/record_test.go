@@ -0,0 +1,146 @@
+package marketdata
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"value-sniffer-radar/internal/clock"
+)
+
+type stubFusion map[string]FusionSnapshot
+
+func (s stubFusion) FetchFusion(_ context.Context, symbol string) (FusionSnapshot, error) {
+	fs, ok := s[symbol]
+	if !ok {
+		return FusionSnapshot{Symbol: symbol, Confidence: ConfidenceFail}, errors.New("no sources")
+	}
+	return fs, nil
+}
+
+func TestRecorderRoundTripAndTornTail(t *testing.T) {
+	dir := t.TempDir()
+	inner := stubFusion{"204001.SH": {
+		Symbol: "204001.SH", Class: ClassRepo, ConsensusRatePct: 1.8, Confidence: ConfidencePass,
+		Providers: []ProviderResult{{Provider: "em", Inlier: true, Snapshot: Snapshot{Quote: Quote{Last: 1.8}, Raw: map[string]any{"f43": 1800}}}},
+	}}
+	clock := time.Date(2026, 1, 8, 10, 0, 0, 0, tickLocation)
+	next := func() time.Time { clock = clock.Add(time.Second); return clock }
+
+	// First process: two records, then a crash (no Close) with a torn tail.
+	r1 := NewRecorder(inner, dir)
+	r1.now = next
+	if _, err := r1.FetchFusion(context.Background(), "204001.SH"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r1.FetchFusion(context.Background(), "131810.SZ"); err == nil {
+		t.Fatal("inner error must pass through")
+	}
+	if fs, _ := inner.FetchFusion(context.Background(), "204001.SH"); fs.Providers[0].Snapshot.Raw == nil {
+		t.Fatal("recorder mutated the caller's snapshot")
+	}
+	if _, err := r1.f.Write([]byte{0x1f, 0x8b, 0x00}); err != nil { // garbage after the last flush
+		t.Fatal(err)
+	}
+
+	// Second process: a new part file in the same date directory.
+	r2 := NewRecorder(inner, dir)
+	r2.now = next
+	if _, err := r2.FetchFusion(context.Background(), "204001.SH"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r2.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if parts, _ := filepath.Glob(filepath.Join(dir, "20260108", "*.gz")); len(parts) != 2 {
+		t.Fatalf("parts=%v", parts)
+	}
+
+	dates, err := TickDates(dir)
+	if err != nil || len(dates) != 1 || dates[0] != "20260108" {
+		t.Fatalf("dates=%v err=%v", dates, err)
+	}
+	var got []TickRecord
+	if err := ReadTicks(dir, "20260108", func(rec TickRecord) error {
+		got = append(got, rec)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("records=%d want=3", len(got))
+	}
+	if got[0].Snapshot.ConsensusRatePct != 1.8 || got[0].Snapshot.Providers[0].Snapshot.Raw != nil || !got[0].Snapshot.Providers[0].Inlier {
+		t.Fatalf("rec0=%+v", got[0])
+	}
+	if got[1].Symbol != "131810.SZ" || got[1].Error != "no sources" || !got[1].TS.After(got[0].TS) {
+		t.Fatalf("rec1=%+v", got[1])
+	}
+}
+
+func TestReplayServesRecordedTicksAtSimulatedTime(t *testing.T) {
+	dir := t.TempDir()
+	rates := []float64{1.5, 1.7, 2.1}
+	i := 0
+	inner := fusionFunc(func(symbol string) (FusionSnapshot, error) {
+		fs := FusionSnapshot{Symbol: symbol, ConsensusRatePct: rates[i], Confidence: ConfidencePass}
+		i++
+		return fs, nil
+	})
+	t0 := time.Date(2026, 1, 8, 10, 0, 0, 0, tickLocation)
+	rec := NewRecorder(inner, dir)
+	for k := range rates {
+		at := t0.Add(time.Duration(k) * 10 * time.Second)
+		rec.now = func() time.Time { return at }
+		if _, err := rec.FetchFusion(context.Background(), "204001.SH"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	sim := clock.NewSim(t0)
+	r, err := NewReplay(dir, []string{"20260108"}, sim, 15*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Len() != 3 {
+		t.Fatalf("len=%d", r.Len())
+	}
+	if first, last := r.Span(); !first.Equal(t0) || !last.Equal(t0.Add(20*time.Second)) {
+		t.Fatalf("span=%v..%v", first, last)
+	}
+
+	for _, c := range []struct {
+		at   time.Duration
+		rate float64 // 0: no tick
+	}{
+		{-time.Second, 0},
+		{0, 1.5},
+		{15 * time.Second, 1.7},
+		{20 * time.Second, 2.1},
+		{35 * time.Second, 2.1},
+		{36 * time.Second, 0}, // older than maxAge
+	} {
+		sim.Set(t0.Add(c.at))
+		fs, err := r.FetchFusion(context.Background(), "204001.SH")
+		if c.rate == 0 {
+			if err == nil || fs.Confidence != ConfidenceFail {
+				t.Fatalf("at %s: want no tick, got %+v err=%v", c.at, fs, err)
+			}
+			continue
+		}
+		if err != nil || fs.ConsensusRatePct != c.rate {
+			t.Fatalf("at %s: rate=%v err=%v want=%v", c.at, fs.ConsensusRatePct, err, c.rate)
+		}
+	}
+}
+
+type fusionFunc func(symbol string) (FusionSnapshot, error)
+
+func (f fusionFunc) FetchFusion(_ context.Context, symbol string) (FusionSnapshot, error) {
+	return f(symbol)
+}
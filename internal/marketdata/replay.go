@@ -0,0 +1,93 @@
+package marketdata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"value-sniffer-radar/internal/clock"
+)
+
+// Replay is a Fusion backed by recorded ticks (see Recorder). FetchFusion
+// returns the last record of the symbol at or before the clock's now, as long as it is
+// no older than maxAge; otherwise the symbol had no quote at that instant.
+type Replay struct {
+	ticks  map[string][]TickRecord // symbol -> records by TS
+	clock  clock.Clock
+	maxAge time.Duration
+}
+
+// NewReplay loads the recorded dates (YYYYMMDD) from dir.
+func NewReplay(dir string, dates []string, clk clock.Clock, maxAge time.Duration) (*Replay, error) {
+	r := &Replay{ticks: map[string][]TickRecord{}, clock: clock.Or(clk), maxAge: maxAge}
+	for _, d := range dates {
+		if err := ReadTicks(dir, d, func(rec TickRecord) error {
+			r.ticks[rec.Symbol] = append(r.ticks[rec.Symbol], rec)
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	for _, recs := range r.ticks {
+		sort.SliceStable(recs, func(i, j int) bool { return recs[i].TS.Before(recs[j].TS) })
+	}
+	return r, nil
+}
+
+// Len is the number of loaded records.
+func (r *Replay) Len() int {
+	n := 0
+	for _, recs := range r.ticks {
+		n += len(recs)
+	}
+	return n
+}
+
+// Span is the time range covered by the loaded records.
+func (r *Replay) Span() (first, last time.Time) {
+	for _, recs := range r.ticks {
+		if len(recs) == 0 {
+			continue
+		}
+		if first.IsZero() || recs[0].TS.Before(first) {
+			first = recs[0].TS
+		}
+		if recs[len(recs)-1].TS.After(last) {
+			last = recs[len(recs)-1].TS
+		}
+	}
+	return first, last
+}
+
+// At returns the last record of symbol at or before t, if it is no older than
+// maxAge.
+func (r *Replay) At(symbol string, t time.Time) (TickRecord, bool) {
+	recs := r.ticks[symbol]
+	i := sort.Search(len(recs), func(i int) bool { return recs[i].TS.After(t) }) - 1
+	if i < 0 || (r.maxAge > 0 && t.Sub(recs[i].TS) > r.maxAge) {
+		return TickRecord{}, false
+	}
+	return recs[i], true
+}
+
+// Covers reports whether the recording reaches t, i.e. a missing tick at t is
+// a gap rather than data not captured yet.
+func (r *Replay) Covers(t time.Time) bool {
+	_, last := r.Span()
+	return !last.Before(t)
+}
+
+func (r *Replay) FetchFusion(_ context.Context, symbol string) (FusionSnapshot, error) {
+	now := r.clock.Now()
+	rec, ok := r.At(symbol, now)
+	if !ok {
+		return FusionSnapshot{Symbol: symbol, Class: ClassOf(symbol), TS: now, Confidence: ConfidenceFail, Reason: "no recorded tick"},
+			fmt.Errorf("replay: no tick for %s at %s", symbol, now.Format(time.RFC3339))
+	}
+	if rec.Error != "" {
+		return rec.Snapshot, errors.New(rec.Error)
+	}
+	return rec.Snapshot, nil
+}
@@ -0,0 +1,41 @@
+package marketdata
+
+import (
+	"context"
+	"io"
+	"log"
+	"time"
+
+	"value-sniffer-radar/internal/repo"
+)
+
+// RepoSampler wraps a Fusion and records every passing repo consensus rate
+// into the open repo rate histories of hs, which percentile thresholds read.
+// Recording errors are logged and never fail FetchFusion.
+type RepoSampler struct {
+	inner Fusion
+	hs    *repo.Histories
+	now   func() time.Time
+}
+
+func NewRepoSampler(inner Fusion, hs *repo.Histories, now func() time.Time) *RepoSampler {
+	return &RepoSampler{inner: inner, hs: hs, now: now}
+}
+
+func (s *RepoSampler) FetchFusion(ctx context.Context, symbol string) (FusionSnapshot, error) {
+	fs, err := s.inner.FetchFusion(ctx, symbol)
+	if err == nil && fs.Confidence == ConfidencePass && ClassOf(symbol) == ClassRepo {
+		if rerr := s.hs.Record(symbol, s.now().In(tickLocation), fs.ConsensusRatePct); rerr != nil {
+			log.Printf("repo history save failed symbol=%s err=%v", symbol, rerr)
+		}
+	}
+	return fs, err
+}
+
+// Close closes the wrapped Fusion if it holds resources (a Recorder).
+func (s *RepoSampler) Close() error {
+	if c, ok := s.inner.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -12,45 +12,72 @@ import (
 	"strings"
 	"time"
 
-	"value-sniffer-radar/internal/config"
+	"value-sniffer-radar/internal/clock"
 )
 
+// AivalQueueParams configure the AI-Value / AstrBot file queue.
+type AivalQueueParams struct {
+	QueueDir string   `yaml:"queue_dir"`
+	Market   string   `yaml:"market"` // default CN-A
+	Tags     []string `yaml:"tags"`
+}
+
+func init() {
+	Register(Type{
+		Name:   "aival_queue",
+		Params: func() any { return &AivalQueueParams{} },
+		New: func(p any) (Notifier, error) {
+			return NewAivalQueue(*p.(*AivalQueueParams))
+		},
+	})
+}
+
 // AivalQueue writes AI-Value (aival.event.v1) JSON files into a queue dir.
 // The AstrBot plugin ai_value can poll this directory and push messages to QQ.
 type AivalQueue struct {
 	queueDir string
 	market   string
 	tags     []string
+	clock    clock.Clock
 }
 
-func NewAivalQueue(c config.NotifierConfig) (*AivalQueue, error) {
-	if strings.TrimSpace(c.QueueDir) == "" {
+func NewAivalQueue(p AivalQueueParams) (*AivalQueue, error) {
+	if strings.TrimSpace(p.QueueDir) == "" {
 		return nil, fmt.Errorf("aival_queue.queue_dir required")
 	}
-	market := strings.TrimSpace(c.Market)
+	market := strings.TrimSpace(p.Market)
 	if market == "" {
 		market = "CN-A"
 	}
 	var tags []string
-	for _, t := range c.Tags {
+	for _, t := range p.Tags {
 		if s := strings.TrimSpace(t); s != "" {
 			tags = append(tags, s)
 		}
 	}
+	sweepOrphans(p.QueueDir, time.Now())
 	return &AivalQueue{
-		queueDir: c.QueueDir,
+		queueDir: p.QueueDir,
 		market:   market,
 		tags:     tags,
+		clock:    clock.Real,
 	}, nil
 }
 
 func (q *AivalQueue) Name() string { return "aival_queue" }
 
-func (q *AivalQueue) Notify(_ context.Context, events []Event) error {
+// SetClock sets the clock used for event ids and created_at.
+func (q *AivalQueue) SetClock(c clock.Clock) { q.clock = clock.Or(c) }
+
+func (q *AivalQueue) Notify(ctx context.Context, events []Event) error {
 	if err := os.MkdirAll(q.queueDir, 0o755); err != nil {
 		return err
 	}
 	for _, e := range events {
+		// Stop between files on shutdown; each file is written atomically.
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if err := q.dropOne(e); err != nil {
 			return err
 		}
@@ -58,9 +85,28 @@ func (q *AivalQueue) Notify(_ context.Context, events []Event) error {
 	return nil
 }
 
+// sweepOrphans removes .tmp files a crashed process left in dir, so the queue
+// only ever holds complete events. Recent ones may still be written by the
+// queue a config reload replaces, and are left alone.
+func sweepOrphans(dir string, now time.Time) {
+	matches, _ := filepath.Glob(filepath.Join(dir, "*_evt_*.json.tmp"))
+	for _, m := range matches {
+		if fi, err := os.Stat(m); err == nil && now.Sub(fi.ModTime()) > time.Minute {
+			_ = os.Remove(m)
+		}
+	}
+}
+
 func (q *AivalQueue) dropOne(e Event) error {
-	now := time.Now()
-	id := newID("evt", now)
+	now := q.clock.Now()
+	id := e.ID
+	if id == "" {
+		id = newID("evt", now)
+	}
+	ts := now
+	if !e.EmittedAt.IsZero() {
+		ts = e.EmittedAt
+	}
 
 	title := strings.TrimSpace(e.Title)
 	if title == "" {
@@ -120,7 +166,7 @@ func (q *AivalQueue) dropOne(e Event) error {
 		"schema": "aival.event.v1",
 		"kind":   "event",
 		"id":     id,
-		"ts":     now.Format(time.RFC3339),
+		"ts":     ts.Format(time.RFC3339),
 		"title":  title,
 		"market": q.market,
 		"text":   text,
@@ -151,9 +197,14 @@ func (q *AivalQueue) dropOne(e Event) error {
 		return err
 	}
 	if err := os.WriteFile(tmpPath, b, 0o644); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, outPath); err != nil {
+		_ = os.Remove(tmpPath)
 		return err
 	}
-	return os.Rename(tmpPath, outPath)
+	return nil
 }
 
 func newID(prefix string, now time.Time) string {
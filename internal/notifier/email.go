@@ -9,10 +9,30 @@ import (
 	"net/smtp"
 	"strings"
 	"time"
-
-	"value-sniffer-radar/internal/config"
 )
 
+// EmailParams configure the email notifier (SMTP over TLS). Credentials are
+// read from the named environment variables.
+type EmailParams struct {
+	SMTPHost      string   `yaml:"smtp_host"`
+	SMTPPort      int      `yaml:"smtp_port"`
+	UsernameEnv   string   `yaml:"username_env"`
+	PasswordEnv   string   `yaml:"password_env"`
+	From          string   `yaml:"from"`
+	To            []string `yaml:"to"`
+	SubjectPrefix string   `yaml:"subject_prefix"`
+}
+
+func init() {
+	Register(Type{
+		Name:   "email",
+		Params: func() any { return &EmailParams{} },
+		New: func(p any) (Notifier, error) {
+			return NewEmail(*p.(*EmailParams))
+		},
+	})
+}
+
 type Email struct {
 	host          string
 	port          int
@@ -23,32 +43,32 @@ type Email struct {
 	subjectPrefix string
 }
 
-func NewEmail(c config.NotifierConfig) (*Email, error) {
-	if c.SMTPHost == "" || c.SMTPPort == 0 {
+func NewEmail(p EmailParams) (*Email, error) {
+	if p.SMTPHost == "" || p.SMTPPort == 0 {
 		return nil, errors.New("email.smtp_host and email.smtp_port required")
 	}
 	user := ""
 	pass := ""
-	if c.UsernameEnv != "" {
-		user = mustEnvOrEmpty(c.UsernameEnv)
+	if p.UsernameEnv != "" {
+		user = mustEnvOrEmpty(p.UsernameEnv)
 	}
-	if c.PasswordEnv != "" {
-		pass = mustEnvOrEmpty(c.PasswordEnv)
+	if p.PasswordEnv != "" {
+		pass = mustEnvOrEmpty(p.PasswordEnv)
 	}
 	if user == "" || pass == "" {
 		return nil, errors.New("email.username_env and email.password_env must be set and present in environment")
 	}
-	if c.From == "" || len(c.To) == 0 {
+	if p.From == "" || len(p.To) == 0 {
 		return nil, errors.New("email.from and email.to required")
 	}
 	return &Email{
-		host:          c.SMTPHost,
-		port:          c.SMTPPort,
+		host:          p.SMTPHost,
+		port:          p.SMTPPort,
 		username:      user,
 		password:      pass,
-		from:          c.From,
-		to:            c.To,
-		subjectPrefix: c.SubjectPrefix,
+		from:          p.From,
+		to:            p.To,
+		subjectPrefix: p.SubjectPrefix,
 	}, nil
 }
 
@@ -2,14 +2,28 @@ package notifier
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
+	"value-sniffer-radar/internal/clock"
 	"value-sniffer-radar/internal/config"
+	"value-sniffer-radar/internal/eventschema"
 )
 
 type Event struct {
+	// ID, EmittedAt and Schema are set once by the engine (Stamp) before
+	// policies run, and every downstream record (paper log, webhook, aival
+	// queue, labels, LLM output) refers to the event by ID.
+	ID        string    `json:"id,omitempty"`
+	EmittedAt time.Time `json:"emitted_at"`
+	Schema    string    `json:"schema,omitempty"` // eventschema.Version
+
 	Source    string                 `json:"source"`
 	TradeDate string                 `json:"trade_date"`
 	Market    string                 `json:"market,omitempty"`
@@ -20,44 +34,144 @@ type Event struct {
 	Data      map[string]interface{} `json:"data,omitempty"`
 }
 
+// Stamp assigns EmittedAt, the schema version and the derived ID. An already
+// stamped event keeps its identity.
+func (e *Event) Stamp(at time.Time) {
+	if e.ID != "" {
+		return
+	}
+	e.EmittedAt = at
+	e.Schema = eventschema.Version
+	e.ID = EventID(*e)
+}
+
+// EventID hashes what identifies one emission: the signal, instrument, trade
+// date, title and emission time. The same inputs (e.g. a backtest replay)
+// give the same ID.
+func EventID(e Event) string {
+	h := sha256.New()
+	for _, s := range []string{e.Source, e.Market, e.Symbol, e.TradeDate, e.Title, e.EmittedAt.UTC().Format(time.RFC3339Nano)} {
+		_, _ = h.Write([]byte(s))
+		_, _ = h.Write([]byte{0})
+	}
+	return "evt_" + hex.EncodeToString(h.Sum(nil))[:24]
+}
+
 type Notifier interface {
 	Name() string
 	Notify(ctx context.Context, events []Event) error
 }
 
-func BuildAll(cfgs []config.NotifierConfig) ([]Notifier, error) {
+// Closer is optionally implemented by notifiers that hold resources (open files,
+// idle connections) which must be flushed/released on shutdown.
+type Closer interface {
+	Close() error
+}
+
+// CloseAll closes every notifier implementing Closer and returns the first error.
+func CloseAll(ns []Notifier) error {
+	var first error
+	for _, n := range ns {
+		c, ok := n.(Closer)
+		if !ok {
+			continue
+		}
+		if err := c.Close(); err != nil && first == nil {
+			first = fmt.Errorf("close notifier %s: %w", n.Name(), err)
+		}
+	}
+	return first
+}
+
+// Clocked is optionally implemented by notifiers that stamp events with the
+// current time; the engine hands them its clock so backtests log simulated time.
+type Clocked interface {
+	SetClock(clock.Clock)
+}
+
+// SetClock passes c to every notifier implementing Clocked.
+func SetClock(ns []Notifier, c clock.Clock) {
+	for _, n := range ns {
+		if cn, ok := n.(Clocked); ok {
+			cn.SetClock(c)
+		}
+	}
+}
+
+// Type is a registered notifier type: the params its config entries decode
+// into and how to build the notifier. Notifier files register their type
+// from init.
+type Type struct {
+	Name string
+	// Params returns a pointer to zero params; nil means the type takes none.
+	Params func() any
+	New    func(params any) (Notifier, error)
+}
+
+var registry = map[string]Type{}
+
+// Register makes a notifier type available to config entries (type: name).
+func Register(t Type) { registry[t.Name] = t }
+
+// Types lists the registered notifier types.
+func Types() []string {
+	out := make([]string, 0, len(registry))
+	for n := range registry {
+		out = append(out, n)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Env is what notifier params may depend on besides their own entry.
+type Env struct {
+	BaseDir string // config file directory; relative paths resolve against it
+}
+
+// Normalizer is implemented by params that check their values or resolve
+// paths against Env.
+type Normalizer interface {
+	Normalize(env Env) error
+}
+
+// DecodeParams decodes and normalizes the params of entry c. Two entries with
+// deeply equal params build equivalent notifiers.
+func DecodeParams(c config.NotifierConfig, env Env) (any, error) {
+	t, ok := registry[c.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown notifier type: %s (registered: %s)", c.Type, strings.Join(Types(), ", "))
+	}
+	var p any = &struct{}{}
+	if t.Params != nil {
+		p = t.Params()
+	}
+	if err := c.DecodeParams(p); err != nil {
+		return nil, fmt.Errorf("notifier %s params: %w", c.Type, err)
+	}
+	if n, ok := p.(Normalizer); ok {
+		if err := n.Normalize(env); err != nil {
+			return nil, fmt.Errorf("notifier %s params: %w", c.Type, err)
+		}
+	}
+	return p, nil
+}
+
+func Build(c config.NotifierConfig, env Env) (Notifier, error) {
+	p, err := DecodeParams(c, env)
+	if err != nil {
+		return nil, err
+	}
+	return registry[c.Type].New(p)
+}
+
+func BuildAll(cfgs []config.NotifierConfig, env Env) ([]Notifier, error) {
 	var out []Notifier
 	for _, c := range cfgs {
-		switch c.Type {
-		case "stdout":
-			out = append(out, NewStdout())
-		case "email":
-			n, err := NewEmail(c)
-			if err != nil {
-				return nil, err
-			}
-			out = append(out, n)
-		case "webhook":
-			n, err := NewWebhook(c)
-			if err != nil {
-				return nil, err
-			}
-			out = append(out, n)
-		case "aival_queue":
-			n, err := NewAivalQueue(c)
-			if err != nil {
-				return nil, err
-			}
-			out = append(out, n)
-		case "paper_log":
-			n, err := NewPaperLog(c)
-			if err != nil {
-				return nil, err
-			}
-			out = append(out, n)
-		default:
-			return nil, fmt.Errorf("unknown notifier type: %s", c.Type)
+		n, err := Build(c, env)
+		if err != nil {
+			return nil, err
 		}
+		out = append(out, n)
 	}
 	if len(out) == 0 {
 		return nil, errors.New("no notifiers configured")
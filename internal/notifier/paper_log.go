@@ -1,46 +1,74 @@
 package notifier
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
-	"value-sniffer-radar/internal/config"
+	"value-sniffer-radar/internal/clock"
 )
 
+// PaperLogParams configure the paper log (JSONL for evaluation).
+type PaperLogParams struct {
+	FilePath string `yaml:"file_path"` // default state/paper.jsonl
+}
+
+func (p *PaperLogParams) Normalize(env Env) error {
+	if p.FilePath != "" && !filepath.IsAbs(p.FilePath) {
+		p.FilePath = filepath.Join(env.BaseDir, p.FilePath)
+	}
+	return nil
+}
+
+func init() {
+	Register(Type{
+		Name:   "paper_log",
+		Params: func() any { return &PaperLogParams{} },
+		New: func(p any) (Notifier, error) {
+			return NewPaperLog(*p.(*PaperLogParams))
+		},
+	})
+}
+
 // PaperLog appends every event as one JSON line (JSONL), for later evaluation/backtest.
+// The file stays open between batches; Close syncs it so no line is left half-written.
 type PaperLog struct {
-	path string
+	path  string
+	clock clock.Clock
+
+	mu sync.Mutex
+	f  *os.File
 }
 
-func NewPaperLog(c config.NotifierConfig) (*PaperLog, error) {
+func NewPaperLog(c PaperLogParams) (*PaperLog, error) {
 	p := c.FilePath
 	if p == "" {
 		p = filepath.Join("state", "paper.jsonl")
 	}
-	return &PaperLog{path: p}, nil
+	return &PaperLog{path: p, clock: clock.Real}, nil
 }
 
+// SetClock stamps records ("ts") with c instead of wall time.
+func (p *PaperLog) SetClock(c clock.Clock) { p.clock = clock.Or(c) }
+
 func (p *PaperLog) Name() string { return "paper_log" }
 
-func (p *PaperLog) Notify(_ context.Context, events []Event) error {
+func (p *PaperLog) Notify(ctx context.Context, events []Event) error {
 	if len(events) == 0 {
 		return nil
 	}
-	if err := os.MkdirAll(filepath.Dir(p.path), 0o755); err != nil {
-		return err
-	}
-
-	f, err := os.OpenFile(p.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
-	if err != nil {
+	if err := ctx.Err(); err != nil {
 		return err
 	}
-	defer f.Close()
 
-	now := time.Now().Format(time.RFC3339)
+	// Encode the whole batch first, then append it with a single write.
+	var buf bytes.Buffer
+	now := p.clock.Now().Format(time.RFC3339)
 	for _, e := range events {
 		rec := map[string]any{
 			"ts":    now,
@@ -50,11 +78,39 @@ func (p *PaperLog) Notify(_ context.Context, events []Event) error {
 		if err != nil {
 			return err
 		}
-		if _, err := f.Write(append(b, '\n')); err != nil {
+		buf.Write(b)
+		buf.WriteByte('\n')
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.f == nil {
+		if err := os.MkdirAll(filepath.Dir(p.path), 0o755); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(p.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
 			return err
 		}
+		p.f = f
 	}
-	return nil
+	_, err := p.f.Write(buf.Bytes())
+	return err
+}
+
+func (p *PaperLog) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.f == nil {
+		return nil
+	}
+	syncErr := p.f.Sync()
+	closeErr := p.f.Close()
+	p.f = nil
+	if syncErr != nil {
+		return syncErr
+	}
+	return closeErr
 }
 
 func (p *PaperLog) String() string { return fmt.Sprintf("paper_log(%s)", p.path) }
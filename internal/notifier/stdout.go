@@ -6,6 +6,10 @@ import (
 	"strings"
 )
 
+func init() {
+	Register(Type{Name: "stdout", New: func(any) (Notifier, error) { return NewStdout(), nil }})
+}
+
 type Stdout struct{}
 
 func NewStdout() *Stdout { return &Stdout{} }
@@ -7,28 +7,45 @@ import (
 	"fmt"
 	"net/http"
 	"time"
-
-	"value-sniffer-radar/internal/config"
 )
 
+// WebhookParams configure the webhook notifier: events are POSTed as JSON.
+type WebhookParams struct {
+	URL            string            `yaml:"url"`
+	TimeoutSeconds int               `yaml:"timeout_seconds"` // default 10
+	Headers        map[string]string `yaml:"headers"`
+}
+
+func init() {
+	Register(Type{
+		Name:   "webhook",
+		Params: func() any { return &WebhookParams{} },
+		New: func(p any) (Notifier, error) {
+			return NewWebhook(*p.(*WebhookParams))
+		},
+	})
+}
+
 type Webhook struct {
 	url     string
 	headers map[string]string
 	timeout time.Duration
+	client  *http.Client
 }
 
-func NewWebhook(c config.NotifierConfig) (*Webhook, error) {
-	if c.URL == "" {
+func NewWebhook(p WebhookParams) (*Webhook, error) {
+	if p.URL == "" {
 		return nil, fmt.Errorf("webhook.url required")
 	}
-	timeout := time.Duration(c.TimeoutSeconds) * time.Second
+	timeout := time.Duration(p.TimeoutSeconds) * time.Second
 	if timeout <= 0 {
 		timeout = 10 * time.Second
 	}
 	return &Webhook{
-		url:     c.URL,
-		headers: c.Headers,
+		url:     p.URL,
+		headers: p.Headers,
 		timeout: timeout,
+		client:  &http.Client{Timeout: timeout},
 	}, nil
 }
 
@@ -52,8 +69,7 @@ func (w *Webhook) Notify(ctx context.Context, events []Event) error {
 		req.Header.Set(k, v)
 	}
 
-	client := &http.Client{Timeout: w.timeout}
-	resp, err := client.Do(req)
+	resp, err := w.client.Do(req)
 	if err != nil {
 		return err
 	}
@@ -63,3 +79,8 @@ func (w *Webhook) Notify(ctx context.Context, events []Event) error {
 	}
 	return nil
 }
+
+func (w *Webhook) Close() error {
+	w.client.CloseIdleConnections()
+	return nil
+}
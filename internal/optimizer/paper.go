@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"time"
 )
 
 type PaperRow struct {
@@ -16,6 +17,9 @@ type PaperRow struct {
 }
 
 type PaperLogEvent struct {
+	ID        string            `json:"id"`
+	EmittedAt time.Time         `json:"emitted_at"`
+	Schema    string            `json:"schema"`
 	Source    string            `json:"source"`
 	TradeDate string            `json:"trade_date"`
 	Market    string            `json:"market"`
@@ -98,6 +102,10 @@ func RewardFromRow(pr PaperRow) (int, bool) {
 }
 
 func EventID(pr PaperRow) string {
+	if pr.Event.ID != "" {
+		return pr.Event.ID
+	}
+	// Paper logs written before events carried an id.
 	h := sha256.Sum256([]byte(pr.TS + "|" + pr.Event.Source + "|" + pr.Event.Symbol + "|" + pr.Event.TradeDate + "|" + pr.Event.Title))
 	return hex.EncodeToString(h[:])
 }
@@ -34,3 +34,22 @@ func TestReadJSONLAndReward(t *testing.T) {
 	}
 }
 
+func TestEventIDPrefersStampedID(t *testing.T) {
+	in := `{"ts":"2026-01-05T10:00:01+08:00","event":{"id":"evt_abc","emitted_at":"2026-01-05T10:00:00+08:00","source":"a","title":"rate 1.81%"}}
+{"ts":"2026-01-05T10:00:01+08:00","event":{"source":"a","title":"rate 1.81%"}}
+`
+	rows, _, err := ReadJSONL(strings.NewReader(in))
+	if err != nil || len(rows) != 2 {
+		t.Fatalf("rows=%d err=%v", len(rows), err)
+	}
+	if id := EventID(rows[0]); id != "evt_abc" {
+		t.Fatalf("stamped id=%s", id)
+	}
+	if rows[0].Event.EmittedAt.IsZero() {
+		t.Fatal("emitted_at not parsed")
+	}
+	// Legacy rows keep the content hash.
+	if id := EventID(rows[1]); len(id) != 64 {
+		t.Fatalf("legacy id=%s", id)
+	}
+}
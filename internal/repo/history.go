@@ -0,0 +1,284 @@
+package repo
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"value-sniffer-radar/internal/state"
+)
+
+// History keeps intraday repo rates per symbol, exchange date and time-of-day
+// bucket, so thresholds can follow where rates usually are at this time of day
+// instead of one fixed number. Instances are shared per path within a
+// Histories set and fed from fusion snapshots (Histories.Record).
+type History struct {
+	path string // empty: kept in memory only
+
+	mu      sync.Mutex
+	rates   map[string]map[string]map[int]float64 // symbol -> YYYYMMDD -> bucket minute -> rate
+	dirty   bool
+	savedAt time.Time
+}
+
+const (
+	// BucketMinutes is the time-of-day resolution of stored samples.
+	BucketMinutes = 5
+	// historyKeepDays bounds the exchange dates kept per symbol.
+	historyKeepDays = 120
+	// historySaveEvery throttles disk writes; samples arrive every few seconds.
+	historySaveEvery = time.Minute
+	// matchWindowMinutes is how far from "now" a past sample may be to count
+	// as the same time of day.
+	matchWindowMinutes = 15
+)
+
+type historyFile struct {
+	BucketMinutes int                                   `json:"bucket_minutes"`
+	Rates         map[string]map[string]map[int]float64 `json:"rates"`
+}
+
+// OpenHistory loads the history stored at path; a missing file is an empty
+// history.
+func OpenHistory(path string) (*History, error) {
+	h := &History{path: path, rates: map[string]map[string]map[int]float64{}}
+	b, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		var f historyFile
+		if err := json.Unmarshal(b, &f); err != nil {
+			return nil, fmt.Errorf("repo history %s: %w", path, err)
+		}
+		if f.Rates != nil && f.BucketMinutes == BucketMinutes {
+			h.rates = f.Rates
+		}
+	case !os.IsNotExist(err):
+		return nil, err
+	}
+	return h, nil
+}
+
+// Histories is the set of repo rate histories one radar keeps, one per file.
+// The engine owns it: signals open theirs through their Env, the marketdata
+// RepoSampler feeds every open one, and the engine flushes them. A nil
+// *Histories holds nothing.
+type Histories struct {
+	mu     sync.Mutex
+	byPath map[string]*History
+	memory bool
+}
+
+func NewHistories() *Histories {
+	return &Histories{byPath: map[string]*History{}}
+}
+
+// NewMemoryHistories is a Histories whose histories start empty and are
+// never read from or written to their paths, so a backtest learns only from
+// the ticks it replays and leaves no state behind.
+func NewMemoryHistories() *Histories {
+	return &Histories{byPath: map[string]*History{}, memory: true}
+}
+
+// Open returns the history stored at path, loading it on first use so every
+// signal configured with the same path shares one instance.
+func (hs *Histories) Open(path string) (*History, error) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	if h, ok := hs.byPath[path]; ok {
+		return h, nil
+	}
+	h := &History{rates: map[string]map[string]map[int]float64{}}
+	if !hs.memory {
+		var err error
+		if h, err = OpenHistory(path); err != nil {
+			return nil, err
+		}
+	}
+	hs.byPath[path] = h
+	return h, nil
+}
+
+func (hs *Histories) list() []*History {
+	if hs == nil {
+		return nil
+	}
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	out := make([]*History, 0, len(hs.byPath))
+	for _, h := range hs.byPath {
+		out = append(out, h)
+	}
+	return out
+}
+
+// Record adds a fused rate of symbol to every open history, so each learns
+// from all repo snapshots, whichever signal fetched them. It returns the
+// first save error.
+func (hs *Histories) Record(symbol string, now time.Time, rate float64) error {
+	var first error
+	for _, h := range hs.list() {
+		if err := h.Add(symbol, now, rate); err != nil && first == nil {
+			first = fmt.Errorf("repo history %s: %w", h.path, err)
+		}
+	}
+	return first
+}
+
+// Flush writes the pending samples of every open history; call it on
+// shutdown, since Add only saves once a minute.
+func (hs *Histories) Flush(now time.Time) error {
+	var first error
+	for _, h := range hs.list() {
+		if err := h.Flush(now); err != nil && first == nil {
+			first = fmt.Errorf("repo history %s: %w", h.path, err)
+		}
+	}
+	return first
+}
+
+// Retain flushes and closes the open histories whose path is not in keep, so
+// a history no signal reads any more (after a reload) stops being fed.
+func (hs *Histories) Retain(keep map[string]bool, now time.Time) error {
+	if hs == nil {
+		return nil
+	}
+	hs.mu.Lock()
+	var drop []*History
+	for path, h := range hs.byPath {
+		if !keep[path] {
+			drop = append(drop, h)
+			delete(hs.byPath, path)
+		}
+	}
+	hs.mu.Unlock()
+	var first error
+	for _, h := range drop {
+		if err := h.Flush(now); err != nil && first == nil {
+			first = fmt.Errorf("repo history %s: %w", h.path, err)
+		}
+	}
+	return first
+}
+
+func bucketOf(t time.Time) int {
+	m := t.Hour()*60 + t.Minute()
+	return m - m%BucketMinutes
+}
+
+// Add records rate for symbol at now (exchange time); the last sample in a
+// bucket wins. The file is rewritten at most once a minute.
+func (h *History) Add(symbol string, now time.Time, rate float64) error {
+	if rate <= 0 || math.IsNaN(rate) || math.IsInf(rate, 0) {
+		return nil
+	}
+	day := now.Format("20060102")
+
+	h.mu.Lock()
+	byDay := h.rates[symbol]
+	if byDay == nil {
+		byDay = map[string]map[int]float64{}
+		h.rates[symbol] = byDay
+	}
+	if byDay[day] == nil {
+		byDay[day] = map[int]float64{}
+		pruneDays(byDay, historyKeepDays)
+	}
+	byDay[day][bucketOf(now)] = rate
+	h.dirty = true
+	if now.Sub(h.savedAt) < historySaveEvery && !h.savedAt.IsZero() {
+		h.mu.Unlock()
+		return nil
+	}
+	h.mu.Unlock()
+	return h.Flush(now)
+}
+
+// Flush writes pending samples to disk; a memory-only history has none.
+func (h *History) Flush(now time.Time) error {
+	h.mu.Lock()
+	if !h.dirty || h.path == "" {
+		h.mu.Unlock()
+		return nil
+	}
+	b, err := json.Marshal(historyFile{BucketMinutes: BucketMinutes, Rates: h.rates})
+	h.dirty = false
+	h.savedAt = now
+	h.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return state.WriteFileAtomic(h.path, append(b, '\n'))
+}
+
+func pruneDays(byDay map[string]map[int]float64, keep int) {
+	if len(byDay) <= keep {
+		return
+	}
+	days := make([]string, 0, len(byDay))
+	for d := range byDay {
+		days = append(days, d)
+	}
+	sort.Strings(days)
+	for _, d := range days[:len(days)-keep] {
+		delete(byDay, d)
+	}
+}
+
+// SameTimeOfDay returns one sample per exchange date before now's date (the
+// bucket closest to now's time of day, within matchWindowMinutes), for at most
+// the last lookbackDays dates with data.
+func (h *History) SameTimeOfDay(symbol string, now time.Time, lookbackDays int) []float64 {
+	today := now.Format("20060102")
+	target := bucketOf(now)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	byDay := h.rates[symbol]
+	days := make([]string, 0, len(byDay))
+	for d := range byDay {
+		if d < today {
+			days = append(days, d)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(days)))
+
+	var out []float64
+	for _, d := range days {
+		if len(out) >= lookbackDays {
+			break
+		}
+		best, bestDist := 0.0, matchWindowMinutes+1
+		for b, v := range byDay[d] {
+			dist := b - target
+			if dist < 0 {
+				dist = -dist
+			}
+			if dist < bestDist || (dist == bestDist && b < target) {
+				best, bestDist = v, dist
+			}
+		}
+		if bestDist <= matchWindowMinutes {
+			out = append(out, best)
+		}
+	}
+	return out
+}
+
+// Percentile is the p-th percentile (0..100) of samples, interpolating
+// linearly between order statistics. It reports false for no samples.
+func Percentile(samples []float64, p float64) (float64, bool) {
+	if len(samples) == 0 {
+		return 0, false
+	}
+	s := append([]float64(nil), samples...)
+	sort.Float64s(s)
+	p = math.Max(0, math.Min(100, p))
+	pos := p / 100 * float64(len(s)-1)
+	lo := int(math.Floor(pos))
+	hi := int(math.Ceil(pos))
+	return s[lo] + (s[hi]-s[lo])*(pos-float64(lo)), true
+}
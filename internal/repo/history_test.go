@@ -0,0 +1,145 @@
+package repo
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHistorySameTimeOfDayAndReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "repo_history.json")
+	h, err := OpenHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loc := time.FixedZone("CST", 8*3600)
+	at := func(day, hh, mm int) time.Time { return time.Date(2026, 1, day, hh, mm, 0, 0, loc) }
+
+	_ = h.Add("204001.SH", at(5, 14, 50), 2.0)
+	_ = h.Add("204001.SH", at(5, 10, 0), 9.0) // different time of day
+	_ = h.Add("204001.SH", at(6, 14, 40), 3.0)
+	_ = h.Add("204001.SH", at(7, 14, 52), 4.0)
+	_ = h.Add("204001.SH", at(8, 14, 51), 5.0) // "today": excluded
+	if err := h.Flush(at(8, 15, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	got := h.SameTimeOfDay("204001.SH", at(8, 14, 52), 2)
+	if len(got) != 2 || got[0] != 4.0 || got[1] != 3.0 {
+		t.Fatalf("samples=%v want [4 3]", got)
+	}
+
+	// A fresh process reads the file back.
+	h2, err := OpenHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := h2.SameTimeOfDay("204001.SH", at(8, 14, 52), 10); len(got) != 3 {
+		t.Fatalf("reloaded samples=%v", got)
+	}
+}
+
+func TestHistoriesRecordFlushAndRetain(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "repo_history.json")
+	other := filepath.Join(dir, "other_history.json")
+	hs := NewHistories()
+	h, err := hs.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := hs.Open(path); again != h {
+		t.Fatal("same path should share one history")
+	}
+	o, err := hs.Open(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loc := time.FixedZone("CST", 8*3600)
+	at := func(day, mm int) time.Time { return time.Date(2026, 1, day, 14, mm, 0, 0, loc) }
+
+	// A sample within a minute of the last save stays pending until a flush.
+	_ = hs.Record("204001.SH", at(5, 50), 2.0)
+	_ = hs.Record("204001.SH", at(6, 50), 3.0)
+	_ = hs.Record("204001.SH", at(6, 50).Add(10*time.Second), 3.5)
+	if got := h.SameTimeOfDay("204001.SH", at(7, 50), 10); len(got) != 2 {
+		t.Fatalf("samples=%v", got)
+	}
+	if err := hs.Flush(at(7, 0)); err != nil {
+		t.Fatal(err)
+	}
+	h2, err := OpenHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := h2.SameTimeOfDay("204001.SH", at(7, 50), 10); len(got) != 2 || got[0] != 3.5 {
+		t.Fatalf("flushed samples=%v want [3.5 2]", got)
+	}
+
+	// Retain flushes what it drops, and dropped histories are no longer fed.
+	_ = hs.Record("204001.SH", at(7, 50), 4.0)
+	if err := hs.Retain(map[string]bool{path: true}, at(7, 51)); err != nil {
+		t.Fatal(err)
+	}
+	_ = hs.Record("204001.SH", at(8, 50), 5.0)
+	if got := o.SameTimeOfDay("204001.SH", at(9, 50), 10); len(got) != 3 || got[0] != 4.0 {
+		t.Fatalf("dropped history samples=%v want [4 3.5 2]", got)
+	}
+	o2, err := OpenHistory(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := o2.SameTimeOfDay("204001.SH", at(9, 50), 10); len(got) != 3 {
+		t.Fatalf("dropped history not flushed: %v", got)
+	}
+	if got := h.SameTimeOfDay("204001.SH", at(9, 50), 10); len(got) != 4 {
+		t.Fatalf("kept history samples=%v", got)
+	}
+}
+
+func TestMemoryHistoriesIgnoreAndLeaveFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "repo_history.json")
+	loc := time.FixedZone("CST", 8*3600)
+	at := func(day int) time.Time { return time.Date(2026, 1, day, 14, 50, 0, 0, loc) }
+	live, err := OpenHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = live.Add("204001.SH", at(5), 2.0)
+
+	hs := NewMemoryHistories()
+	h, err := hs.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := h.SameTimeOfDay("204001.SH", at(6), 10); len(got) != 0 {
+		t.Fatalf("memory history should start empty: %v", got)
+	}
+	_ = hs.Record("204001.SH", at(6), 3.0)
+	if err := hs.Flush(at(6)); err != nil {
+		t.Fatal(err)
+	}
+	if got := h.SameTimeOfDay("204001.SH", at(7), 10); len(got) != 1 || got[0] != 3.0 {
+		t.Fatalf("memory samples=%v", got)
+	}
+	after, err := OpenHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := after.SameTimeOfDay("204001.SH", at(7), 10); len(got) != 1 || got[0] != 2.0 {
+		t.Fatalf("file must be left alone: %v", got)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	s := []float64{5, 1, 3, 2, 4}
+	if v, _ := Percentile(s, 50); v != 3 {
+		t.Fatalf("p50=%v", v)
+	}
+	if v, _ := Percentile(s, 95); v < 4.79 || v > 4.81 {
+		t.Fatalf("p95=%v", v)
+	}
+	if _, ok := Percentile(nil, 95); ok {
+		t.Fatal("empty samples")
+	}
+}
@@ -0,0 +1,178 @@
+// Package repo describes exchange reverse repo instruments (SSE GC / SZSE R
+// series) and their settlement rules, so yields of different tenors and trade
+// days can be compared on one annualized basis.
+package repo
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Instrument is one exchange reverse repo.
+type Instrument struct {
+	Symbol    string // "204001.SH"
+	Exchange  string // SH | SZ
+	Name      string // GC001 / R-001
+	TenorDays int    // nominal tenor in calendar days
+
+	// Order sizing in CNY, used for book depth: SSE quotes per 1000 CNY lot,
+	// SZSE per 100 CNY bond; both reject orders under 1000 CNY.
+	LotNotional float64
+	MinNotional float64
+}
+
+func inst(symbol, name string, tenor int) Instrument {
+	exchange := symbol[len(symbol)-2:]
+	lot := 1000.0
+	if exchange == "SZ" {
+		lot = 100
+	}
+	return Instrument{
+		Symbol: symbol, Exchange: exchange, Name: name, TenorDays: tenor,
+		LotNotional: lot, MinNotional: 1000,
+	}
+}
+
+var registry = []Instrument{
+	inst("204001.SH", "GC001", 1),
+	inst("204002.SH", "GC002", 2),
+	inst("204003.SH", "GC003", 3),
+	inst("204004.SH", "GC004", 4),
+	inst("204007.SH", "GC007", 7),
+	inst("204014.SH", "GC014", 14),
+	inst("204028.SH", "GC028", 28),
+	inst("204091.SH", "GC091", 91),
+	inst("204182.SH", "GC182", 182),
+	inst("131810.SZ", "R-001", 1),
+	inst("131811.SZ", "R-002", 2),
+	inst("131800.SZ", "R-003", 3),
+	inst("131809.SZ", "R-004", 4),
+	inst("131801.SZ", "R-007", 7),
+	inst("131802.SZ", "R-014", 14),
+	inst("131803.SZ", "R-028", 28),
+	inst("131805.SZ", "R-091", 91),
+	inst("131806.SZ", "R-182", 182),
+}
+
+var bySymbol = func() map[string]Instrument {
+	m := make(map[string]Instrument, len(registry))
+	for _, in := range registry {
+		m[in.Symbol] = in
+	}
+	return m
+}()
+
+// Lookup finds a repo by "204001.SH" style symbol.
+func Lookup(symbol string) (Instrument, bool) {
+	in, ok := bySymbol[strings.ToUpper(strings.TrimSpace(symbol))]
+	return in, ok
+}
+
+// Ladder lists every registered repo ordered by exchange, then tenor.
+func Ladder() []Instrument {
+	out := append([]Instrument(nil), registry...)
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Exchange != out[j].Exchange {
+			return out[i].Exchange < out[j].Exchange
+		}
+		return out[i].TenorDays < out[j].TenorDays
+	})
+	return out
+}
+
+// TradingDays reports whether an exchange date is open; *session.Calendar
+// satisfies it. A nil TradingDays means Monday-Friday.
+type TradingDays interface {
+	IsTradingDay(t time.Time) bool
+}
+
+// Settlement is when cash lent through a repo traded on TradeDate moves:
+//
+//   - interest accrues from FirstSettle, the next trading day after the trade,
+//   - for TenorDays calendar days, with Maturity rolled forward to a trading day,
+//   - and the cash is usable again for trading on Usable, the last trading day
+//     before Maturity (it becomes withdrawable on Maturity).
+//
+// So a Thursday GC001 accrues Friday to Monday (3 days) while tying the cash
+// up for one day; a Friday GC001 accrues one day across a three-day lock.
+type Settlement struct {
+	TradeDate   time.Time
+	FirstSettle time.Time
+	Maturity    time.Time
+	Usable      time.Time
+	AccrualDays int // FirstSettle -> Maturity, calendar days
+	LockDays    int // TradeDate -> Usable, calendar days
+}
+
+// Settle computes the settlement schedule of in traded on tradeDate.
+func Settle(in Instrument, tradeDate time.Time, days TradingDays) Settlement {
+	open := func(t time.Time) bool {
+		if days != nil {
+			return days.IsTradingDay(t)
+		}
+		wd := t.Weekday()
+		return wd != time.Saturday && wd != time.Sunday
+	}
+	// Walk bounded: the longest exchange holiday is well under a month.
+	next := func(t time.Time) time.Time {
+		for i := 0; i < 30; i++ {
+			t = t.AddDate(0, 0, 1)
+			if open(t) {
+				break
+			}
+		}
+		return t
+	}
+
+	d := time.Date(tradeDate.Year(), tradeDate.Month(), tradeDate.Day(), 0, 0, 0, 0, tradeDate.Location())
+	s := Settlement{TradeDate: d, FirstSettle: next(d)}
+	s.Maturity = s.FirstSettle.AddDate(0, 0, in.TenorDays)
+	if !open(s.Maturity) {
+		s.Maturity = next(s.Maturity)
+	}
+	s.Usable = s.Maturity
+	for i := 0; i < 30; i++ {
+		s.Usable = s.Usable.AddDate(0, 0, -1)
+		if open(s.Usable) {
+			break
+		}
+	}
+	s.AccrualDays = calendarDays(s.FirstSettle, s.Maturity)
+	s.LockDays = calendarDays(s.TradeDate, s.Usable)
+	if s.LockDays < 1 {
+		s.LockDays = 1
+	}
+	return s
+}
+
+func calendarDays(from, to time.Time) int {
+	// Dates are midnights in one fixed zone, so hours divide evenly.
+	return int(to.Sub(from).Hours() / 24)
+}
+
+// Yield is a repo rate normalized for comparison across tenors and trade days.
+// All values are annualized pct (ACT/365).
+type Yield struct {
+	RatePct      float64 // quoted rate
+	AccruedPct   float64 // interest over AccrualDays, pct of notional (not annualized)
+	FeePct       float64 // trade fee annualized over LockDays
+	GrossPct     float64 // interest annualized over LockDays
+	EffectivePct float64 // GrossPct - FeePct
+}
+
+// EffectiveYield annualizes the interest earned over s.AccrualDays, net of the
+// trade fee (feePct, charged once, in pct of the notional; see costs.Model),
+// over the days the cash is actually unavailable (s.LockDays).
+func EffectiveYield(ratePct, feePct float64, s Settlement) Yield {
+	y := Yield{RatePct: ratePct}
+	y.AccruedPct = ratePct * float64(s.AccrualDays) / 365.0
+	if s.LockDays <= 0 {
+		return y
+	}
+	scale := 365.0 / float64(s.LockDays)
+	y.GrossPct = y.AccruedPct * scale
+	y.FeePct = feePct * scale
+	y.EffectivePct = y.GrossPct - y.FeePct
+	return y
+}
@@ -0,0 +1,64 @@
+package repo
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+type closedDays map[string]bool
+
+func (c closedDays) IsTradingDay(t time.Time) bool {
+	wd := t.Weekday()
+	return wd != time.Saturday && wd != time.Sunday && !c[t.Format("20060102")]
+}
+
+func day(s string) time.Time {
+	t, _ := time.Parse("20060102", s)
+	return t
+}
+
+func TestSettleWeekendAndHoliday(t *testing.T) {
+	gc001, _ := Lookup("204001.sh")
+	gc007, _ := Lookup("204007.SH")
+	holiday := closedDays{"20261001": true, "20261002": true, "20261005": true, "20261006": true, "20261007": true}
+
+	cases := []struct {
+		in              Instrument
+		trade           string
+		days            TradingDays
+		accrual, lock   int
+		maturity, usage string
+	}{
+		{gc001, "20260108", nil, 3, 1, "20260112", "20260109"},     // Thursday: Fri..Mon accrued, usable Friday
+		{gc001, "20260109", nil, 1, 3, "20260113", "20260112"},     // Friday: Mon..Tue accrued, usable Monday
+		{gc001, "20260929", holiday, 8, 1, "20261008", "20260930"}, // second to last day before the holiday
+		{gc001, "20260930", holiday, 1, 8, "20261009", "20261008"}, // last day: accrual starts after it
+		{gc007, "20260929", holiday, 8, 1, "20261008", "20260930"},
+	}
+	for _, c := range cases {
+		s := Settle(c.in, day(c.trade), c.days)
+		if s.AccrualDays != c.accrual || s.LockDays != c.lock ||
+			s.Maturity.Format("20060102") != c.maturity || s.Usable.Format("20060102") != c.usage {
+			t.Errorf("%s %s: got accrual=%d lock=%d maturity=%s usable=%s", c.in.Name, c.trade,
+				s.AccrualDays, s.LockDays, s.Maturity.Format("20060102"), s.Usable.Format("20060102"))
+		}
+	}
+}
+
+func TestEffectiveYield(t *testing.T) {
+	y := EffectiveYield(2.0, 0.001, Settlement{AccrualDays: 3, LockDays: 1})
+	if math.Abs(y.GrossPct-6.0) > 1e-9 || math.Abs(y.FeePct-0.365) > 1e-9 || math.Abs(y.EffectivePct-5.635) > 1e-9 {
+		t.Fatalf("yield=%+v", y)
+	}
+}
+
+func TestLadderOrder(t *testing.T) {
+	l := Ladder()
+	if len(l) != 18 || l[0].Symbol != "204001.SH" || l[9].Symbol != "131810.SZ" || l[17].TenorDays != 182 {
+		t.Fatalf("ladder=%v", l)
+	}
+	if _, ok := Lookup("600000.SH"); ok {
+		t.Fatal("stock found in repo registry")
+	}
+}
@@ -0,0 +1,174 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule yields the next fire time strictly after a given instant.
+type Schedule interface {
+	Next(after time.Time) time.Time
+	String() string
+}
+
+// Parse accepts "@every <duration>" (e.g. "@every 3s") or a 5-field cron
+// expression "minute hour day-of-month month day-of-week" evaluated in loc,
+// e.g. "*/5 9-15 * * 1-5". Fields support "*", "a-b", "a,b", and "/step".
+func Parse(expr string, loc *time.Location) (Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, errors.New("empty schedule")
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+	if strings.HasPrefix(expr, "@every") {
+		d, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(expr, "@every")))
+		if err != nil {
+			return nil, fmt.Errorf("schedule %q: %w", expr, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("schedule %q: duration must be > 0", expr)
+		}
+		return Every(d), nil
+	}
+	return parseCron(expr, loc)
+}
+
+// Every fires at a fixed period.
+type Every time.Duration
+
+func (e Every) Next(after time.Time) time.Time { return after.Add(time.Duration(e)) }
+
+func (e Every) String() string { return "@every " + time.Duration(e).String() }
+
+// Cron is a minute-resolution cron schedule.
+type Cron struct {
+	expr   string
+	loc    *time.Location
+	minute [60]bool
+	hour   [24]bool
+	dom    [32]bool
+	month  [13]bool
+	dow    [7]bool
+
+	domAny bool
+	dowAny bool
+}
+
+func (c *Cron) String() string { return c.expr }
+
+func parseCron(expr string, loc *time.Location) (*Cron, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("schedule %q: want 5 cron fields (min hour dom month dow) or @every <duration>", expr)
+	}
+	c := &Cron{expr: expr, loc: loc}
+	specs := []struct {
+		name     string
+		min, max int
+		set      func(int)
+	}{
+		{"minute", 0, 59, func(i int) { c.minute[i] = true }},
+		{"hour", 0, 23, func(i int) { c.hour[i] = true }},
+		{"day-of-month", 1, 31, func(i int) { c.dom[i] = true }},
+		{"month", 1, 12, func(i int) { c.month[i] = true }},
+		{"day-of-week", 0, 7, func(i int) { c.dow[i%7] = true }}, // 7 is Sunday too
+	}
+	for i, sp := range specs {
+		if err := parseField(fields[i], sp.min, sp.max, sp.set); err != nil {
+			return nil, fmt.Errorf("schedule %q %s: %w", expr, sp.name, err)
+		}
+	}
+	c.domAny = fields[2] == "*"
+	c.dowAny = fields[4] == "*"
+	return c, nil
+}
+
+func parseField(f string, min, max int, set func(int)) error {
+	for _, part := range strings.Split(f, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid step %q", part)
+			}
+			step = n
+			part = part[:i]
+		}
+		lo, hi := min, max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			ab := strings.SplitN(part, "-", 2)
+			a, err1 := strconv.Atoi(ab[0])
+			b, err2 := strconv.Atoi(ab[1])
+			if err1 != nil || err2 != nil || a > b {
+				return fmt.Errorf("invalid range %q", part)
+			}
+			lo, hi = a, b
+		default:
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return fmt.Errorf("invalid value %q", part)
+			}
+			lo, hi = n, n
+			if step > 1 {
+				hi = max
+			}
+		}
+		if lo < min || hi > max {
+			return fmt.Errorf("value out of range [%d,%d] in %q", min, max, f)
+		}
+		for v := lo; v <= hi; v += step {
+			set(v)
+		}
+	}
+	return nil
+}
+
+func (c *Cron) dayMatches(t time.Time) bool {
+	dom := c.dom[t.Day()]
+	dow := c.dow[int(t.Weekday())]
+	// Classic cron: when both day fields are restricted, either may match.
+	switch {
+	case c.domAny && c.dowAny:
+		return true
+	case c.domAny:
+		return dow
+	case c.dowAny:
+		return dom
+	default:
+		return dom || dow
+	}
+}
+
+// Next returns the first matching minute strictly after `after`.
+func (c *Cron) Next(after time.Time) time.Time {
+	t := after.In(c.loc).Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if !c.month[int(t.Month())] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, c.loc)
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, c.loc)
+			continue
+		}
+		if !c.hour[t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, c.loc)
+			continue
+		}
+		if !c.minute[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	// Unsatisfiable (e.g. Feb 30); never fire.
+	return limit
+}
@@ -0,0 +1,56 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+var cst = time.FixedZone("CST", 8*3600)
+
+func TestParseEvery(t *testing.T) {
+	s, err := Parse("@every 3s", cst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2026, 1, 29, 10, 0, 0, 0, cst)
+	if got := s.Next(at); !got.Equal(at.Add(3 * time.Second)) {
+		t.Fatalf("next=%s", got)
+	}
+	if _, err := Parse("@every -1s", cst); err == nil {
+		t.Fatalf("expected error for non-positive duration")
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	cases := []struct {
+		expr string
+		at   time.Time
+		want time.Time
+	}{
+		// every 5 minutes during 9-15h on weekdays
+		{"*/5 9-15 * * 1-5", time.Date(2026, 1, 29, 10, 2, 30, 0, cst), time.Date(2026, 1, 29, 10, 5, 0, 0, cst)},
+		// Friday 15:59 rolls to Monday 09:00
+		{"*/5 9-15 * * 1-5", time.Date(2026, 1, 30, 15, 59, 0, 0, cst), time.Date(2026, 2, 2, 9, 0, 0, 0, cst)},
+		// daily post-close run
+		{"5 15 * * 1-5", time.Date(2026, 1, 29, 15, 5, 0, 0, cst), time.Date(2026, 1, 30, 15, 5, 0, 0, cst)},
+		// list + month end rollover
+		{"0,30 9 31 * *", time.Date(2026, 1, 31, 9, 30, 0, 0, cst), time.Date(2026, 3, 31, 9, 0, 0, 0, cst)},
+	}
+	for _, c := range cases {
+		s, err := Parse(c.expr, cst)
+		if err != nil {
+			t.Fatalf("%s: %v", c.expr, err)
+		}
+		if got := s.Next(c.at); !got.Equal(c.want) {
+			t.Fatalf("%s after %s: got=%s want=%s", c.expr, c.at, got, c.want)
+		}
+	}
+}
+
+func TestCronInvalid(t *testing.T) {
+	for _, expr := range []string{"* * * *", "61 * * * *", "*/0 * * * *", "5-1 * * * *", "x * * * *"} {
+		if _, err := Parse(expr, cst); err == nil {
+			t.Fatalf("expected error for %q", expr)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"value-sniffer-radar/internal/state"
+	"value-sniffer-radar/internal/tushare"
+)
+
+// Calendar answers "is this exchange date open?" from Tushare trade_cal (SSE),
+// cached locally so restarts and ticks don't re-query it. Without data for a
+// date (no token, fetch failure) it falls back to Monday-Friday.
+type Calendar struct {
+	client    *tushare.Client
+	cachePath string
+
+	mu        sync.Mutex
+	days      map[string]bool // YYYYMMDD -> is_open
+	fetchedOn string          // exchange date of the last successful fetch
+	end       string          // last date covered
+	lastTry   time.Time
+	warned    bool
+}
+
+// refreshRetry bounds how often a failing trade_cal refresh is retried.
+const refreshRetry = 10 * time.Minute
+
+type calendarFile struct {
+	Exchange  string          `json:"exchange"`
+	FetchedOn string          `json:"fetched_on"`
+	Start     string          `json:"start"`
+	End       string          `json:"end"`
+	Days      map[string]bool `json:"days"`
+}
+
+// NewCalendar loads the cache at cachePath and writes refreshes back to it.
+func NewCalendar(client *tushare.Client, cachePath string) *Calendar {
+	return NewCalendarFrom(client, cachePath, cachePath)
+}
+
+// NewCalendarFrom starts from the cache at seedPath but writes refreshes to
+// cachePath, so a backtest can use the live calendar without rewriting it.
+func NewCalendarFrom(client *tushare.Client, seedPath, cachePath string) *Calendar {
+	if seedPath == "" {
+		seedPath = filepath.Join("state", "trade_cal.json")
+	}
+	if cachePath == "" {
+		cachePath = filepath.Join("state", "trade_cal.json")
+	}
+	c := &Calendar{client: client, cachePath: cachePath, days: map[string]bool{}}
+	c.loadCache(seedPath)
+	return c
+}
+
+// Refresh re-fetches trade_cal at most once per exchange date (or when now runs past
+// the cached range). Errors keep the previous data.
+func (c *Calendar) Refresh(ctx context.Context, now time.Time) error {
+	today := now.In(Location).Format("20060102")
+
+	c.mu.Lock()
+	fresh := c.fetchedOn == today && c.end >= today
+	retrying := !c.lastTry.IsZero() && now.Sub(c.lastTry) < refreshRetry
+	if !fresh && !retrying {
+		c.lastTry = now
+	}
+	c.mu.Unlock()
+	if fresh || retrying || c.client == nil {
+		return nil
+	}
+
+	start := now.In(Location).AddDate(0, 0, -30).Format("20060102")
+	end := now.In(Location).AddDate(0, 0, 90).Format("20060102")
+	rows, err := c.client.Query(ctx, "trade_cal", map[string]any{
+		"exchange":   "SSE",
+		"start_date": start,
+		"end_date":   end,
+	}, []string{"cal_date", "is_open"})
+	if err != nil {
+		return fmt.Errorf("trade_cal refresh: %w", err)
+	}
+	if len(rows) == 0 {
+		return fmt.Errorf("trade_cal refresh: no rows for %s..%s", start, end)
+	}
+
+	days := make(map[string]bool, len(rows))
+	maxDate := ""
+	for _, r := range rows {
+		d := tushare.GetString(r, "cal_date")
+		if d == "" {
+			continue
+		}
+		days[d] = tushare.GetString(r, "is_open") == "1"
+		if d > maxDate {
+			maxDate = d
+		}
+	}
+
+	c.mu.Lock()
+	for d, open := range days {
+		c.days[d] = open
+	}
+	c.fetchedOn = today
+	c.end = maxDate
+	f := calendarFile{Exchange: "SSE", FetchedOn: today, Start: start, End: maxDate, Days: c.copyDaysLocked()}
+	c.mu.Unlock()
+
+	return c.saveCache(f)
+}
+
+// IsTradingDay reports whether t's exchange date is open.
+func (c *Calendar) IsTradingDay(t time.Time) bool {
+	t = t.In(Location)
+	d := t.Format("20060102")
+
+	c.mu.Lock()
+	open, ok := c.days[d]
+	if !ok && !c.warned {
+		c.warned = true
+		log.Printf("trade calendar has no entry for %s; falling back to weekdays", d)
+	}
+	c.mu.Unlock()
+
+	if ok {
+		return open
+	}
+	wd := t.Weekday()
+	return wd != time.Saturday && wd != time.Sunday
+}
+
+// Phase returns the session phase at now.
+func (c *Calendar) Phase(now time.Time) Phase {
+	return PhaseAt(now, c.IsTradingDay(now))
+}
+
+func (c *Calendar) copyDaysLocked() map[string]bool {
+	out := make(map[string]bool, len(c.days))
+	for k, v := range c.days {
+		out[k] = v
+	}
+	return out
+}
+
+func (c *Calendar) loadCache(path string) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	var f calendarFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		log.Printf("trade calendar cache ignored path=%s err=%v", path, err)
+		return
+	}
+	for d, open := range f.Days {
+		c.days[d] = open
+	}
+	c.fetchedOn = f.FetchedOn
+	c.end = f.End
+}
+
+func (c *Calendar) saveCache(f calendarFile) error {
+	b, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return state.WriteFileAtomic(c.cachePath, append(b, '\n'))
+}
@@ -0,0 +1,96 @@
+package session
+
+import (
+	"time"
+)
+
+// Location is exchange time for SSE/SZSE (China has no DST).
+var Location = time.FixedZone("CST", 8*3600)
+
+// Phase is where the exchange day is at a given instant.
+type Phase string
+
+const (
+	PhaseUnknown     Phase = ""             // session model disabled
+	PhaseClosed      Phase = "closed"       // weekend / exchange holiday
+	PhasePreOpen     Phase = "pre_open"     // before 09:15
+	PhaseCallAuction Phase = "call_auction" // 09:15-09:30 opening call auction
+	PhaseMorning     Phase = "morning"      // 09:30-11:30 continuous trading
+	PhaseLunchBreak  Phase = "lunch_break"  // 11:30-13:00
+	PhaseAfternoon   Phase = "afternoon"    // 13:00-15:00 (incl. 14:57 closing call auction)
+	PhasePostClose   Phase = "post_close"   // after 15:00 on a trading day
+)
+
+// Phases lists every known phase, in intraday order.
+var Phases = []Phase{
+	PhaseClosed, PhasePreOpen, PhaseCallAuction, PhaseMorning, PhaseLunchBreak, PhaseAfternoon, PhasePostClose,
+}
+
+// DefaultSignalPhases is used when a signal does not configure `phases`.
+var DefaultSignalPhases = []Phase{PhaseMorning, PhaseAfternoon}
+
+// Trading reports continuous trading (morning/afternoon sessions).
+func (p Phase) Trading() bool {
+	return p == PhaseMorning || p == PhaseAfternoon
+}
+
+// ParsePhase validates a config phase name.
+func ParsePhase(s string) (Phase, bool) {
+	for _, p := range Phases {
+		if string(p) == s {
+			return p, true
+		}
+	}
+	return PhaseUnknown, false
+}
+
+// Info is what the engine tells a signal about "now".
+// Now is the engine's clock in exchange time; signals must use it instead of time.Now().
+type Info struct {
+	Phase Phase
+	Now   time.Time
+
+	// PostClosePass is true for the once-per-trade-day run after the close.
+	PostClosePass bool
+
+	// Calendar knows exchange holidays; nil when engine.session is disabled
+	// (callers then assume Monday-Friday).
+	Calendar TradingDays
+}
+
+// TradingDays reports whether t's exchange date is open. *Calendar implements it.
+type TradingDays interface {
+	IsTradingDay(t time.Time) bool
+}
+
+// Known reports whether the session model is enabled (Phase is meaningful).
+func (i Info) Known() bool { return i.Phase != PhaseUnknown }
+
+// CloseTime returns 15:00 exchange time on t's exchange date.
+func CloseTime(t time.Time) time.Time {
+	t = t.In(Location)
+	return time.Date(t.Year(), t.Month(), t.Day(), 15, 0, 0, 0, Location)
+}
+
+// PhaseAt maps an instant to a phase given whether its exchange date is a trading day.
+func PhaseAt(t time.Time, tradingDay bool) Phase {
+	if !tradingDay {
+		return PhaseClosed
+	}
+	t = t.In(Location)
+	m := t.Hour()*60 + t.Minute()
+	switch {
+	case m < 9*60+15:
+		return PhasePreOpen
+	case m < 9*60+30:
+		return PhaseCallAuction
+	case m < 11*60+30:
+		return PhaseMorning
+	case m < 13*60:
+		return PhaseLunchBreak
+	case m < 15*60:
+		return PhaseAfternoon
+	default:
+		return PhasePostClose
+	}
+}
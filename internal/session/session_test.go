@@ -0,0 +1,100 @@
+package session
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"value-sniffer-radar/internal/tushare"
+)
+
+func TestPhaseAt(t *testing.T) {
+	at := func(h, m int) time.Time {
+		return time.Date(2026, 1, 29, h, m, 0, 0, Location)
+	}
+	cases := []struct {
+		t    time.Time
+		want Phase
+	}{
+		{at(9, 0), PhasePreOpen},
+		{at(9, 20), PhaseCallAuction},
+		{at(9, 30), PhaseMorning},
+		{at(11, 29), PhaseMorning},
+		{at(12, 0), PhaseLunchBreak},
+		{at(13, 0), PhaseAfternoon},
+		{at(14, 58), PhaseAfternoon},
+		{at(15, 0), PhasePostClose},
+	}
+	for _, c := range cases {
+		if got := PhaseAt(c.t, true); got != c.want {
+			t.Fatalf("PhaseAt(%s)=%s want=%s", c.t.Format("15:04"), got, c.want)
+		}
+	}
+	if got := PhaseAt(at(10, 0), false); got != PhaseClosed {
+		t.Fatalf("non-trading day phase=%s", got)
+	}
+	// 02:00 UTC is 10:00 exchange time.
+	if got := PhaseAt(time.Date(2026, 1, 29, 2, 0, 0, 0, time.UTC), true); got != PhaseMorning {
+		t.Fatalf("utc input phase=%s", got)
+	}
+}
+
+func TestCalendarUsesCacheThenWeekdayFallback(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trade_cal.json")
+	// 2026-02-16 is a Monday inside the Spring Festival holiday.
+	content := `{"exchange":"SSE","fetched_on":"20260216","start":"20260201","end":"20260301","days":{"20260216":false,"20260224":true}}`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := NewCalendar(nil, path)
+
+	holiday := time.Date(2026, 2, 16, 10, 0, 0, 0, Location)
+	if c.IsTradingDay(holiday) {
+		t.Fatalf("expected cached holiday to be closed")
+	}
+	if got := c.Phase(holiday); got != PhaseClosed {
+		t.Fatalf("phase=%s want=closed", got)
+	}
+	if !c.IsTradingDay(time.Date(2026, 2, 24, 10, 0, 0, 0, Location)) {
+		t.Fatalf("expected cached open day")
+	}
+	// Not in cache: Saturday falls back to closed, Wednesday to open.
+	if c.IsTradingDay(time.Date(2026, 5, 9, 10, 0, 0, 0, Location)) {
+		t.Fatalf("expected weekend fallback closed")
+	}
+	if !c.IsTradingDay(time.Date(2026, 5, 13, 10, 0, 0, 0, Location)) {
+		t.Fatalf("expected weekday fallback open")
+	}
+}
+
+func TestCalendarFromLeavesSeedUntouched(t *testing.T) {
+	dir := t.TempDir()
+	seed, out := filepath.Join(dir, "live.json"), filepath.Join(dir, "out", "trade_cal.json")
+	content := `{"exchange":"SSE","fetched_on":"20260216","start":"20260201","end":"20260301","days":{"20260216":false}}`
+	if err := os.WriteFile(seed, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"code":0,"data":{"fields":["cal_date","is_open"],"items":[["20260505","0"],["20260506","1"]]}}`))
+	}))
+	defer srv.Close()
+
+	c := NewCalendarFrom(tushare.New(tushare.Options{BaseURL: srv.URL, Token: "t"}), seed, out)
+	if c.IsTradingDay(time.Date(2026, 2, 16, 10, 0, 0, 0, Location)) {
+		t.Fatalf("expected seeded holiday to be closed")
+	}
+	if err := c.Refresh(context.Background(), time.Date(2026, 5, 6, 10, 0, 0, 0, Location)); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(seed); string(b) != content {
+		t.Fatalf("seed rewritten: %s", b)
+	}
+	if b, err := os.ReadFile(out); err != nil || !strings.Contains(string(b), `"20260505": false`) {
+		t.Fatalf("cache=%s err=%v", b, err)
+	}
+}
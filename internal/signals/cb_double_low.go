@@ -2,6 +2,7 @@ package signals
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sort"
 	"time"
@@ -9,9 +10,36 @@ import (
 	"value-sniffer-radar/internal/config"
 	"value-sniffer-radar/internal/marketdata"
 	"value-sniffer-radar/internal/notifier"
+	"value-sniffer-radar/internal/session"
 	"value-sniffer-radar/internal/tushare"
 )
 
+// CBDoubleLowParams configure cb_double_low: bonds whose price plus
+// conversion premium is at or below max_double_low.
+type CBDoubleLowParams struct {
+	MinAmount    float64 `yaml:"min_amount"`
+	MaxDoubleLow float64 `yaml:"max_double_low"` // default 125
+	TopN         int     `yaml:"top_n"`          // default 20
+}
+
+func (p *CBDoubleLowParams) Normalize(Env) error {
+	if p.MaxDoubleLow < 0 {
+		return errors.New("max_double_low must be >= 0")
+	}
+	return checkTopN(p.TopN)
+}
+
+func init() {
+	Register(Type{
+		Name:   "cb_double_low",
+		Params: func() any { return &CBDoubleLowParams{} },
+		New: func(c config.SignalConfig, p any) (Signal, error) {
+			return NewCBDoubleLow(c, *p.(*CBDoubleLowParams)), nil
+		},
+		NeedsTushare: always,
+	})
+}
+
 // CBDoubleLow alerts when (bond_price + premium_pct) <= threshold.
 // It is NOT arbitrage; it's a classic low-risk-ish selection heuristic for CN convertible bonds.
 type CBDoubleLow struct {
@@ -23,12 +51,12 @@ type CBDoubleLow struct {
 	topN         int
 }
 
-func NewCBDoubleLow(c config.SignalConfig) *CBDoubleLow {
-	topN := c.TopN
+func NewCBDoubleLow(c config.SignalConfig, p CBDoubleLowParams) *CBDoubleLow {
+	topN := p.TopN
 	if topN <= 0 {
 		topN = 20
 	}
-	thr := c.MaxDoubleLow
+	thr := p.MaxDoubleLow
 	if thr <= 0 {
 		thr = 125
 	}
@@ -45,7 +73,7 @@ func NewCBDoubleLow(c config.SignalConfig) *CBDoubleLow {
 		name:         name,
 		tier:         tier,
 		minInterval:  minInt,
-		minAmount:    c.MinAmount,
+		minAmount:    p.MinAmount,
 		maxDoubleLow: thr,
 		topN:         topN,
 	}
@@ -74,7 +102,7 @@ type cbDLAlert struct {
 	amount     float64
 }
 
-func (s *CBDoubleLow) Evaluate(ctx context.Context, client *tushare.Client, tradeDate string, _ marketdata.Fusion) ([]notifier.Event, error) {
+func (s *CBDoubleLow) Evaluate(ctx context.Context, client *tushare.Client, tradeDate string, _ marketdata.Fusion, _ session.Info) ([]notifier.Event, error) {
 	cbBasics, err := client.Query(ctx, "cb_basic", map[string]any{
 		"list_status": "L",
 	}, []string{"ts_code", "stk_code", "conv_price", "bond_short_name"})
@@ -10,9 +10,35 @@ import (
 	"value-sniffer-radar/internal/config"
 	"value-sniffer-radar/internal/marketdata"
 	"value-sniffer-radar/internal/notifier"
+	"value-sniffer-radar/internal/session"
 	"value-sniffer-radar/internal/tushare"
 )
 
+// CBPremiumParams configure cb_premium: convertible bonds whose conversion
+// premium (daily close) is at or below premium_pct_low or at or above
+// premium_pct_high.
+type CBPremiumParams struct {
+	MinAmount      float64 `yaml:"min_amount"` // daily amount filter
+	PremiumPctLow  float64 `yaml:"premium_pct_low"`
+	PremiumPctHigh float64 `yaml:"premium_pct_high"`
+	TopN           int     `yaml:"top_n"` // default 20
+}
+
+func (p *CBPremiumParams) Normalize(Env) error {
+	return checkPremiumRange(p.PremiumPctLow, p.PremiumPctHigh, p.TopN)
+}
+
+func init() {
+	Register(Type{
+		Name:   "cb_premium",
+		Params: func() any { return &CBPremiumParams{} },
+		New: func(c config.SignalConfig, p any) (Signal, error) {
+			return NewCBPremium(c, *p.(*CBPremiumParams)), nil
+		},
+		NeedsTushare: always,
+	})
+}
+
 type CBPremium struct {
 	name        string
 	tier        string
@@ -23,8 +49,8 @@ type CBPremium struct {
 	topN        int
 }
 
-func NewCBPremium(c config.SignalConfig) *CBPremium {
-	topN := c.TopN
+func NewCBPremium(c config.SignalConfig, p CBPremiumParams) *CBPremium {
+	topN := p.TopN
 	if topN <= 0 {
 		topN = 20
 	}
@@ -41,9 +67,9 @@ func NewCBPremium(c config.SignalConfig) *CBPremium {
 		name:        name,
 		tier:        tier,
 		minInterval: minInt,
-		minAmount:   c.MinAmount,
-		premiumLow:  c.PremiumPctLow,
-		premiumHigh: c.PremiumPctHigh,
+		minAmount:   p.MinAmount,
+		premiumLow:  p.PremiumPctLow,
+		premiumHigh: p.PremiumPctHigh,
 		topN:        topN,
 	}
 }
@@ -69,7 +95,7 @@ type cbAlert struct {
 	amount     float64
 }
 
-func (s *CBPremium) Evaluate(ctx context.Context, client *tushare.Client, tradeDate string, _ marketdata.Fusion) ([]notifier.Event, error) {
+func (s *CBPremium) Evaluate(ctx context.Context, client *tushare.Client, tradeDate string, _ marketdata.Fusion, _ session.Info) ([]notifier.Event, error) {
 	cbBasics, err := client.Query(ctx, "cb_basic", map[string]any{
 		"list_status": "L",
 	}, []string{"ts_code", "stk_code", "conv_price"})
@@ -0,0 +1,357 @@
+package signals
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"value-sniffer-radar/internal/config"
+	"value-sniffer-radar/internal/marketdata"
+	"value-sniffer-radar/internal/notifier"
+	"value-sniffer-radar/internal/session"
+	"value-sniffer-radar/internal/tushare"
+)
+
+// CBPremiumRealtimeParams configure cb_premium_realtime: intraday
+// conversion discounts (and premiums when premium_pct_high is set).
+type CBPremiumRealtimeParams struct {
+	CBCodes        []string `yaml:"cb_codes"` // e.g. ["113050.SH","123100.SZ"]
+	MinAmount      float64  `yaml:"min_amount"`
+	PremiumPctLow  float64  `yaml:"premium_pct_low"`  // default -2 when both are unset
+	PremiumPctHigh float64  `yaml:"premium_pct_high"` // unset: no premium-side alerts
+	TopN           int      `yaml:"top_n"`            // default 10
+	ConfirmK       int      `yaml:"confirm_k"`
+	Execution      `yaml:",inline"`
+	Window         `yaml:",inline"`
+}
+
+func (p *CBPremiumRealtimeParams) Normalize(env Env) error {
+	if len(p.CBCodes) == 0 {
+		return errors.New("cb_codes required")
+	}
+	if err := p.Execution.normalize(env); err != nil {
+		return err
+	}
+	if err := checkConfirmK(p.ConfirmK); err != nil {
+		return err
+	}
+	return checkPremiumRange(p.PremiumPctLow, p.PremiumPctHigh, p.TopN)
+}
+
+func init() {
+	Register(Type{
+		Name:   "cb_premium_realtime",
+		Params: func() any { return &CBPremiumRealtimeParams{} },
+		New: func(c config.SignalConfig, p any) (Signal, error) {
+			return NewCBPremiumRealtime(c, *p.(*CBPremiumRealtimeParams)), nil
+		},
+		NeedsTushare: always,
+	})
+}
+
+// CBPremiumRealtime is the intraday counterpart of cb_premium: bond and underlying
+// stock prices come from marketdata fusion, conversion terms from a cb_basic table
+// refreshed once per trade date. Premium is measured at mid prices; crossing both
+// books (spread, depth slippage) is reported so the net edge policy sees it.
+type CBPremiumRealtime struct {
+	name        string
+	tier        string
+	minInterval time.Duration
+
+	cbCodes     []string
+	minAmount   float64
+	premiumLow  float64
+	premiumHigh float64
+	topN        int
+	notional    notionals
+
+	windowStart string
+	windowEnd   string
+
+	confirmK int
+	streaks  map[string]int
+
+	basicsDate string
+	basics     map[string]cbBasic
+}
+
+func NewCBPremiumRealtime(c config.SignalConfig, p CBPremiumRealtimeParams) *CBPremiumRealtime {
+	name := c.Name
+	if name == "" {
+		name = "cb_premium_realtime"
+	}
+	tier := c.Tier
+	if tier == "" {
+		tier = "action"
+	}
+	topN := p.TopN
+	if topN <= 0 {
+		topN = 10
+	}
+	minInt := time.Duration(c.MinIntervalSeconds) * time.Second
+	if minInt <= 0 {
+		minInt = 5 * time.Second
+	}
+	confirmK := p.ConfirmK
+	if confirmK <= 0 {
+		confirmK = 1
+	}
+	// Intraday the interesting side is the conversion discount; the premium
+	// side only alerts when premium_pct_high is set.
+	low, high := p.PremiumPctLow, p.PremiumPctHigh
+	if low == 0 && high == 0 {
+		low = -2.0
+	}
+	if high == 0 {
+		high = math.Inf(1)
+	}
+	return &CBPremiumRealtime{
+		name:        name,
+		tier:        tier,
+		minInterval: minInt,
+		cbCodes:     normalizeCBCodes(p.CBCodes),
+		minAmount:   p.MinAmount,
+		premiumLow:  low,
+		premiumHigh: high,
+		topN:        topN,
+		notional:    newNotionals(p.Execution),
+		windowStart: strings.TrimSpace(p.WindowStart),
+		windowEnd:   strings.TrimSpace(p.WindowEnd),
+		confirmK:    confirmK,
+		streaks:     map[string]int{},
+	}
+}
+
+func (s *CBPremiumRealtime) Name() string { return s.name }
+
+func (s *CBPremiumRealtime) MinInterval() time.Duration { return s.minInterval }
+
+type cbRTAlert struct {
+	basic      cbBasic
+	bond       marketdata.FusionSnapshot
+	stock      marketdata.FusionSnapshot
+	bondMid    float64
+	stkMid     float64
+	convValue  float64
+	premiumPct float64
+}
+
+func (s *CBPremiumRealtime) Evaluate(ctx context.Context, client *tushare.Client, tradeDate string, md marketdata.Fusion, sess session.Info) ([]notifier.Event, error) {
+	if md == nil {
+		return nil, fmt.Errorf("marketdata disabled: enable config.marketdata and providers for %s", s.name)
+	}
+	if len(s.cbCodes) == 0 {
+		return nil, fmt.Errorf("%s: cb_codes is empty", s.name)
+	}
+	if !withinWindow(sess.Now, s.windowStart, s.windowEnd) {
+		return nil, nil
+	}
+	if err := s.refreshBasics(ctx, client, tradeDate); err != nil {
+		return nil, err
+	}
+
+	var alerts []cbRTAlert
+	for _, code := range s.cbCodes {
+		a, ok := s.check(ctx, md, code)
+		if ok {
+			s.streaks[code]++
+		} else {
+			s.streaks[code] = 0
+		}
+		if !ok || s.streaks[code] < s.confirmK {
+			continue
+		}
+		alerts = append(alerts, a)
+	}
+	if len(alerts) == 0 {
+		return nil, nil
+	}
+
+	sort.Slice(alerts, func(i, j int) bool { return math.Abs(alerts[i].premiumPct) > math.Abs(alerts[j].premiumPct) })
+	if len(alerts) > s.topN {
+		alerts = alerts[:s.topN]
+	}
+
+	events := make([]notifier.Event, 0, len(alerts))
+	for _, a := range alerts {
+		thr := s.premiumLow
+		side := "discount"
+		if a.premiumPct >= s.premiumHigh {
+			thr = s.premiumHigh
+			side = "premium"
+		}
+		bq, sq := a.bond.Quote, a.stock.Quote
+		body := fmt.Sprintf(
+			"bond=%.3f (bid %.3f / ask %.3f)\nstk=%s %.3f (bid %.3f / ask %.3f)\nconv_price=%.4f\nconv_value=%.3f\npremium=%.2f%%\nconfidence=%s/%s\n",
+			a.bondMid, bq.Bid1, bq.Ask1, a.basic.stkCode, a.stkMid, sq.Bid1, sq.Ask1, a.basic.convPrice, a.convValue, a.premiumPct, a.bond.Confidence, a.stock.Confidence,
+		)
+		data := map[string]interface{}{
+			"premium_pct":           a.premiumPct,
+			"threshold_premium_pct": thr,
+			"expected_edge_pct":     math.Abs(a.premiumPct - thr),
+			"side":                  side,
+			"bond_price":            a.bondMid,
+			"bond_bid1":             bq.Bid1,
+			"bond_ask1":             bq.Ask1,
+			"stk_code":              a.basic.stkCode,
+			"stk_price":             a.stkMid,
+			"stk_bid1":              sq.Bid1,
+			"stk_ask1":              sq.Ask1,
+			"conv_price":            a.basic.convPrice,
+			"conv_value":            a.convValue,
+			"amount":                bq.Amount,
+			"confidence":            string(a.bond.Confidence),
+		}
+		// Discount: buy the bond, sell the stock; premium: the reverse.
+		bondSide, stkSide := marketdata.Buy, marketdata.Sell
+		if side == "premium" {
+			bondSide, stkSide = marketdata.Sell, marketdata.Buy
+		}
+		// The stock leg hedges the bond leg, so both are sized by the bond.
+		notional := s.notional.For(a.bond.Symbol)
+		fillExecution(data,
+			marketdata.EstimateExecution(a.bond, bondSide, notional),
+			marketdata.EstimateExecution(a.stock, stkSide, notional))
+		events = append(events, notifier.Event{
+			Source:    s.name,
+			TradeDate: tradeDate,
+			Market:    "CN-A",
+			Symbol:    a.basic.tsCode,
+			Title:     fmt.Sprintf("CB realtime %s %.2f%% (%s)", side, a.premiumPct, a.basic.tsCode),
+			Body:      body,
+			Tags: map[string]string{
+				"kind":       "cb",
+				"strategy":   "conversion_premium",
+				"underlying": a.basic.stkCode,
+				"tier":       s.tier,
+				"confidence": string(a.bond.Confidence),
+			},
+			Data: data,
+		})
+	}
+	return events, nil
+}
+
+// check fuses the bond and its underlying and reports whether the mid premium
+// breaches a threshold with both quotes passing consensus.
+func (s *CBPremiumRealtime) check(ctx context.Context, md marketdata.Fusion, code string) (cbRTAlert, bool) {
+	b, ok := s.basics[code]
+	if !ok || b.stkCode == "" || b.convPrice <= 0 {
+		return cbRTAlert{}, false
+	}
+	bond, err := md.FetchFusion(ctx, code)
+	if err != nil || bond.Confidence != marketdata.ConfidencePass {
+		return cbRTAlert{}, false
+	}
+	if s.minAmount > 0 && bond.Quote.Amount > 0 && bond.Quote.Amount < s.minAmount {
+		return cbRTAlert{}, false
+	}
+	stock, err := md.FetchFusion(ctx, b.stkCode)
+	if err != nil || stock.Confidence != marketdata.ConfidencePass {
+		return cbRTAlert{}, false
+	}
+
+	bondMid := midPrice(bond.Quote)
+	stkMid := midPrice(stock.Quote)
+	convValue := stkMid * (100.0 / b.convPrice)
+	if bondMid <= 0 || convValue <= 0 {
+		return cbRTAlert{}, false
+	}
+	premium := (bondMid - convValue) / convValue * 100.0
+	if premium > s.premiumLow && premium < s.premiumHigh {
+		return cbRTAlert{}, false
+	}
+	return cbRTAlert{
+		basic:      b,
+		bond:       bond,
+		stock:      stock,
+		bondMid:    bondMid,
+		stkMid:     stkMid,
+		convValue:  convValue,
+		premiumPct: premium,
+	}, true
+}
+
+// midPrice is the bid/ask mid, or last if the book is missing or crossed.
+func midPrice(q marketdata.Quote) float64 {
+	if q.Bid1 <= 0 || q.Ask1 <= 0 || q.Ask1 < q.Bid1 {
+		return q.Last
+	}
+	return (q.Bid1 + q.Ask1) / 2
+}
+
+// refreshBasics loads conversion terms once per trade date (the Tushare cache,
+// when enabled, also survives restarts).
+func (s *CBPremiumRealtime) refreshBasics(ctx context.Context, client *tushare.Client, tradeDate string) error {
+	if s.basics != nil && s.basicsDate == tradeDate {
+		return nil
+	}
+	if client == nil {
+		return fmt.Errorf("%s: tushare client required for cb_basic", s.name)
+	}
+	rows, err := client.Query(ctx, "cb_basic", map[string]any{
+		"list_status": "L",
+	}, []string{"ts_code", "stk_code", "conv_price", "bond_short_name"})
+	if err != nil {
+		if s.basics != nil {
+			return nil // keep yesterday's terms rather than going blind
+		}
+		return err
+	}
+	want := map[string]bool{}
+	for _, c := range s.cbCodes {
+		want[c] = true
+	}
+	basics := map[string]cbBasic{}
+	for _, r := range rows {
+		tsCode := tushare.GetString(r, "ts_code")
+		if !want[tsCode] {
+			continue
+		}
+		basics[tsCode] = cbBasic{
+			tsCode:    tsCode,
+			stkCode:   tushare.GetString(r, "stk_code"),
+			convPrice: tushare.GetFloat(r, "conv_price"),
+		}
+	}
+	s.basics = basics
+	s.basicsDate = tradeDate
+	return nil
+}
+
+// normalizeCBCodes accepts "113050.SH", "SH113050" or bare "113050"
+// (11xxxx -> SH, 12xxxx -> SZ).
+func normalizeCBCodes(in []string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, raw := range in {
+		u := strings.ToUpper(strings.TrimSpace(raw))
+		if u == "" {
+			continue
+		}
+		if strings.HasPrefix(u, "SH") && len(u) > 2 {
+			u = u[2:] + ".SH"
+		} else if strings.HasPrefix(u, "SZ") && len(u) > 2 {
+			u = u[2:] + ".SZ"
+		}
+		if !strings.Contains(u, ".") && len(u) == 6 {
+			if strings.HasPrefix(u, "11") {
+				u = u + ".SH"
+			} else if strings.HasPrefix(u, "12") {
+				u = u + ".SZ"
+			}
+		}
+		if !strings.Contains(u, ".") {
+			continue
+		}
+		if !seen[u] {
+			seen[u] = true
+			out = append(out, u)
+		}
+	}
+	return out
+}
@@ -0,0 +1,70 @@
+package signals
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"value-sniffer-radar/internal/config"
+	"value-sniffer-radar/internal/eventschema"
+	"value-sniffer-radar/internal/marketdata"
+	"value-sniffer-radar/internal/session"
+	"value-sniffer-radar/internal/tushare"
+)
+
+func TestCBPremiumRealtimeDiscountWithSpread(t *testing.T) {
+	var basicCalls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		basicCalls++
+		_ = json.NewEncoder(w).Encode(map[string]any{"code": 0, "data": map[string]any{
+			"fields": []string{"ts_code", "stk_code", "conv_price"},
+			"items":  [][]any{{"113050.SH", "600000.SH", 10.0}, {"123100.SZ", "300750.SZ", 200.0}},
+		}})
+	}))
+	defer srv.Close()
+	client := tushare.New(tushare.Options{BaseURL: srv.URL, Token: "t"})
+
+	pass := marketdata.ConfidencePass
+	md := fakeFusion{snaps: map[string]marketdata.FusionSnapshot{
+		// conv value = 11.00 * 100/10 = 110; bond mid 107 => premium -2.73%
+		"113050.SH": {Quote: marketdata.Quote{Last: 107, Bid1: 106.9, Ask1: 107.1, Amount: 5e7}, Confidence: pass},
+		"600000.SH": {Quote: marketdata.Quote{Last: 11, Bid1: 10.99, Ask1: 11.01}, Confidence: pass},
+		// conv value = 200 * 100/200 = 100; bond 130 => +30% premium, premium side disabled
+		"123100.SZ": {Quote: marketdata.Quote{Last: 130, Bid1: 129.9, Ask1: 130.1}, Confidence: pass},
+		"300750.SZ": {Quote: marketdata.Quote{Last: 200, Bid1: 199.9, Ask1: 200.1}, Confidence: pass},
+	}}
+
+	s := NewCBPremiumRealtime(config.SignalConfig{Name: "cbrt"}, CBPremiumRealtimeParams{CBCodes: []string{"113050", "SZ123100"}, PremiumPctLow: -2})
+	for i := 0; i < 2; i++ {
+		evs, err := s.Evaluate(context.Background(), client, "20260106", md, session.Info{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(evs) != 1 || evs[0].Symbol != "113050.SH" {
+			t.Fatalf("events=%+v", evs)
+		}
+		d := evs[0].Data
+		wantPremium := (107.0 - 110.0) / 110.0 * 100
+		if got := d["premium_pct"].(float64); math.Abs(got-wantPremium) > 1e-9 {
+			t.Fatalf("premium=%v want=%v", got, wantPremium)
+		}
+		wantSpread := 0.1/107*100 + 0.01/11*100
+		if got := d["spread_pct"].(float64); math.Abs(got-wantSpread) > 1e-9 {
+			t.Fatalf("spread=%v want=%v", got, wantSpread)
+		}
+		if got := d["expected_edge_pct"].(float64); math.Abs(got-(-2-wantPremium)) > 1e-9 {
+			t.Fatalf("expected_edge=%v", got)
+		}
+		evs[0].Stamp(time.Date(2026, 1, 6, 10, 0, 0, 0, session.Location))
+		if err := eventschema.Validate(evs[0]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if basicCalls != 1 {
+		t.Fatalf("cb_basic calls=%d want=1 per trade date", basicCalls)
+	}
+}
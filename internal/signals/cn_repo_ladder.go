@@ -0,0 +1,266 @@
+package signals
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"value-sniffer-radar/internal/config"
+	"value-sniffer-radar/internal/costs"
+	"value-sniffer-radar/internal/marketdata"
+	"value-sniffer-radar/internal/notifier"
+	"value-sniffer-radar/internal/repo"
+	"value-sniffer-radar/internal/session"
+	"value-sniffer-radar/internal/tushare"
+)
+
+// CNRepoLadderParams configure cn_repo_ladder: the repo tenor with the best
+// effective yield, when it reaches min_yield_pct.
+type CNRepoLadderParams struct {
+	RepoCodes   []string `yaml:"repo_codes"`    // default: the whole GC/R ladder
+	MinYieldPct float64  `yaml:"min_yield_pct"` // default 2
+	TopN        int      `yaml:"top_n"`         // ladder rows in the body (default 5)
+	Execution   `yaml:",inline"`
+	Window      `yaml:",inline"`
+
+	costs config.CostsConfig // engine.costs: the fee schedule ranks the ladder
+}
+
+func (p *CNRepoLadderParams) Normalize(env Env) error {
+	for _, code := range normalizeRepoCodes(p.RepoCodes) {
+		if _, ok := repo.Lookup(code); !ok {
+			return errors.New("repo_codes: unknown repo " + code)
+		}
+	}
+	if err := p.Execution.normalize(env); err != nil {
+		return err
+	}
+	p.costs = env.Costs
+	return checkTopN(p.TopN)
+}
+
+func init() {
+	Register(Type{
+		Name:   "cn_repo_ladder",
+		Params: func() any { return &CNRepoLadderParams{} },
+		New: func(c config.SignalConfig, p any) (Signal, error) {
+			return NewCNRepoLadder(c, *p.(*CNRepoLadderParams)), nil
+		},
+		// Falls back to repo_daily without realtime marketdata.
+		NeedsTushare: func(_ any, env Env) bool { return !env.MarketdataEnabled },
+	})
+}
+
+// CNRepoLadder ranks the reverse repo tenor ladder (GC001..GC182, R-001..R-182)
+// by effective annualized yield: interest over the calendar days actually
+// accrued, net of the fee from the engine's cost schedule, over the days the
+// cash is locked. Around
+// weekends and holidays this is what separates e.g. a Thursday GC001 (3 days
+// accrued for 1 day locked) from a GC002 that runs into the holiday.
+//
+// Rates come from realtime marketdata fusion when enabled, else from Tushare
+// repo_daily (weighted price). Holidays come from the engine's session calendar
+// (engine.session.enabled); without it weekends are the only closed days.
+type CNRepoLadder struct {
+	name        string
+	tier        string
+	minInterval time.Duration
+
+	instruments []repo.Instrument
+	minYieldPct float64 // threshold on the best effective yield
+	topN        int     // ladder rows listed in the body
+	notional    notionals
+	fees        *costs.Model
+
+	windowStart string
+	windowEnd   string
+}
+
+func NewCNRepoLadder(c config.SignalConfig, p CNRepoLadderParams) *CNRepoLadder {
+	name := c.Name
+	if name == "" {
+		name = "cn_repo_ladder"
+	}
+	tier := c.Tier
+	if tier == "" {
+		tier = "observe"
+	}
+	topN := p.TopN
+	if topN <= 0 {
+		topN = 5
+	}
+	minYield := p.MinYieldPct
+	if minYield <= 0 {
+		minYield = 2.0
+	}
+	var insts []repo.Instrument
+	for _, code := range normalizeRepoCodes(p.RepoCodes) {
+		if in, ok := repo.Lookup(code); ok {
+			insts = append(insts, in)
+		}
+	}
+	if len(insts) == 0 {
+		insts = repo.Ladder()
+	}
+	return &CNRepoLadder{
+		name:        name,
+		tier:        tier,
+		minInterval: time.Duration(c.MinIntervalSeconds) * time.Second,
+		instruments: insts,
+		minYieldPct: minYield,
+		topN:        topN,
+		notional:    newNotionals(p.Execution),
+		fees:        costs.FromConfig(p.costs),
+		windowStart: strings.TrimSpace(p.WindowStart),
+		windowEnd:   strings.TrimSpace(p.WindowEnd),
+	}
+}
+
+func (s *CNRepoLadder) Name() string { return s.name }
+
+func (s *CNRepoLadder) MinInterval() time.Duration { return s.minInterval }
+
+type ladderRung struct {
+	in    repo.Instrument
+	settl repo.Settlement
+	yield repo.Yield
+	fs    *marketdata.FusionSnapshot // realtime only
+}
+
+func (s *CNRepoLadder) Evaluate(ctx context.Context, client *tushare.Client, tradeDate string, md marketdata.Fusion, sess session.Info) ([]notifier.Event, error) {
+	if !withinWindow(sess.Now, s.windowStart, s.windowEnd) {
+		return nil, nil
+	}
+	td, err := time.ParseInLocation("20060102", tradeDate, session.Location)
+	if err != nil {
+		return nil, fmt.Errorf("%s: trade date %q: %w", s.name, tradeDate, err)
+	}
+
+	var rungs []ladderRung
+	for _, in := range s.instruments {
+		rate, fs, ok, err := s.rate(ctx, client, md, in.Symbol, tradeDate)
+		if err != nil {
+			return nil, err
+		}
+		if !ok || rate <= 0 {
+			continue
+		}
+		st := repo.Settle(in, td, sess.Calendar)
+		rungs = append(rungs, ladderRung{in: in, settl: st, yield: repo.EffectiveYield(rate, s.feePct(in.Symbol), st), fs: fs})
+	}
+	if len(rungs) == 0 {
+		return nil, nil
+	}
+	sort.SliceStable(rungs, func(i, j int) bool { return rungs[i].yield.EffectivePct > rungs[j].yield.EffectivePct })
+
+	best := rungs[0]
+	if best.yield.EffectivePct < s.minYieldPct {
+		return nil, nil
+	}
+
+	shown := rungs
+	if len(shown) > s.topN {
+		shown = shown[:s.topN]
+	}
+	var b strings.Builder
+	ladder := make([]map[string]interface{}, 0, len(shown))
+	for i, r := range shown {
+		fmt.Fprintf(&b, "%d. %s %s rate=%.3f%% eff=%.3f%% accrual=%dd lock=%dd maturity=%s\n",
+			i+1, r.in.Name, r.in.Symbol, r.yield.RatePct, r.yield.EffectivePct,
+			r.settl.AccrualDays, r.settl.LockDays, r.settl.Maturity.Format("2006-01-02"))
+		ladder = append(ladder, map[string]interface{}{
+			"symbol":              r.in.Symbol,
+			"tenor_days":          r.in.TenorDays,
+			"rate_pct":            r.yield.RatePct,
+			"effective_yield_pct": r.yield.EffectivePct,
+			"accrual_days":        r.settl.AccrualDays,
+			"lock_days":           r.settl.LockDays,
+		})
+	}
+
+	data := map[string]interface{}{
+		"rate_pct":            best.yield.RatePct,
+		"tenor_days":          best.in.TenorDays,
+		"accrual_days":        best.settl.AccrualDays,
+		"lock_days":           best.settl.LockDays,
+		"first_settle_date":   best.settl.FirstSettle.Format("20060102"),
+		"maturity_date":       best.settl.Maturity.Format("20060102"),
+		"usable_date":         best.settl.Usable.Format("20060102"),
+		"gross_yield_pct":     best.yield.GrossPct,
+		"effective_yield_pct": best.yield.EffectivePct,
+		"threshold_yield_pct": s.minYieldPct,
+		// Gross: the net edge policy subtracts fee_pct, annualized over the
+		// lock days like effective_yield_pct rather than over the tenor.
+		"expected_edge_pct": best.yield.GrossPct - s.minYieldPct,
+		"fee_pct":           best.yield.FeePct,
+		"ladder":            ladder,
+	}
+	if best.fs != nil {
+		fillExecution(data, marketdata.EstimateExecution(*best.fs, marketdata.Sell, s.notional.For(best.in.Symbol)))
+		// Book costs are quoted rate points; they scale like the rate.
+		scale := float64(best.settl.AccrualDays) / float64(best.settl.LockDays)
+		for _, k := range []string{"spread_pct", "slippage_pct"} {
+			if v, ok := data[k].(float64); ok {
+				data[k] = v * scale
+			}
+		}
+	}
+
+	return []notifier.Event{{
+		Source:    s.name,
+		TradeDate: tradeDate,
+		Market:    "CN-A",
+		Symbol:    best.in.Symbol,
+		Title:     fmt.Sprintf("Repo ladder best %s eff %.2f%% (rate %.2f%%, %dd accrued)", best.in.Name, best.yield.EffectivePct, best.yield.RatePct, best.settl.AccrualDays),
+		Body:      b.String(),
+		Tags: map[string]string{
+			"kind":     "repo",
+			"strategy": "tenor_ladder",
+			"tier":     s.tier,
+		},
+		Data: data,
+	}}, nil
+}
+
+// feePct is the one-off fee of lending notional through symbol, in pct of the
+// notional, as priced by the cost schedule.
+func (s *CNRepoLadder) feePct(symbol string) float64 {
+	b, ok := s.fees.Fees("CN-A", []costs.Leg{{Symbol: symbol, Side: marketdata.Sell, Notional: s.notional.For(symbol)}})
+	if !ok || b.Legs[0].Notional <= 0 {
+		return 0
+	}
+	return b.Legs[0].Amount() / b.Legs[0].Notional * 100.0
+}
+
+// rate returns the current rate of symbol: the fused realtime consensus when
+// marketdata is on (only if the sources agree), else the repo_daily weighted price.
+func (s *CNRepoLadder) rate(ctx context.Context, client *tushare.Client, md marketdata.Fusion, symbol, tradeDate string) (float64, *marketdata.FusionSnapshot, bool, error) {
+	if md != nil {
+		fs, err := md.FetchFusion(ctx, symbol)
+		if err != nil || fs.Confidence != marketdata.ConfidencePass {
+			return 0, nil, false, nil
+		}
+		return fs.ConsensusRatePct, &fs, true, nil
+	}
+	if client == nil {
+		return 0, nil, false, fmt.Errorf("%s: needs marketdata or Tushare", s.name)
+	}
+	rows, err := client.Query(ctx, "repo_daily", map[string]any{
+		"ts_code":    symbol,
+		"trade_date": tradeDate,
+	}, []string{"ts_code", "close", "weight"})
+	if err != nil {
+		return 0, nil, false, err
+	}
+	for _, r := range rows {
+		rate := tushare.GetFloat(r, "weight")
+		if rate <= 0 {
+			rate = tushare.GetFloat(r, "close")
+		}
+		return rate, nil, true, nil
+	}
+	return 0, nil, false, nil
+}
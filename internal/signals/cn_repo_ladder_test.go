@@ -0,0 +1,68 @@
+package signals
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"value-sniffer-radar/internal/config"
+	"value-sniffer-radar/internal/eventschema"
+	"value-sniffer-radar/internal/marketdata"
+	"value-sniffer-radar/internal/session"
+)
+
+func TestCNRepoLadderRanksByEffectiveYield(t *testing.T) {
+	s := NewCNRepoLadder(config.SignalConfig{Name: "ladder"}, CNRepoLadderParams{
+		RepoCodes: []string{"204001", "204007", "131810"}, MinYieldPct: 2.0,
+	})
+	rt := func(sym string, rate float64) marketdata.FusionSnapshot {
+		return marketdata.FusionSnapshot{Symbol: sym, Class: marketdata.ClassRepo, ConsensusRatePct: rate, Confidence: marketdata.ConfidencePass}
+	}
+	md := fakeFusion{snaps: map[string]marketdata.FusionSnapshot{
+		"204001.SH": rt("204001.SH", 1.5),
+		"204007.SH": rt("204007.SH", 2.5),
+		"131810.SZ": {Symbol: "131810.SZ", ConsensusRatePct: 9.0, Confidence: marketdata.ConfidenceFail},
+	}}
+	now := time.Date(2026, 1, 8, 10, 0, 0, 0, session.Location)
+
+	// Thursday: GC001 accrues 3 days over a 1-day lock (~4.5%) and beats GC007 (2.5% over 7/7);
+	// the disagreeing SZ quote is skipped.
+	evs, err := s.Evaluate(context.Background(), nil, "20260108", md, session.Info{Now: now})
+	if err != nil || len(evs) != 1 {
+		t.Fatalf("events=%d err=%v", len(evs), err)
+	}
+	ev := evs[0]
+	if ev.Symbol != "204001.SH" || ev.Data["accrual_days"] != 3 || ev.Data["lock_days"] != 1 {
+		t.Fatalf("event=%s data=%v", ev.Symbol, ev.Data)
+	}
+	if l := ev.Data["ladder"].([]map[string]interface{}); len(l) != 2 || l[1]["symbol"] != "204007.SH" {
+		t.Fatalf("ladder=%v", l)
+	}
+	ev.Stamp(now)
+	if err := eventschema.Validate(ev); err != nil {
+		t.Fatal(err)
+	}
+
+	// Friday: GC001 earns 1 day over a 3-day lock, GC007 wins but stays above the threshold.
+	evs, _ = s.Evaluate(context.Background(), nil, "20260109", md, session.Info{Now: now.AddDate(0, 0, 1)})
+	if len(evs) != 1 || evs[0].Symbol != "204007.SH" {
+		t.Fatalf("friday events=%+v", evs)
+	}
+
+	// A Friday GC001 spike still wins; its fee is spread over the 3-day lock,
+	// not the 1-day tenor, matching effective_yield_pct.
+	md.snaps["204001.SH"] = rt("204001.SH", 20)
+	evs, _ = s.Evaluate(context.Background(), nil, "20260109", md, session.Info{Now: now.AddDate(0, 0, 1)})
+	if len(evs) != 1 || evs[0].Symbol != "204001.SH" || evs[0].Data["lock_days"] != 3 {
+		t.Fatalf("friday spike events=%+v", evs)
+	}
+	d := evs[0].Data
+	fee := d["fee_pct"].(float64)
+	if want := s.feePct("204001.SH") * 365 / 3; fee <= 0 || math.Abs(fee-want) > 1e-9 {
+		t.Fatalf("fee_pct=%v want=%v", fee, want)
+	}
+	if gap := d["gross_yield_pct"].(float64) - d["effective_yield_pct"].(float64); math.Abs(gap-fee) > 1e-9 {
+		t.Fatalf("gross-effective=%v fee_pct=%v", gap, fee)
+	}
+}
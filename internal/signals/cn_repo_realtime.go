@@ -3,6 +3,7 @@ package signals
 import (
 	"context"
 	"fmt"
+	"log"
 	"sort"
 	"strings"
 	"time"
@@ -10,9 +11,65 @@ import (
 	"value-sniffer-radar/internal/config"
 	"value-sniffer-radar/internal/marketdata"
 	"value-sniffer-radar/internal/notifier"
+	"value-sniffer-radar/internal/repo"
+	"value-sniffer-radar/internal/session"
 	"value-sniffer-radar/internal/tushare"
 )
 
+// CNRepoRealtimeParams configure cn_repo_realtime: fused intraday repo
+// rates at or above the yield threshold.
+type CNRepoRealtimeParams struct {
+	RepoCodes     []string `yaml:"repo_codes"` // default 204001.SH, 131810.SZ
+	RepoThreshold `yaml:",inline"`
+	TopN          int `yaml:"top_n"`     // default 5
+	ConfirmK      int `yaml:"confirm_k"` // consecutive breaches before alerting (default 1)
+	Execution     `yaml:",inline"`
+	Window        `yaml:",inline"`
+
+	history *repo.History // from Env.Histories, percentile mode only
+}
+
+func (p *CNRepoRealtimeParams) Normalize(env Env) error {
+	if err := p.RepoThreshold.normalize(env); err != nil {
+		return err
+	}
+	if err := p.Execution.normalize(env); err != nil {
+		return err
+	}
+	if err := checkConfirmK(p.ConfirmK); err != nil {
+		return err
+	}
+	if err := checkTopN(p.TopN); err != nil {
+		return err
+	}
+	if p.ThresholdMode == "percentile" && env.Histories != nil {
+		h, err := env.Histories.Open(p.HistoryPath)
+		if err != nil {
+			log.Printf("repo history unavailable, using min_yield_pct: %v", err)
+		}
+		p.history = h
+	}
+	return nil
+}
+
+// RepoHistoryPath is the history the signal reads, empty in fixed mode.
+func (p CNRepoRealtimeParams) RepoHistoryPath() string {
+	if p.ThresholdMode != "percentile" {
+		return ""
+	}
+	return p.HistoryPath
+}
+
+func init() {
+	Register(Type{
+		Name:   "cn_repo_realtime",
+		Params: func() any { return &CNRepoRealtimeParams{} },
+		New: func(c config.SignalConfig, p any) (Signal, error) {
+			return NewCNRepoRealtime(c, *p.(*CNRepoRealtimeParams)), nil
+		},
+	})
+}
+
 // CNRepoRealtime alerts on reverse repo yield spikes using realtime marketdata fusion.
 // It will only emit tier=action when fusion confidence passes (multi-source consensus).
 type CNRepoRealtime struct {
@@ -20,9 +77,11 @@ type CNRepoRealtime struct {
 	tier        string
 	minInterval time.Duration
 
-	repoCodes   []string
-	minYieldPct float64
-	topN        int
+	repoCodes []string
+	threshold repoThreshold
+	history   *repo.History // percentile mode only
+	topN      int
+	notional  notionals
 
 	windowStart string
 	windowEnd   string
@@ -31,7 +90,7 @@ type CNRepoRealtime struct {
 	streaks  map[string]int
 }
 
-func NewCNRepoRealtime(c config.SignalConfig) *CNRepoRealtime {
+func NewCNRepoRealtime(c config.SignalConfig, p CNRepoRealtimeParams) *CNRepoRealtime {
 	name := c.Name
 	if name == "" {
 		name = "cn_repo_realtime"
@@ -40,15 +99,15 @@ func NewCNRepoRealtime(c config.SignalConfig) *CNRepoRealtime {
 	if tier == "" {
 		tier = "action"
 	}
-	topN := c.TopN
+	topN := p.TopN
 	if topN <= 0 {
 		topN = 5
 	}
-	minYield := c.MinYieldPct
+	minYield := p.MinYieldPct
 	if minYield <= 0 {
 		minYield = 4.0
 	}
-	repoCodes := normalizeRepoCodes(c.RepoCodes)
+	repoCodes := normalizeRepoCodes(p.RepoCodes)
 	if len(repoCodes) == 0 {
 		repoCodes = []string{"204001.SH", "131810.SZ"}
 	}
@@ -56,20 +115,24 @@ func NewCNRepoRealtime(c config.SignalConfig) *CNRepoRealtime {
 	if minInt <= 0 {
 		minInt = 3 * time.Second
 	}
-	confirmK := c.ConfirmK
+	confirmK := p.ConfirmK
 	if confirmK <= 0 {
 		confirmK = 1
 	}
 
+	threshold := newRepoThreshold(p.RepoThreshold, minYield)
+
 	return &CNRepoRealtime{
 		name:        name,
 		tier:        tier,
 		minInterval: minInt,
 		repoCodes:   repoCodes,
-		minYieldPct: minYield,
+		threshold:   threshold,
+		history:     p.history,
 		topN:        topN,
-		windowStart: strings.TrimSpace(c.WindowStart),
-		windowEnd:   strings.TrimSpace(c.WindowEnd),
+		notional:    newNotionals(p.Execution),
+		windowStart: strings.TrimSpace(p.WindowStart),
+		windowEnd:   strings.TrimSpace(p.WindowEnd),
 		confirmK:    confirmK,
 		streaks:     map[string]int{},
 	}
@@ -80,18 +143,20 @@ func (s *CNRepoRealtime) Name() string { return s.name }
 func (s *CNRepoRealtime) MinInterval() time.Duration { return s.minInterval }
 
 type repoRTAlert struct {
+	fs        marketdata.FusionSnapshot
 	tsCode    string
 	ratePct   float64
+	thr       thresholdPick
 	conf      marketdata.Confidence
 	reason    string
 	providers []marketdata.ProviderResult
 }
 
-func (s *CNRepoRealtime) Evaluate(ctx context.Context, _ *tushare.Client, tradeDate string, md marketdata.Fusion) ([]notifier.Event, error) {
+func (s *CNRepoRealtime) Evaluate(ctx context.Context, _ *tushare.Client, tradeDate string, md marketdata.Fusion, sess session.Info) ([]notifier.Event, error) {
 	if md == nil {
 		return nil, fmt.Errorf("marketdata disabled: enable config.marketdata and providers for %s", s.name)
 	}
-	if !withinWindow(time.Now(), s.windowStart, s.windowEnd) {
+	if !withinWindow(sess.Now, s.windowStart, s.windowEnd) {
 		return nil, nil
 	}
 
@@ -103,7 +168,13 @@ func (s *CNRepoRealtime) Evaluate(ctx context.Context, _ *tushare.Client, tradeD
 		}
 
 		pass := fs.Confidence == marketdata.ConfidencePass
-		thr := fs.ConsensusRatePct >= s.minYieldPct
+		var samples []float64
+		if s.history != nil {
+			// The engine's repo sampler records fusion results into the history.
+			samples = s.history.SameTimeOfDay(code, sess.Now, s.threshold.lookback)
+		}
+		pick := s.threshold.pick(samples)
+		thr := fs.ConsensusRatePct >= pick.value
 		if pass && thr {
 			s.streaks[code]++
 		} else {
@@ -118,8 +189,10 @@ func (s *CNRepoRealtime) Evaluate(ctx context.Context, _ *tushare.Client, tradeD
 		}
 
 		alerts = append(alerts, repoRTAlert{
+			fs:        fs,
 			tsCode:    code,
 			ratePct:   fs.ConsensusRatePct,
+			thr:       pick,
 			conf:      fs.Confidence,
 			reason:    fs.Reason,
 			providers: fs.Providers,
@@ -143,9 +216,20 @@ func (s *CNRepoRealtime) Evaluate(ctx context.Context, _ *tushare.Client, tradeD
 				body += fmt.Sprintf("- %s: error=%s\n", pr.Provider, pr.Error)
 				continue
 			}
-			body += fmt.Sprintf("- %s: rate=%.4f%% inlier=%v outlier=%v\n", pr.Provider, pr.Snapshot.RatePct, pr.Inlier, pr.Outlier)
+			body += fmt.Sprintf("- %s: rate=%.4f%% inlier=%v outlier=%v\n", pr.Provider, pr.Snapshot.Quote.Last, pr.Inlier, pr.Outlier)
 		}
 
+		data := map[string]any{
+			"consensus_rate_pct": a.ratePct,
+			"expected_edge_pct":  a.ratePct - a.thr.value,
+			"confidence":         string(a.conf),
+			"reason":             a.reason,
+			"providers":          a.providers,
+		}
+		s.threshold.fill(data, a.thr)
+		// Lending cash via reverse repo sells at the bid rate; costs are in rate points.
+		fillExecution(data, marketdata.EstimateExecution(a.fs, marketdata.Sell, s.notional.For(a.fs.Symbol)))
+
 		events = append(events, notifier.Event{
 			Source:    s.name,
 			TradeDate: tradeDate,
@@ -159,14 +243,7 @@ func (s *CNRepoRealtime) Evaluate(ctx context.Context, _ *tushare.Client, tradeD
 				"tier":       s.tier,
 				"confidence": string(a.conf),
 			},
-			Data: map[string]any{
-				"consensus_rate_pct":  a.ratePct,
-				"threshold_yield_pct": s.minYieldPct,
-				"expected_edge_pct":   a.ratePct - s.minYieldPct,
-				"confidence":          string(a.conf),
-				"reason":              a.reason,
-				"providers":           a.providers,
-			},
+			Data: data,
 		})
 	}
 	return events, nil
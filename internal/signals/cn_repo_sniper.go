@@ -10,9 +10,38 @@ import (
 	"value-sniffer-radar/internal/config"
 	"value-sniffer-radar/internal/marketdata"
 	"value-sniffer-radar/internal/notifier"
+	"value-sniffer-radar/internal/session"
 	"value-sniffer-radar/internal/tushare"
 )
 
+// CNRepoSniperParams configure cn_repo_sniper: daily repo rates at or above
+// the yield threshold.
+type CNRepoSniperParams struct {
+	RepoCodes     []string `yaml:"repo_codes"` // default 204001.SH, 131810.SZ
+	RepoThreshold `yaml:",inline"`
+	MinAmount     float64 `yaml:"min_amount"`
+	TopN          int     `yaml:"top_n"` // default 10
+	Window        `yaml:",inline"`
+}
+
+func (p *CNRepoSniperParams) Normalize(env Env) error {
+	if err := p.RepoThreshold.normalize(env); err != nil {
+		return err
+	}
+	return checkTopN(p.TopN)
+}
+
+func init() {
+	Register(Type{
+		Name:   "cn_repo_sniper",
+		Params: func() any { return &CNRepoSniperParams{} },
+		New: func(c config.SignalConfig, p any) (Signal, error) {
+			return NewCNRepoSniper(c, *p.(*CNRepoSniperParams)), nil
+		},
+		NeedsTushare: always,
+	})
+}
+
 // CNRepoSniper alerts on CN reverse repo "yield" spikes.
 //
 // NOTE:
@@ -23,16 +52,16 @@ type CNRepoSniper struct {
 	tier        string
 	minInterval time.Duration
 
-	repoCodes   []string
-	minYieldPct float64
-	minAmount   float64
-	topN        int
+	repoCodes []string
+	threshold repoThreshold
+	minAmount float64
+	topN      int
 
 	windowStart string
 	windowEnd   string
 }
 
-func NewCNRepoSniper(c config.SignalConfig) *CNRepoSniper {
+func NewCNRepoSniper(c config.SignalConfig, p CNRepoSniperParams) *CNRepoSniper {
 	name := c.Name
 	if name == "" {
 		name = "cn_repo_sniper"
@@ -41,16 +70,16 @@ func NewCNRepoSniper(c config.SignalConfig) *CNRepoSniper {
 	if tier == "" {
 		tier = "action"
 	}
-	topN := c.TopN
+	topN := p.TopN
 	if topN <= 0 {
 		topN = 10
 	}
-	minYield := c.MinYieldPct
+	minYield := p.MinYieldPct
 	if minYield <= 0 {
 		// Default threshold is deliberately conservative: only alert when rates look "interesting".
 		minYield = 4.0
 	}
-	repoCodes := normalizeRepoCodes(c.RepoCodes)
+	repoCodes := normalizeRepoCodes(p.RepoCodes)
 	if len(repoCodes) == 0 {
 		// Commonly traded baseline repos:
 		// - 204001: SH 1-day reverse repo (GC001 in colloquial naming)
@@ -63,11 +92,11 @@ func NewCNRepoSniper(c config.SignalConfig) *CNRepoSniper {
 		tier:        tier,
 		minInterval: minInt,
 		repoCodes:   repoCodes,
-		minYieldPct: minYield,
-		minAmount:   c.MinAmount,
+		threshold:   newRepoThreshold(p.RepoThreshold, minYield),
+		minAmount:   p.MinAmount,
 		topN:        topN,
-		windowStart: strings.TrimSpace(c.WindowStart),
-		windowEnd:   strings.TrimSpace(c.WindowEnd),
+		windowStart: strings.TrimSpace(p.WindowStart),
+		windowEnd:   strings.TrimSpace(p.WindowEnd),
 	}
 }
 
@@ -85,15 +114,25 @@ type repoAlert struct {
 	vol        float64
 	avgAmt     float64
 	amountHint string
+	thr        thresholdPick
 }
 
-func (s *CNRepoSniper) Evaluate(ctx context.Context, client *tushare.Client, tradeDate string, _ marketdata.Fusion) ([]notifier.Event, error) {
-	if !withinWindow(time.Now(), s.windowStart, s.windowEnd) {
+func (s *CNRepoSniper) Evaluate(ctx context.Context, client *tushare.Client, tradeDate string, _ marketdata.Fusion, sess session.Info) ([]notifier.Event, error) {
+	if !withinWindow(sess.Now, s.windowStart, s.windowEnd) {
 		return nil, nil
 	}
 
 	var alerts []repoAlert
 	for _, code := range s.repoCodes {
+		var samples []float64
+		if s.threshold.percentile {
+			var err error
+			if samples, err = s.dailyHistory(ctx, client, code, tradeDate); err != nil {
+				return nil, err
+			}
+		}
+		pick := s.threshold.pick(samples)
+
 		rows, err := client.Query(ctx, "repo_daily", map[string]any{
 			"ts_code":    code,
 			"trade_date": tradeDate,
@@ -116,7 +155,7 @@ func (s *CNRepoSniper) Evaluate(ctx context.Context, client *tushare.Client, tra
 			if s.minAmount > 0 && amount > 0 && amount < s.minAmount {
 				continue
 			}
-			if rate < s.minYieldPct {
+			if rate < pick.value {
 				continue
 			}
 			alerts = append(alerts, repoAlert{
@@ -128,6 +167,7 @@ func (s *CNRepoSniper) Evaluate(ctx context.Context, client *tushare.Client, tra
 				amount:    amount,
 				vol:       tushare.GetFloat(r, "vol"),
 				avgAmt:    tushare.GetFloat(r, "avg_amt"),
+				thr:       pick,
 			})
 		}
 	}
@@ -151,6 +191,16 @@ func (s *CNRepoSniper) Evaluate(ctx context.Context, client *tushare.Client, tra
 			"rate=%.4f%%\nts_code=%s\nclose=%.4f\nweight=%.4f\namount=%.0f\nvol=%.0f\navg_amt=%.0f\n",
 			a.ratePct, a.tsCode, a.close, a.weight, a.amount, a.vol, a.avgAmt,
 		)
+		data := map[string]interface{}{
+			"rate_pct":          a.ratePct,
+			"expected_edge_pct": a.ratePct - a.thr.value,
+			"close":             a.close,
+			"weight":            a.weight,
+			"amount":            a.amount,
+			"vol":               a.vol,
+			"avg_amt":           a.avgAmt,
+		}
+		s.threshold.fill(data, a.thr)
 		events = append(events, notifier.Event{
 			Source:    s.name,
 			TradeDate: td,
@@ -163,21 +213,52 @@ func (s *CNRepoSniper) Evaluate(ctx context.Context, client *tushare.Client, tra
 				"strategy": "yield_spike",
 				"tier":     s.tier,
 			},
-			Data: map[string]interface{}{
-				"rate_pct":            a.ratePct,
-				"threshold_yield_pct": s.minYieldPct,
-				"expected_edge_pct":   a.ratePct - s.minYieldPct,
-				"close":               a.close,
-				"weight":              a.weight,
-				"amount":              a.amount,
-				"vol":                 a.vol,
-				"avg_amt":             a.avgAmt,
-			},
+			Data: data,
 		})
 	}
 	return events, nil
 }
 
+// dailyHistory returns the weighted rates of code on up to lookback trade days
+// before tradeDate, newest first.
+func (s *CNRepoSniper) dailyHistory(ctx context.Context, client *tushare.Client, code, tradeDate string) ([]float64, error) {
+	td, err := time.Parse("20060102", tradeDate)
+	if err != nil {
+		return nil, err
+	}
+	// Calendar span that covers lookback trade days plus a long holiday.
+	start := td.AddDate(0, 0, -(s.threshold.lookback*7/5 + 14)).Format("20060102")
+	rows, err := client.Query(ctx, "repo_daily", map[string]any{
+		"ts_code":    code,
+		"start_date": start,
+		"end_date":   tradeDate,
+	}, []string{"trade_date", "close", "weight"})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return tushare.GetString(rows[i], "trade_date") > tushare.GetString(rows[j], "trade_date")
+	})
+	var out []float64
+	for _, r := range rows {
+		if tushare.GetString(r, "trade_date") >= tradeDate {
+			continue
+		}
+		rate := tushare.GetFloat(r, "weight")
+		if rate <= 0 {
+			rate = tushare.GetFloat(r, "close")
+		}
+		if rate <= 0 {
+			continue
+		}
+		out = append(out, rate)
+		if len(out) >= s.threshold.lookback {
+			break
+		}
+	}
+	return out, nil
+}
+
 func normalizeRepoCodes(in []string) []string {
 	var out []string
 	seen := map[string]bool{}
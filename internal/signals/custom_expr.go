@@ -0,0 +1,501 @@
+package signals
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"value-sniffer-radar/internal/config"
+	"value-sniffer-radar/internal/eventschema"
+	"value-sniffer-radar/internal/expr"
+	"value-sniffer-radar/internal/marketdata"
+	"value-sniffer-radar/internal/notifier"
+	"value-sniffer-radar/internal/session"
+	"value-sniffer-radar/internal/tushare"
+)
+
+// CustomExprParams configure custom_expr, a screen written in config instead
+// of Go: rows come from datasets (Tushare APIs or fused realtime quotes)
+// joined on ts_code, gain derived columns, and alert when filter holds.
+// Expressions use package expr.
+type CustomExprParams struct {
+	Kind     string          `yaml:"kind"`     // tags.kind: repo | cb | fund; selects the data schema
+	Strategy string          `yaml:"strategy"` // tags.strategy, default custom_expr
+	Datasets []CustomDataset `yaml:"datasets"` // the first is the base; the others join onto it
+	Derive   []CustomColumn  `yaml:"derive"`   // in order; later columns may use earlier ones
+	Filter   string          `yaml:"filter"`   // rows for which this is true alert
+	Sort     string          `yaml:"sort"`     // highest first (negate for lowest); default dataset order
+	TopN     int             `yaml:"top_n"`    // default 20
+	Event    CustomEvent     `yaml:"event"`
+	Window   `yaml:",inline"`
+}
+
+// CustomDataset is one source of rows. Its columns are reachable both as
+// name.column and, unless an earlier dataset has the column, as column.
+type CustomDataset struct {
+	Name   string         `yaml:"name"`   // default: the api, or md for marketdata
+	API    string         `yaml:"api"`    // Tushare api_name
+	Params map[string]any `yaml:"params"` // "{trade_date}" in string values becomes the trade date
+	Fields []string       `yaml:"fields"` // Tushare fields; also the columns a left join leaves null
+	// Marketdata lists codes ("510300.SH") to fetch fused quotes for instead
+	// of calling api. Rows: ts_code, last, bid1, ask1, mid, bid1_size,
+	// ask1_size, volume, amount, iopv, prev_close; quotes failing fusion are
+	// left out.
+	Marketdata []string `yaml:"marketdata"`
+	Join       string   `yaml:"join"` // on ts_code: inner (default) drops unmatched rows, left keeps them
+}
+
+// CustomColumn is a derived column.
+type CustomColumn struct {
+	Name string `yaml:"name"`
+	Expr string `yaml:"expr"`
+}
+
+// CustomEvent maps a row onto the event.
+type CustomEvent struct {
+	Symbol string            `yaml:"symbol"` // expression, default ts_code
+	Title  string            `yaml:"title"`  // template: {expr} or {expr:%.2f}; default "<name> <symbol>"
+	Body   string            `yaml:"body"`   // template; default the data fields, one per line
+	Data   map[string]string `yaml:"data"`   // data key -> expression; must cover the kind's required fields (expected_edge_pct, ...)
+}
+
+var identRE = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func (p *CustomExprParams) Normalize(Env) error {
+	if _, ok := eventschema.Kinds[p.Kind]; !ok {
+		return errors.New("kind must be repo, cb or fund")
+	}
+	if len(p.Datasets) == 0 {
+		return errors.New("datasets required")
+	}
+	seen := map[string]bool{}
+	for i := range p.Datasets {
+		d := &p.Datasets[i]
+		if (d.API == "") == (len(d.Marketdata) == 0) {
+			return fmt.Errorf("datasets[%d]: set one of api or marketdata", i)
+		}
+		if d.Name == "" {
+			d.Name = d.API
+			if d.Name == "" {
+				d.Name = "md"
+			}
+		}
+		if !identRE.MatchString(d.Name) || seen[d.Name] {
+			return fmt.Errorf("datasets[%d]: name %q must be a unique identifier", i, d.Name)
+		}
+		seen[d.Name] = true
+		d.Join = strings.ToLower(strings.TrimSpace(d.Join))
+		switch {
+		case i == 0 && d.Join != "":
+			return fmt.Errorf("datasets[0]: the base dataset does not join")
+		case d.Join == "" && i > 0:
+			d.Join = "inner"
+		case d.Join != "" && d.Join != "inner" && d.Join != "left":
+			return fmt.Errorf("datasets[%d]: join must be inner or left", i)
+		}
+		codes := d.Marketdata
+		d.Marketdata = nil
+		for _, c := range codes {
+			c = strings.ToUpper(strings.TrimSpace(c))
+			if !strings.Contains(c, ".") {
+				return fmt.Errorf("datasets[%d]: marketdata code %q needs an exchange suffix (.SH/.SZ)", i, c)
+			}
+			d.Marketdata = append(d.Marketdata, c)
+		}
+	}
+	if strings.TrimSpace(p.Filter) == "" {
+		return errors.New("filter required")
+	}
+	if p.Strategy == "" {
+		p.Strategy = "custom_expr"
+	}
+	for _, k := range eventschema.Required(p.Kind) {
+		if p.Event.Data[k] == "" {
+			return fmt.Errorf("event.data.%s required for kind %s", k, p.Kind)
+		}
+	}
+	if err := checkTopN(p.TopN); err != nil {
+		return err
+	}
+	_, err := p.compile()
+	return err
+}
+
+func init() {
+	Register(Type{
+		Name:   "custom_expr",
+		Params: func() any { return &CustomExprParams{} },
+		New: func(c config.SignalConfig, p any) (Signal, error) {
+			return NewCustomExpr(c, *p.(*CustomExprParams))
+		},
+		NeedsTushare: func(p any, _ Env) bool {
+			for _, d := range p.(*CustomExprParams).Datasets {
+				if d.API != "" {
+					return true
+				}
+			}
+			return false
+		},
+	})
+}
+
+type namedExpr struct {
+	name string
+	x    *expr.Expr
+}
+
+// customProgram is CustomExprParams compiled.
+type customProgram struct {
+	derive       []namedExpr
+	filter, sort *expr.Expr
+	symbol       *expr.Expr
+	title, body  *expr.Template // nil: defaults
+	data         []namedExpr    // sorted by name
+}
+
+// compile parses every expression; errors name the key they come from.
+func (p *CustomExprParams) compile() (customProgram, error) {
+	var prog customProgram
+	var err error
+	for i, d := range p.Derive {
+		if !identRE.MatchString(d.Name) {
+			return prog, fmt.Errorf("derive[%d]: name %q must be an identifier", i, d.Name)
+		}
+		x, err := expr.Compile(d.Expr)
+		if err != nil {
+			return prog, fmt.Errorf("derive[%s]: %w", d.Name, err)
+		}
+		prog.derive = append(prog.derive, namedExpr{d.Name, x})
+	}
+	if prog.filter, err = expr.Compile(p.Filter); err != nil {
+		return prog, fmt.Errorf("filter: %w", err)
+	}
+	if p.Sort != "" {
+		if prog.sort, err = expr.Compile(p.Sort); err != nil {
+			return prog, fmt.Errorf("sort: %w", err)
+		}
+	}
+	symbol := p.Event.Symbol
+	if symbol == "" {
+		symbol = "ts_code"
+	}
+	if prog.symbol, err = expr.Compile(symbol); err != nil {
+		return prog, fmt.Errorf("event.symbol: %w", err)
+	}
+	if p.Event.Title != "" {
+		if prog.title, err = expr.CompileTemplate(p.Event.Title); err != nil {
+			return prog, fmt.Errorf("event.title: %w", err)
+		}
+	}
+	if p.Event.Body != "" {
+		if prog.body, err = expr.CompileTemplate(p.Event.Body); err != nil {
+			return prog, fmt.Errorf("event.body: %w", err)
+		}
+	}
+	keys := make([]string, 0, len(p.Event.Data))
+	for k := range p.Event.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		x, err := expr.Compile(p.Event.Data[k])
+		if err != nil {
+			return prog, fmt.Errorf("event.data.%s: %w", k, err)
+		}
+		prog.data = append(prog.data, namedExpr{k, x})
+	}
+	return prog, nil
+}
+
+// CustomExpr is the signal a custom_expr entry declares.
+type CustomExpr struct {
+	name        string
+	tier        string
+	minInterval time.Duration
+
+	kind     string
+	strategy string
+	datasets []CustomDataset
+	prog     customProgram
+	topN     int
+
+	windowStart string
+	windowEnd   string
+}
+
+func NewCustomExpr(c config.SignalConfig, p CustomExprParams) (*CustomExpr, error) {
+	prog, err := p.compile()
+	if err != nil {
+		return nil, err
+	}
+	name := c.Name
+	if name == "" {
+		name = "custom_expr"
+	}
+	tier := c.Tier
+	if tier == "" {
+		tier = "observe"
+	}
+	topN := p.TopN
+	if topN <= 0 {
+		topN = 20
+	}
+	strategy := p.Strategy
+	if strategy == "" {
+		strategy = "custom_expr"
+	}
+	return &CustomExpr{
+		name:        name,
+		tier:        tier,
+		minInterval: time.Duration(c.MinIntervalSeconds) * time.Second,
+		kind:        p.Kind,
+		strategy:    strategy,
+		datasets:    p.Datasets,
+		prog:        prog,
+		topN:        topN,
+		windowStart: strings.TrimSpace(p.WindowStart),
+		windowEnd:   strings.TrimSpace(p.WindowEnd),
+	}, nil
+}
+
+func (s *CustomExpr) Name() string { return s.name }
+
+func (s *CustomExpr) MinInterval() time.Duration { return s.minInterval }
+
+type customHit struct {
+	row    expr.Map
+	key    float64
+	hasKey bool
+}
+
+func (s *CustomExpr) Evaluate(ctx context.Context, client *tushare.Client, tradeDate string, md marketdata.Fusion, sess session.Info) ([]notifier.Event, error) {
+	if !withinWindow(sess.Now, s.windowStart, s.windowEnd) {
+		return nil, nil
+	}
+
+	var rows []expr.Map
+	for i, d := range s.datasets {
+		got, cols, err := s.fetch(ctx, d, client, md, tradeDate)
+		if err != nil {
+			return nil, fmt.Errorf("%s: dataset %s: %w", s.name, d.Name, err)
+		}
+		if i == 0 {
+			for _, r := range got {
+				row := make(expr.Map, 2*len(r))
+				for k, v := range r {
+					row[k] = v
+					row[d.Name+"."+k] = v
+				}
+				rows = append(rows, row)
+			}
+			continue
+		}
+		rows = joinRows(rows, d, got, cols)
+	}
+
+	var hits []customHit
+	for _, row := range rows {
+		for _, d := range s.prog.derive {
+			v, err := d.x.Eval(row)
+			if err != nil {
+				return nil, s.rowErr(row, "derive "+d.name, err)
+			}
+			row[d.name] = v
+		}
+		ok, err := s.prog.filter.Bool(row)
+		if err != nil {
+			return nil, s.rowErr(row, "filter", err)
+		}
+		if !ok {
+			continue
+		}
+		h := customHit{row: row}
+		if s.prog.sort != nil {
+			if h.key, h.hasKey, err = s.prog.sort.Number(row); err != nil {
+				return nil, s.rowErr(row, "sort", err)
+			}
+		}
+		hits = append(hits, h)
+	}
+	if s.prog.sort != nil {
+		// Rows whose sort key is null go last.
+		sort.SliceStable(hits, func(i, j int) bool {
+			if hits[i].hasKey != hits[j].hasKey {
+				return hits[i].hasKey
+			}
+			return hits[i].key > hits[j].key
+		})
+	}
+	if len(hits) > s.topN {
+		hits = hits[:s.topN]
+	}
+
+	var events []notifier.Event
+	for _, h := range hits {
+		ev, err := s.event(h.row, tradeDate)
+		if err != nil {
+			return nil, s.rowErr(h.row, "event", err)
+		}
+		events = append(events, ev)
+	}
+	return events, nil
+}
+
+func (s *CustomExpr) rowErr(row expr.Map, what string, err error) error {
+	return fmt.Errorf("%s: %s (ts_code=%s): %w", s.name, what, expr.Format(row["ts_code"]), err)
+}
+
+// fetch returns the rows of d and its columns.
+func (s *CustomExpr) fetch(ctx context.Context, d CustomDataset, client *tushare.Client, md marketdata.Fusion, tradeDate string) ([]map[string]any, []string, error) {
+	if len(d.Marketdata) > 0 {
+		if md == nil {
+			return nil, nil, fmt.Errorf("marketdata disabled: enable config.marketdata and providers for %s", s.name)
+		}
+		var rows []map[string]any
+		for _, code := range d.Marketdata {
+			fs, err := md.FetchFusion(ctx, code)
+			if err != nil || fs.Confidence != marketdata.ConfidencePass {
+				continue
+			}
+			rows = append(rows, quoteRow(code, fs.Quote))
+		}
+		return rows, quoteColumns, nil
+	}
+
+	if client == nil {
+		return nil, nil, errors.New("tushare client required")
+	}
+	params := make(map[string]any, len(d.Params))
+	for k, v := range d.Params {
+		if str, ok := v.(string); ok {
+			v = strings.ReplaceAll(str, "{trade_date}", tradeDate)
+		}
+		params[k] = v
+	}
+	rows, err := client.Query(ctx, d.API, params, d.Fields)
+	if err != nil {
+		return nil, nil, err
+	}
+	cols := append([]string(nil), d.Fields...)
+	if len(cols) == 0 && len(rows) > 0 {
+		for k := range rows[0] {
+			cols = append(cols, k)
+		}
+		sort.Strings(cols)
+	}
+	return rows, cols, nil
+}
+
+var quoteColumns = []string{"ts_code", "last", "bid1", "ask1", "mid", "bid1_size", "ask1_size", "volume", "amount", "iopv", "prev_close"}
+
+// quoteRow flattens a fused quote. Prices a provider did not report (zero)
+// are null, so they cannot pass as real prices in expressions.
+func quoteRow(code string, q marketdata.Quote) map[string]any {
+	price := func(v float64) any {
+		if v <= 0 {
+			return nil
+		}
+		return v
+	}
+	mid := price(q.Last)
+	if q.Bid1 > 0 && q.Ask1 > 0 {
+		mid = (q.Bid1 + q.Ask1) / 2
+	}
+	return map[string]any{
+		"ts_code":    code,
+		"last":       price(q.Last),
+		"bid1":       price(q.Bid1),
+		"ask1":       price(q.Ask1),
+		"mid":        mid,
+		"bid1_size":  q.Bid1Size,
+		"ask1_size":  q.Ask1Size,
+		"volume":     q.Volume,
+		"amount":     q.Amount,
+		"iopv":       price(q.IOPV),
+		"prev_close": price(q.PrevClose),
+	}
+}
+
+// joinRows joins rows of dataset d onto base by ts_code; of several rows
+// with one code the first counts.
+func joinRows(base []expr.Map, d CustomDataset, rows []map[string]any, cols []string) []expr.Map {
+	idx := make(map[string]map[string]any, len(rows))
+	for _, r := range rows {
+		code := tushare.GetString(r, "ts_code")
+		if _, dup := idx[code]; code != "" && !dup {
+			idx[code] = r
+		}
+	}
+	var out []expr.Map
+	for _, row := range base {
+		code, _ := row["ts_code"].(string)
+		m, ok := idx[code]
+		if !ok && d.Join != "left" {
+			continue
+		}
+		for _, c := range cols {
+			v := m[c] // nil when unmatched
+			row[d.Name+"."+c] = v
+			if _, taken := row[c]; !taken {
+				row[c] = v
+			}
+		}
+		out = append(out, row)
+	}
+	return out
+}
+
+func (s *CustomExpr) event(row expr.Map, tradeDate string) (notifier.Event, error) {
+	sym, err := s.prog.symbol.Eval(row)
+	if err != nil {
+		return notifier.Event{}, err
+	}
+	symbol := ""
+	if sym != nil {
+		symbol = expr.Format(sym)
+	}
+
+	data := make(map[string]interface{}, len(s.prog.data))
+	var body strings.Builder
+	for _, d := range s.prog.data {
+		v, err := d.x.Eval(row)
+		if err != nil {
+			return notifier.Event{}, err
+		}
+		if v != nil {
+			data[d.name] = v
+		}
+		fmt.Fprintf(&body, "%s=%s\n", d.name, expr.Format(v))
+	}
+
+	title := s.name + " " + symbol
+	if s.prog.title != nil {
+		if title, err = s.prog.title.Render(row); err != nil {
+			return notifier.Event{}, err
+		}
+	}
+	text := body.String()
+	if s.prog.body != nil {
+		if text, err = s.prog.body.Render(row); err != nil {
+			return notifier.Event{}, err
+		}
+	}
+	return notifier.Event{
+		Source:    s.name,
+		TradeDate: tradeDate,
+		Market:    "CN-A",
+		Symbol:    symbol,
+		Title:     title,
+		Body:      text,
+		Tags: map[string]string{
+			"kind":     s.kind,
+			"tier":     s.tier,
+			"strategy": s.strategy,
+		},
+		Data: data,
+	}, nil
+}
@@ -0,0 +1,147 @@
+package signals
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"value-sniffer-radar/internal/config"
+	"value-sniffer-radar/internal/eventschema"
+	"value-sniffer-radar/internal/marketdata"
+	"value-sniffer-radar/internal/session"
+	"value-sniffer-radar/internal/tushare"
+)
+
+const customFundYAML = `
+- type: custom_expr
+  name: fund_gap
+  enabled: true
+  params:
+    kind: fund
+    datasets:
+      - api: fund_daily
+        name: daily
+        params: {trade_date: "{trade_date}"}
+        fields: [ts_code, close, amount]
+      - api: fund_nav
+        name: nav
+        params: {nav_date: "{trade_date}"}
+        fields: [ts_code, unit_nav]
+      - marketdata: ["510300.SH", "513100.SH"]
+        join: left
+    derive:
+      - name: premium_pct
+        expr: (daily.close - unit_nav) / unit_nav * 100
+    filter: abs(premium_pct) > 3 && amount > 1e7
+    sort: abs(premium_pct)
+    event:
+      title: "Fund gap {premium_pct:%.2f}% ({ts_code})"
+      data:
+        premium_pct: premium_pct
+        expected_edge_pct: abs(premium_pct) - 3
+        side: 'premium_pct < 0 ? "discount" : "premium"'
+        iopv: md.iopv
+`
+
+func TestCustomExprJoinsFiltersAndMapsEvents(t *testing.T) {
+	var queried []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			APIName string         `json:"api_name"`
+			Params  map[string]any `json:"params"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		td, _ := req.Params["trade_date"].(string)
+		nd, _ := req.Params["nav_date"].(string)
+		queried = append(queried, req.APIName+":"+td+nd)
+		data := map[string]any{"fields": []string{"ts_code", "close", "amount"}, "items": [][]any{
+			{"510300.SH", 1.05, 9e8},
+			{"513100.SH", 0.90, 5e8},
+			{"159915.SZ", 2.00, 1e6},  // too small
+			{"511990.SH", 100.0, 9e8}, // no NAV: dropped by the inner join
+		}}
+		if req.APIName == "fund_nav" {
+			data = map[string]any{"fields": []string{"ts_code", "unit_nav"}, "items": [][]any{
+				{"510300.SH", 1.00}, {"513100.SH", 1.00}, {"159915.SZ", 1.00},
+			}}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"code": 0, "data": data})
+	}))
+	defer srv.Close()
+
+	c := parseEntries(t, customFundYAML)[0]
+	env := Env{MarketdataEnabled: true}
+	if !NeedTushare([]config.SignalConfig{c}, env) {
+		t.Fatalf("api datasets need tushare")
+	}
+	sig, err := Build(c, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	md := fakeFusion{snaps: map[string]marketdata.FusionSnapshot{
+		"510300.SH": {Symbol: "510300.SH", Quote: marketdata.Quote{Last: 1.05, IOPV: 1.01}, Confidence: marketdata.ConfidencePass},
+	}}
+	client := tushare.New(tushare.Options{BaseURL: srv.URL, Token: "t"})
+	evs, err := sig.Evaluate(context.Background(), client, "20260106", md, session.Info{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(queried, ",") != "fund_daily:20260106,fund_nav:20260106" {
+		t.Fatalf("queries=%v", queried)
+	}
+	// Sorted by |premium|: 513100 (-10%) before 510300 (+5%).
+	if len(evs) != 2 || evs[0].Symbol != "513100.SH" || evs[1].Symbol != "510300.SH" {
+		t.Fatalf("events=%+v", evs)
+	}
+	d := evs[0].Data
+	if d["side"] != "discount" || d["expected_edge_pct"].(float64) < 6.99 || evs[0].Tags["kind"] != "fund" || evs[0].Tags["tier"] != "observe" {
+		t.Fatalf("event=%+v", evs[0])
+	}
+	if _, ok := d["iopv"]; ok {
+		t.Fatalf("left join without a quote must leave iopv out: %v", d)
+	}
+	if evs[1].Data["iopv"] != 1.01 || evs[1].Title != "Fund gap 5.00% (510300.SH)" {
+		t.Fatalf("event=%+v", evs[1])
+	}
+	for _, ev := range evs {
+		ev.Stamp(time.Date(2026, 1, 6, 10, 0, 0, 0, session.Location))
+		if err := eventschema.Validate(ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCustomExprParamErrors(t *testing.T) {
+	valid := map[string]string{
+		"kind":     "repo",
+		"datasets": "[{api: repo_daily}]",
+		"filter":   "'true'",
+		"event":    "{data: {expected_edge_pct: '1'}}",
+	}
+	cases := []struct{ key, val, want string }{
+		{"kind", "fx", "kind must be"},
+		{"filter", "'amount >'", "filter: col 9"},
+		{"event", "{data: {side: '1'}}", "event.data.expected_edge_pct required"},
+		{"event", "{data: {expected_edge_pct: '1'}, title: '{x'}", "event.title"},
+		{"datasets", "[{api: x, marketdata: [510300.SH]}]", "set one of api or marketdata"},
+		{"datasets", "[{api: a}, {api: b, join: outer}]", "join must be inner or left"},
+	}
+	for _, tc := range cases {
+		yml := "- type: custom_expr\n  name: bad\n  params:\n"
+		for _, k := range []string{"kind", "datasets", "filter", "event"} {
+			v := valid[k]
+			if k == tc.key {
+				v = tc.val
+			}
+			yml += "    " + k + ": " + v + "\n"
+		}
+		_, err := Build(parseEntries(t, yml)[0], Env{})
+		if err == nil || !strings.Contains(err.Error(), tc.want) || !strings.Contains(err.Error(), "signal bad (custom_expr)") {
+			t.Fatalf("%s=%s: err=%v want %q", tc.key, tc.val, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package signals
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"value-sniffer-radar/internal/config"
+	"value-sniffer-radar/internal/marketdata"
+	"value-sniffer-radar/internal/notifier"
+	"value-sniffer-radar/internal/session"
+	"value-sniffer-radar/internal/tushare"
+)
+
+// ETFIOPVRealtimeParams configure etf_iopv_realtime: ETFs trading away from
+// their IOPV.
+type ETFIOPVRealtimeParams struct {
+	ETFCodes       []string `yaml:"etf_codes"`        // e.g. ["510300.SH","159915.SZ"]
+	PremiumPctLow  float64  `yaml:"premium_pct_low"`  // default -1 when both are unset
+	PremiumPctHigh float64  `yaml:"premium_pct_high"` // default 1 when both are unset
+	TopN           int      `yaml:"top_n"`            // default 5
+	ConfirmK       int      `yaml:"confirm_k"`
+	Execution      `yaml:",inline"`
+	Window         `yaml:",inline"`
+}
+
+func (p *ETFIOPVRealtimeParams) Normalize(env Env) error {
+	if len(p.ETFCodes) == 0 {
+		return errors.New("etf_codes required")
+	}
+	if err := p.Execution.normalize(env); err != nil {
+		return err
+	}
+	if err := checkConfirmK(p.ConfirmK); err != nil {
+		return err
+	}
+	return checkPremiumRange(p.PremiumPctLow, p.PremiumPctHigh, p.TopN)
+}
+
+func init() {
+	Register(Type{
+		Name:   "etf_iopv_realtime",
+		Params: func() any { return &ETFIOPVRealtimeParams{} },
+		New: func(c config.SignalConfig, p any) (Signal, error) {
+			return NewETFIOPVRealtime(c, *p.(*ETFIOPVRealtimeParams)), nil
+		},
+	})
+}
+
+// ETFIOPVRealtime alerts on intraday ETF premium/discount versus the exchange-published
+// IOPV, using multi-source marketdata fusion. Like cn_repo_realtime it only emits when
+// fusion confidence passes, and optionally after confirm_k consecutive breaches.
+type ETFIOPVRealtime struct {
+	name        string
+	tier        string
+	minInterval time.Duration
+
+	etfCodes    []string
+	premiumLow  float64
+	premiumHigh float64
+	topN        int
+	notional    notionals
+
+	windowStart string
+	windowEnd   string
+
+	confirmK int
+	streaks  map[string]int
+}
+
+func NewETFIOPVRealtime(c config.SignalConfig, p ETFIOPVRealtimeParams) *ETFIOPVRealtime {
+	name := c.Name
+	if name == "" {
+		name = "etf_iopv_realtime"
+	}
+	tier := c.Tier
+	if tier == "" {
+		tier = "action"
+	}
+	topN := p.TopN
+	if topN <= 0 {
+		topN = 5
+	}
+	low, high := p.PremiumPctLow, p.PremiumPctHigh
+	if low == 0 && high == 0 {
+		low, high = -1.0, 1.0
+	}
+	minInt := time.Duration(c.MinIntervalSeconds) * time.Second
+	if minInt <= 0 {
+		minInt = 3 * time.Second
+	}
+	confirmK := p.ConfirmK
+	if confirmK <= 0 {
+		confirmK = 1
+	}
+	return &ETFIOPVRealtime{
+		name:        name,
+		tier:        tier,
+		minInterval: minInt,
+		etfCodes:    normalizeETFCodes(p.ETFCodes),
+		premiumLow:  low,
+		premiumHigh: high,
+		topN:        topN,
+		notional:    newNotionals(p.Execution),
+		windowStart: strings.TrimSpace(p.WindowStart),
+		windowEnd:   strings.TrimSpace(p.WindowEnd),
+		confirmK:    confirmK,
+		streaks:     map[string]int{},
+	}
+}
+
+func (s *ETFIOPVRealtime) Name() string { return s.name }
+
+func (s *ETFIOPVRealtime) MinInterval() time.Duration { return s.minInterval }
+
+type etfRTAlert struct {
+	fs         marketdata.FusionSnapshot
+	premiumPct float64
+}
+
+func (s *ETFIOPVRealtime) Evaluate(ctx context.Context, _ *tushare.Client, tradeDate string, md marketdata.Fusion, sess session.Info) ([]notifier.Event, error) {
+	if md == nil {
+		return nil, fmt.Errorf("marketdata disabled: enable config.marketdata and providers for %s", s.name)
+	}
+	if len(s.etfCodes) == 0 {
+		return nil, fmt.Errorf("%s: etf_codes is empty", s.name)
+	}
+	if !withinWindow(sess.Now, s.windowStart, s.windowEnd) {
+		return nil, nil
+	}
+
+	var alerts []etfRTAlert
+	for _, code := range s.etfCodes {
+		fs, err := md.FetchFusion(ctx, code)
+		if err != nil {
+			continue
+		}
+
+		// Both price and IOPV must have passed fusion: fusion leaves IOPV zero
+		// unless required_sources inliers reported it, since providers without
+		// IOPV (iopv_field/iopv_index unset) vouch only for the price.
+		pass := fs.Confidence == marketdata.ConfidencePass && fs.Quote.IOPV > 0
+		premium := 0.0
+		if pass {
+			premium = (fs.Quote.Last - fs.Quote.IOPV) / fs.Quote.IOPV * 100.0
+		}
+		thr := premium <= s.premiumLow || premium >= s.premiumHigh
+		if pass && thr {
+			s.streaks[code]++
+		} else {
+			s.streaks[code] = 0
+		}
+		if !pass || !thr || s.streaks[code] < s.confirmK {
+			continue
+		}
+		alerts = append(alerts, etfRTAlert{fs: fs, premiumPct: premium})
+	}
+	if len(alerts) == 0 {
+		return nil, nil
+	}
+
+	sort.Slice(alerts, func(i, j int) bool { return math.Abs(alerts[i].premiumPct) > math.Abs(alerts[j].premiumPct) })
+	if len(alerts) > s.topN {
+		alerts = alerts[:s.topN]
+	}
+
+	events := make([]notifier.Event, 0, len(alerts))
+	for _, a := range alerts {
+		q := a.fs.Quote
+		thr := s.premiumLow
+		side := "discount"
+		if a.premiumPct >= s.premiumHigh {
+			thr = s.premiumHigh
+			side = "premium"
+		}
+		body := fmt.Sprintf("price=%.4f\niopv=%.4f\npremium=%.3f%%\nconfidence=%s\nreason=%s\n", q.Last, q.IOPV, a.premiumPct, a.fs.Confidence, a.fs.Reason)
+		for _, pr := range a.fs.Providers {
+			if pr.Error != "" {
+				body += fmt.Sprintf("- %s: error=%s\n", pr.Provider, pr.Error)
+				continue
+			}
+			body += fmt.Sprintf("- %s: price=%.4f iopv=%.4f inlier=%v outlier=%v\n", pr.Provider, pr.Snapshot.Quote.Last, pr.Snapshot.Quote.IOPV, pr.Inlier, pr.Outlier)
+		}
+
+		data := map[string]any{
+			"premium_pct":           a.premiumPct,
+			"threshold_premium_pct": thr,
+			"expected_edge_pct":     math.Abs(a.premiumPct - thr),
+			"side":                  side,
+			"price":                 q.Last,
+			"iopv":                  q.IOPV,
+			"confidence":            string(a.fs.Confidence),
+			"reason":                a.fs.Reason,
+			"providers":             a.fs.Providers,
+		}
+		// Premium: sell the ETF (create/redeem against it); discount: buy it.
+		tradeSide := marketdata.Buy
+		if side == "premium" {
+			tradeSide = marketdata.Sell
+		}
+		fillExecution(data, marketdata.EstimateExecution(a.fs, tradeSide, s.notional.For(a.fs.Symbol)))
+
+		events = append(events, notifier.Event{
+			Source:    s.name,
+			TradeDate: tradeDate,
+			Market:    "CN-A",
+			Symbol:    a.fs.Symbol,
+			Title:     fmt.Sprintf("ETF IOPV %s %.2f%% (%s)", side, a.premiumPct, a.fs.Symbol),
+			Body:      body,
+			Tags: map[string]string{
+				"kind":       "fund",
+				"strategy":   "iopv_premium",
+				"tier":       s.tier,
+				"confidence": string(a.fs.Confidence),
+			},
+			Data: data,
+		})
+	}
+	return events, nil
+}
+
+// normalizeETFCodes accepts "510300.SH", "SH510300" or bare "510300"
+// (5xxxxx -> SH, 15xxxx/16xxxx -> SZ).
+func normalizeETFCodes(in []string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, raw := range in {
+		u := strings.ToUpper(strings.TrimSpace(raw))
+		if u == "" {
+			continue
+		}
+		if strings.HasPrefix(u, "SH") && len(u) > 2 {
+			u = u[2:] + ".SH"
+		} else if strings.HasPrefix(u, "SZ") && len(u) > 2 {
+			u = u[2:] + ".SZ"
+		}
+		if !strings.Contains(u, ".") && len(u) == 6 {
+			if strings.HasPrefix(u, "5") {
+				u = u + ".SH"
+			} else if strings.HasPrefix(u, "15") || strings.HasPrefix(u, "16") {
+				u = u + ".SZ"
+			}
+		}
+		if !strings.Contains(u, ".") {
+			continue
+		}
+		if !seen[u] {
+			seen[u] = true
+			out = append(out, u)
+		}
+	}
+	return out
+}
@@ -0,0 +1,57 @@
+package signals
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"value-sniffer-radar/internal/config"
+	"value-sniffer-radar/internal/marketdata"
+	"value-sniffer-radar/internal/session"
+)
+
+type fakeFusion struct {
+	snaps map[string]marketdata.FusionSnapshot
+}
+
+func (f fakeFusion) FetchFusion(_ context.Context, symbol string) (marketdata.FusionSnapshot, error) {
+	return f.snaps[symbol], nil
+}
+
+func TestETFIOPVRealtimeConfirmK(t *testing.T) {
+	s := NewETFIOPVRealtime(config.SignalConfig{Name: "etf"}, ETFIOPVRealtimeParams{
+		ETFCodes: []string{"510300", "159915"}, PremiumPctLow: -0.5, PremiumPctHigh: 0.5, ConfirmK: 2,
+	})
+	md := fakeFusion{snaps: map[string]marketdata.FusionSnapshot{
+		"510300.SH": {Symbol: "510300.SH", Quote: marketdata.Quote{Last: 4.03, IOPV: 4.0}, Confidence: marketdata.ConfidencePass},
+		"159915.SZ": {Symbol: "159915.SZ", Quote: marketdata.Quote{Last: 2.0, IOPV: 2.0}, Confidence: marketdata.ConfidencePass},
+	}}
+
+	evs, err := s.Evaluate(context.Background(), nil, "20260106", md, session.Info{})
+	if err != nil || len(evs) != 0 {
+		t.Fatalf("first breach must wait for confirm_k: events=%d err=%v", len(evs), err)
+	}
+	evs, err = s.Evaluate(context.Background(), nil, "20260106", md, session.Info{})
+	if err != nil || len(evs) != 1 {
+		t.Fatalf("events=%d err=%v", len(evs), err)
+	}
+	if evs[0].Symbol != "510300.SH" || evs[0].Data["side"] != "premium" {
+		t.Fatalf("event=%+v", evs[0])
+	}
+
+	// A failed consensus resets the streak.
+	snap := md.snaps["510300.SH"]
+	snap.Confidence = marketdata.ConfidenceFail
+	md.snaps["510300.SH"] = snap
+	if evs, _ := s.Evaluate(context.Background(), nil, "20260106", md, session.Info{}); len(evs) != 0 {
+		t.Fatalf("fail confidence must not alert: %d", len(evs))
+	}
+}
+
+func TestNormalizeETFCodes(t *testing.T) {
+	got := normalizeETFCodes([]string{"510300", "SZ159915", "588000.sh", "160119", "510300.SH", " "})
+	want := []string{"510300.SH", "159915.SZ", "588000.SH", "160119.SZ"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got=%v want=%v", got, want)
+	}
+}
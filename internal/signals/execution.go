@@ -0,0 +1,61 @@
+package signals
+
+import (
+	"strings"
+
+	"value-sniffer-radar/internal/costs"
+	"value-sniffer-radar/internal/marketdata"
+)
+
+// notionals sizes the hypothetical orders execution costs are estimated for.
+type notionals struct {
+	def      float64
+	bySymbol map[string]float64
+}
+
+func newNotionals(x Execution) notionals {
+	def := x.TradeNotional
+	if def <= 0 {
+		def = costs.DefaultTradeNotional
+	}
+	return notionals{def: def, bySymbol: x.NotionalBySymbol}
+}
+
+func (n notionals) For(symbol string) float64 {
+	if v, ok := n.bySymbol[strings.ToUpper(symbol)]; ok && v > 0 {
+		return v
+	}
+	return n.def
+}
+
+// fillExecution writes the execution inputs of the engine's net edge policy
+// from one or more legs: the legs themselves (priced by the engine's fee
+// schedule) and, when every leg has a book, spread_pct, slippage_pct and
+// liquidity_ok. Legs add up; liquidity is ok only if every leg is. Without a
+// book the engine falls back to its default spread/slippage.
+func fillExecution(data map[string]interface{}, legs ...marketdata.Execution) {
+	if len(legs) == 0 {
+		return
+	}
+	orders := make([]costs.Leg, 0, len(legs))
+	var spread, slip float64
+	ok, hasBook := true, true
+	for _, ex := range legs {
+		orders = append(orders, costs.Leg{Symbol: ex.Symbol, Side: ex.Side, Notional: ex.Notional})
+		hasBook = hasBook && ex.HasBook
+		spread += ex.SpreadPct
+		slip += ex.SlippagePct
+		ok = ok && ex.LiquidityOK
+	}
+	data["legs"] = orders
+	data["trade_notional"] = legs[0].Notional
+	if !hasBook {
+		return
+	}
+	data["spread_pct"] = spread
+	data["slippage_pct"] = slip
+	data["liquidity_ok"] = ok
+	if len(legs) == 1 {
+		data["depth_notional"] = legs[0].DepthNotional
+	}
+}
@@ -2,7 +2,9 @@ package signals
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"math"
 	"sort"
 	"time"
@@ -10,9 +12,40 @@ import (
 	"value-sniffer-radar/internal/config"
 	"value-sniffer-radar/internal/marketdata"
 	"value-sniffer-radar/internal/notifier"
+	"value-sniffer-radar/internal/session"
 	"value-sniffer-radar/internal/tushare"
 )
 
+// FundPremiumParams configure fund_premium: listed funds trading away from
+// their latest NAV.
+type FundPremiumParams struct {
+	Market          string  `yaml:"market"`             // fund_basic market, default E (exchange-listed)
+	PickTopByAmount int     `yaml:"pick_top_by_amount"` // default 50
+	MaxNavLagDays   int     `yaml:"max_nav_lag_days"`   // NAV older than trade_date by more days is stale (default 0)
+	MinAmount       float64 `yaml:"min_amount"`
+	PremiumPctLow   float64 `yaml:"premium_pct_low"`
+	PremiumPctHigh  float64 `yaml:"premium_pct_high"`
+	TopN            int     `yaml:"top_n"` // default 20
+}
+
+func (p *FundPremiumParams) Normalize(Env) error {
+	if p.PickTopByAmount < 0 || p.MaxNavLagDays < 0 {
+		return errors.New("pick_top_by_amount and max_nav_lag_days must be >= 0")
+	}
+	return checkPremiumRange(p.PremiumPctLow, p.PremiumPctHigh, p.TopN)
+}
+
+func init() {
+	Register(Type{
+		Name:   "fund_premium",
+		Params: func() any { return &FundPremiumParams{} },
+		New: func(c config.SignalConfig, p any) (Signal, error) {
+			return NewFundPremium(c, *p.(*FundPremiumParams)), nil
+		},
+		NeedsTushare: always,
+	})
+}
+
 type FundPremium struct {
 	name            string
 	tier            string
@@ -23,18 +56,19 @@ type FundPremium struct {
 	premiumLow      float64
 	premiumHigh     float64
 	topN            int
+	maxNavLagDays   int
 }
 
-func NewFundPremium(c config.SignalConfig) *FundPremium {
-	topN := c.TopN
+func NewFundPremium(c config.SignalConfig, p FundPremiumParams) *FundPremium {
+	topN := p.TopN
 	if topN <= 0 {
 		topN = 20
 	}
-	market := c.Market
+	market := p.Market
 	if market == "" {
 		market = "E"
 	}
-	pick := c.PickTopByAmount
+	pick := p.PickTopByAmount
 	if pick <= 0 {
 		pick = 50
 	}
@@ -53,10 +87,11 @@ func NewFundPremium(c config.SignalConfig) *FundPremium {
 		minInterval:     minInt,
 		market:          market,
 		pickTopByAmount: pick,
-		minAmount:       c.MinAmount,
-		premiumLow:      c.PremiumPctLow,
-		premiumHigh:     c.PremiumPctHigh,
+		minAmount:       p.MinAmount,
+		premiumLow:      p.PremiumPctLow,
+		premiumHigh:     p.PremiumPctHigh,
 		topN:            topN,
+		maxNavLagDays:   p.MaxNavLagDays,
 	}
 }
 
@@ -73,12 +108,18 @@ type fundRow struct {
 type fundAlert struct {
 	tsCode     string
 	close      float64
-	nav        float64
+	nav        navPoint
 	premiumPct float64
 	amount     float64
 }
 
-func (s *FundPremium) Evaluate(ctx context.Context, client *tushare.Client, tradeDate string, _ marketdata.Fusion) ([]notifier.Event, error) {
+type navPoint struct {
+	value   float64
+	date    string // YYYYMMDD
+	lagDays int    // calendar days between date and trade_date
+}
+
+func (s *FundPremium) Evaluate(ctx context.Context, client *tushare.Client, tradeDate string, _ marketdata.Fusion, sess session.Info) ([]notifier.Event, error) {
 	// Step 1: pick top funds by amount to limit fund_nav calls.
 	params := map[string]any{
 		"trade_date": tradeDate,
@@ -110,28 +151,20 @@ func (s *FundPremium) Evaluate(ctx context.Context, client *tushare.Client, trad
 		funds = funds[:s.pickTopByAmount]
 	}
 
-	// Step 2: per fund fetch NAV.
+	// Step 2: NAVs for the trade date and the previous trade day in one call
+	// each; per-fund fallback only for what both miss.
+	navs, err := s.fetchNAVs(ctx, client, tradeDate, sess.Calendar, funds)
+	if err != nil {
+		return nil, err
+	}
+
 	var alerts []fundAlert
 	for _, f := range funds {
-		navRows, err := client.Query(ctx, "fund_nav", map[string]any{
-			"ts_code":    f.tsCode,
-			"start_date": tradeDate,
-			"end_date":   tradeDate,
-		}, []string{"ts_code", "nav_date", "unit_nav"})
-		if err != nil {
+		nav, ok := navs[f.tsCode]
+		if !ok {
 			continue
 		}
-		nav := 0.0
-		for _, nr := range navRows {
-			nav = tushare.GetFloat(nr, "unit_nav")
-			if nav > 0 {
-				break
-			}
-		}
-		if nav <= 0 {
-			continue
-		}
-		premiumPct := (f.close - nav) / nav * 100.0
+		premiumPct := (f.close - nav.value) / nav.value * 100.0
 		if premiumPct <= s.premiumLow || premiumPct >= s.premiumHigh {
 			alerts = append(alerts, fundAlert{
 				tsCode:     f.tsCode,
@@ -153,7 +186,14 @@ func (s *FundPremium) Evaluate(ctx context.Context, client *tushare.Client, trad
 
 	events := make([]notifier.Event, 0, len(alerts))
 	for _, a := range alerts {
-		body := fmt.Sprintf("close=%.4f\nnav=%.4f\npremium=%.2f%%\namount=%.0f\n", a.close, a.nav, a.premiumPct, a.amount)
+		stale := a.nav.lagDays > s.maxNavLagDays
+		body := fmt.Sprintf("close=%.4f\nnav=%.4f (nav_date=%s)\npremium=%.2f%%\namount=%.0f\n", a.close, a.nav.value, a.nav.date, a.premiumPct, a.amount)
+		tier := s.tier
+		if stale {
+			// A lagging NAV (QDII/LOF) makes the premium an artefact of timing, not an edge.
+			body += fmt.Sprintf("nav_stale=true (lag %d days)\n", a.nav.lagDays)
+			tier = "observe"
+		}
 		thr := s.premiumLow
 		side := "discount"
 		if a.premiumPct >= s.premiumHigh {
@@ -170,7 +210,7 @@ func (s *FundPremium) Evaluate(ctx context.Context, client *tushare.Client, trad
 			Body:      body,
 			Tags: map[string]string{
 				"kind": "fund",
-				"tier": s.tier,
+				"tier": tier,
 			},
 			Data: map[string]interface{}{
 				"premium_pct":           a.premiumPct,
@@ -178,10 +218,121 @@ func (s *FundPremium) Evaluate(ctx context.Context, client *tushare.Client, trad
 				"expected_edge_pct":     expected,
 				"side":                  side,
 				"close":                 a.close,
-				"nav":                   a.nav,
+				"nav":                   a.nav.value,
+				"nav_date":              a.nav.date,
+				"nav_lag_days":          a.nav.lagDays,
+				"nav_stale":             stale,
 				"amount":                a.amount,
 			},
 		})
 	}
 	return events, nil
 }
+
+// navLookbackDays bounds the per-fund fallback window; QDII NAVs can lag a
+// few days around holidays.
+const navLookbackDays = 10
+
+// fetchNAVs returns the latest NAV on or before tradeDate for each fund. Bulk
+// fund_nav(nav_date=...) calls for the trade date and then the previous trade
+// day cover most funds (the T NAV is often unpublished intraday); funds missing
+// from both (QDII lag) fall back to a per-fund lookback query ending on the
+// previous trade day, so no per-fund range ever spans the still-changing
+// trade date.
+func (s *FundPremium) fetchNAVs(ctx context.Context, client *tushare.Client, tradeDate string, days session.TradingDays, funds []fundRow) (map[string]navPoint, error) {
+	td, err := time.Parse("20060102", tradeDate)
+	if err != nil {
+		return nil, fmt.Errorf("fund_premium: bad trade_date %q", tradeDate)
+	}
+	prev := prevTradeDay(td, days).Format("20060102")
+	fields := []string{"ts_code", "nav_date", "unit_nav"}
+	navs := make(map[string]navPoint, len(funds))
+	missing := func() map[string]bool {
+		m := make(map[string]bool, len(funds))
+		for _, f := range funds {
+			if _, ok := navs[f.tsCode]; !ok {
+				m[f.tsCode] = true
+			}
+		}
+		return m
+	}
+
+	for _, navDate := range []string{tradeDate, prev} {
+		want := missing()
+		if len(want) == 0 {
+			return navs, nil
+		}
+		params := map[string]any{"nav_date": navDate}
+		if s.market != "" {
+			params["market"] = s.market
+		}
+		rows, err := client.Query(ctx, "fund_nav", params, fields)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rows {
+			if code := tushare.GetString(r, "ts_code"); want[code] {
+				keepLatestNAV(navs, code, r, td)
+			}
+		}
+	}
+
+	var failed int
+	var lastErr error
+	for _, f := range funds {
+		if _, ok := navs[f.tsCode]; ok {
+			continue
+		}
+		rows, err := client.Query(ctx, "fund_nav", map[string]any{
+			"ts_code":    f.tsCode,
+			"start_date": td.AddDate(0, 0, -navLookbackDays).Format("20060102"),
+			"end_date":   prev,
+		}, fields)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			failed++
+			lastErr = err
+			continue
+		}
+		for _, r := range rows {
+			keepLatestNAV(navs, f.tsCode, r, td)
+		}
+	}
+	if failed > 0 {
+		log.Printf("signal %s fund_nav fallback failed=%d last_err=%v", s.name, failed, lastErr)
+	}
+	return navs, nil
+}
+
+// prevTradeDay is the last open exchange date before td; a nil days means
+// Monday-Friday.
+func prevTradeDay(td time.Time, days session.TradingDays) time.Time {
+	d := time.Date(td.Year(), td.Month(), td.Day(), 12, 0, 0, 0, session.Location)
+	// Walk bounded: the longest exchange holiday is well under a month.
+	for i := 0; i < 30; i++ {
+		d = d.AddDate(0, 0, -1)
+		if days != nil {
+			if days.IsTradingDay(d) {
+				break
+			}
+		} else if wd := d.Weekday(); wd != time.Saturday && wd != time.Sunday {
+			break
+		}
+	}
+	return d
+}
+
+func keepLatestNAV(navs map[string]navPoint, code string, r map[string]interface{}, td time.Time) {
+	nav := tushare.GetFloat(r, "unit_nav")
+	date := tushare.GetString(r, "nav_date")
+	d, err := time.Parse("20060102", date)
+	if nav <= 0 || err != nil || d.After(td) {
+		return
+	}
+	if cur, ok := navs[code]; ok && cur.date >= date {
+		return
+	}
+	navs[code] = navPoint{value: nav, date: date, lagDays: int(td.Sub(d).Hours() / 24)}
+}
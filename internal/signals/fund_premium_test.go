@@ -0,0 +1,119 @@
+package signals
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"value-sniffer-radar/internal/config"
+	"value-sniffer-radar/internal/session"
+	"value-sniffer-radar/internal/tushare"
+)
+
+func TestFundPremiumBulkNAVWithStaleFallback(t *testing.T) {
+	var navCalls []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			APIName string         `json:"api_name"`
+			Params  map[string]any `json:"params"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		data := map[string]any{"fields": []string{"ts_code", "nav_date", "unit_nav", "close", "amount"}, "items": [][]any{}}
+		switch req.APIName {
+		case "fund_daily":
+			data["items"] = [][]any{
+				{"510300.SH", nil, nil, 1.05, 9e8},
+				{"513100.SH", nil, nil, 1.10, 5e8},
+			}
+		case "fund_nav":
+			navCalls = append(navCalls, req.Params)
+			if req.Params["nav_date"] == "20260106" {
+				data["items"] = [][]any{{"510300.SH", "20260106", 1.00, nil, nil}, {"999999.SH", "20260106", 1.0, nil, nil}}
+			} else if req.Params["ts_code"] == "513100.SH" {
+				data["items"] = [][]any{{"513100.SH", "20260102", 0.90, nil, nil}, {"513100.SH", "20260105", 1.00, nil, nil}}
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"code": 0, "data": data})
+	}))
+	defer srv.Close()
+
+	s := NewFundPremium(config.SignalConfig{Name: "fp"}, FundPremiumParams{PremiumPctLow: -1, PremiumPctHigh: 3})
+	client := tushare.New(tushare.Options{BaseURL: srv.URL, Token: "t"})
+	evs, err := s.Evaluate(context.Background(), client, "20260106", nil, session.Info{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(navCalls) != 3 {
+		t.Fatalf("fund_nav calls=%d want=3 (bulk T, bulk T-1, one fallback): %v", len(navCalls), navCalls)
+	}
+	if navCalls[1]["nav_date"] != "20260105" || navCalls[2]["end_date"] != "20260105" {
+		t.Fatalf("T-1 bulk / fallback window should end on the previous trade day: %v", navCalls)
+	}
+	if len(evs) != 2 {
+		t.Fatalf("events=%d want=2", len(evs))
+	}
+	got := map[string]map[string]interface{}{}
+	tiers := map[string]string{}
+	for _, ev := range evs {
+		got[ev.Symbol] = ev.Data
+		tiers[ev.Symbol] = ev.Tags["tier"]
+	}
+	if d := got["510300.SH"]; d["nav_stale"] != false || d["nav_date"] != "20260106" || tiers["510300.SH"] != "action" {
+		t.Fatalf("fresh nav: data=%v tier=%s", d, tiers["510300.SH"])
+	}
+	if d := got["513100.SH"]; d["nav_stale"] != true || d["nav_date"] != "20260105" || d["nav_lag_days"] != 1 || tiers["513100.SH"] != "observe" {
+		t.Fatalf("stale nav: data=%v tier=%s", d, tiers["513100.SH"])
+	}
+}
+
+func TestFundPremiumPartialBulkNAVUsesPreviousTradeDay(t *testing.T) {
+	var navCalls []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			APIName string         `json:"api_name"`
+			Params  map[string]any `json:"params"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		data := map[string]any{"fields": []string{"ts_code", "nav_date", "unit_nav", "close", "amount"}, "items": [][]any{}}
+		switch req.APIName {
+		case "fund_daily":
+			data["items"] = [][]any{
+				{"510300.SH", nil, nil, 1.05, 9e8},
+				{"513100.SH", nil, nil, 1.10, 5e8},
+			}
+		case "fund_nav":
+			navCalls = append(navCalls, req.Params)
+			// Monday: the bulk T call only has 510300 so far; Friday's has both.
+			switch req.Params["nav_date"] {
+			case "20260105":
+				data["items"] = [][]any{{"510300.SH", "20260105", 1.00, nil, nil}}
+			case "20260102":
+				data["items"] = [][]any{{"510300.SH", "20260102", 0.99, nil, nil}, {"513100.SH", "20260102", 1.00, nil, nil}}
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"code": 0, "data": data})
+	}))
+	defer srv.Close()
+
+	s := NewFundPremium(config.SignalConfig{Name: "fp"}, FundPremiumParams{PremiumPctLow: -1, PremiumPctHigh: 3, MaxNavLagDays: 3})
+	client := tushare.New(tushare.Options{BaseURL: srv.URL, Token: "t"})
+	evs, err := s.Evaluate(context.Background(), client, "20260105", nil, session.Info{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(navCalls) != 2 || navCalls[0]["nav_date"] != "20260105" || navCalls[1]["nav_date"] != "20260102" {
+		t.Fatalf("fund_nav calls=%v want bulk T then bulk previous trade day, no per-fund calls", navCalls)
+	}
+	got := map[string]map[string]interface{}{}
+	for _, ev := range evs {
+		got[ev.Symbol] = ev.Data
+	}
+	if d := got["510300.SH"]; d["nav_date"] != "20260105" {
+		t.Fatalf("510300 should keep the T NAV: %v", d)
+	}
+	if d := got["513100.SH"]; d["nav_date"] != "20260102" || d["nav_lag_days"] != 3 || d["nav_stale"] != false {
+		t.Fatalf("513100 should use Friday's NAV: %v", d)
+	}
+}
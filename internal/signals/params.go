@@ -0,0 +1,160 @@
+package signals
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+
+	"value-sniffer-radar/internal/config"
+	"value-sniffer-radar/internal/repo"
+)
+
+// Env is what signal params may depend on besides their own entry.
+type Env struct {
+	BaseDir           string             // config file directory; relative paths resolve against it
+	Costs             config.CostsConfig // order sizing defaults for Execution
+	MarketdataEnabled bool
+
+	// StateDir, when set, holds the files signals keep state in (repo
+	// history) instead of their configured directories. Backtests use it to
+	// leave live state alone.
+	StateDir string
+
+	// Histories, when set, is the engine's set of repo rate histories;
+	// percentile repo signals open theirs from it. Unset, they fall back to
+	// min_yield_pct.
+	Histories *repo.Histories
+}
+
+// EnvOf is the Env of a loaded config.
+func EnvOf(cfg *config.Config) Env {
+	return Env{BaseDir: cfg.Dir, Costs: cfg.Engine.Costs, MarketdataEnabled: cfg.Marketdata.Enabled}
+}
+
+func (env Env) path(p, def string) string {
+	if p == "" {
+		p = def
+	}
+	if env.StateDir != "" {
+		return filepath.Join(env.StateDir, filepath.Base(p))
+	}
+	if !filepath.IsAbs(p) && env.BaseDir != "" {
+		p = filepath.Join(env.BaseDir, p)
+	}
+	return p
+}
+
+// Normalizer is implemented by params that check their values or fill in
+// defaults from Env. Errors name the offending key.
+type Normalizer interface {
+	Normalize(env Env) error
+}
+
+// Window limits evaluation to a time of day (exchange time), "HH:MM" or "HHMM".
+type Window struct {
+	WindowStart string `yaml:"window_start"`
+	WindowEnd   string `yaml:"window_end"`
+}
+
+// Execution sizes the orders realtime signals estimate spread, slippage and
+// liquidity for.
+type Execution struct {
+	TradeNotional    float64            `yaml:"trade_notional"`     // CNY; default engine.costs.trade_notional
+	NotionalBySymbol map[string]float64 `yaml:"notional_by_symbol"` // merged over engine.costs.notional_by_symbol
+}
+
+func (x *Execution) normalize(env Env) error {
+	if x.TradeNotional < 0 {
+		return errors.New("trade_notional must be >= 0")
+	}
+	if x.TradeNotional == 0 {
+		x.TradeNotional = env.Costs.TradeNotional
+	}
+	merged := make(map[string]float64, len(env.Costs.NotionalBySymbol)+len(x.NotionalBySymbol))
+	for k, v := range env.Costs.NotionalBySymbol {
+		merged[k] = v
+	}
+	for k, v := range x.NotionalBySymbol {
+		if v < 0 {
+			return errors.New("notional_by_symbol values must be >= 0")
+		}
+		merged[strings.ToUpper(strings.TrimSpace(k))] = v
+	}
+	x.NotionalBySymbol = merged
+	return nil
+}
+
+// RepoThreshold is the yield threshold of repo signals. "fixed" uses
+// min_yield_pct; "percentile" uses threshold_percentile of the last
+// threshold_lookback_days trade days (daily rates for the sniper, same time
+// of day from history_path for realtime), falling back to min_yield_pct
+// while history is thin.
+type RepoThreshold struct {
+	MinYieldPct           float64 `yaml:"min_yield_pct"`
+	ThresholdMode         string  `yaml:"threshold_mode"`          // fixed (default) | percentile
+	ThresholdPercentile   float64 `yaml:"threshold_percentile"`    // default 95
+	ThresholdLookbackDays int     `yaml:"threshold_lookback_days"` // default 20
+	HistoryPath           string  `yaml:"history_path"`            // default state/repo_history.json
+}
+
+// YieldThreshold is the configured min_yield_pct (0 when unset); labels
+// judge repo alerts against it.
+func (r RepoThreshold) YieldThreshold() float64 { return r.MinYieldPct }
+
+// YieldThresholder is implemented by the params of signals alerting on a
+// repo rate threshold.
+type YieldThresholder interface {
+	YieldThreshold() float64
+}
+
+// RepoHistoryReader is implemented by the params of signals reading a repo
+// rate history; the path is empty when the signal does not.
+type RepoHistoryReader interface {
+	RepoHistoryPath() string
+}
+
+func (r *RepoThreshold) normalize(env Env) error {
+	r.ThresholdMode = strings.ToLower(strings.TrimSpace(r.ThresholdMode))
+	switch r.ThresholdMode {
+	case "":
+		r.ThresholdMode = "fixed"
+	case "fixed", "percentile":
+	default:
+		return errors.New("threshold_mode must be fixed or percentile")
+	}
+	if r.ThresholdPercentile < 0 || r.ThresholdPercentile > 100 {
+		return errors.New("threshold_percentile must be within 0..100")
+	}
+	if r.ThresholdPercentile == 0 {
+		r.ThresholdPercentile = 95
+	}
+	if r.ThresholdLookbackDays < 0 {
+		return errors.New("threshold_lookback_days must be >= 0")
+	}
+	if r.ThresholdLookbackDays == 0 {
+		r.ThresholdLookbackDays = 20
+	}
+	r.HistoryPath = env.path(r.HistoryPath, filepath.Join("state", "repo_history.json"))
+	return nil
+}
+
+func checkConfirmK(k int) error {
+	if k < 0 {
+		return errors.New("confirm_k must be >= 0")
+	}
+	return nil
+}
+
+func checkTopN(n int) error {
+	if n < 0 {
+		return errors.New("top_n must be >= 0")
+	}
+	return nil
+}
+
+func checkPremiumRange(low, high float64, topN int) error {
+	if low != 0 && high != 0 && low > high {
+		return errors.New("premium_pct_low must be <= premium_pct_high")
+	}
+	return checkTopN(topN)
+}
@@ -0,0 +1,59 @@
+package signals
+
+import (
+	"value-sniffer-radar/internal/repo"
+)
+
+// minThresholdSamples is how many trade days of history a percentile
+// threshold needs before it replaces min_yield_pct.
+const minThresholdSamples = 5
+
+// repoThreshold picks a repo signal's yield threshold: min_yield_pct, or a
+// percentile of recent rates in percentile mode.
+type repoThreshold struct {
+	fixed      float64
+	percentile bool
+	pct        float64
+	lookback   int
+}
+
+func newRepoThreshold(c RepoThreshold, fixed float64) repoThreshold {
+	pct := c.ThresholdPercentile
+	if pct <= 0 {
+		pct = 95
+	}
+	lookback := c.ThresholdLookbackDays
+	if lookback <= 0 {
+		lookback = 20
+	}
+	return repoThreshold{fixed: fixed, percentile: c.ThresholdMode == "percentile", pct: pct, lookback: lookback}
+}
+
+// thresholdPick is the threshold used for one symbol and how it was chosen.
+type thresholdPick struct {
+	value   float64
+	source  string // fixed | percentile | fixed_fallback (too little history)
+	samples int
+}
+
+func (t repoThreshold) pick(samples []float64) thresholdPick {
+	if !t.percentile {
+		return thresholdPick{value: t.fixed, source: "fixed"}
+	}
+	if len(samples) < minThresholdSamples {
+		return thresholdPick{value: t.fixed, source: "fixed_fallback", samples: len(samples)}
+	}
+	v, _ := repo.Percentile(samples, t.pct)
+	return thresholdPick{value: v, source: "percentile", samples: len(samples)}
+}
+
+// fill writes the chosen threshold into event data.
+func (t repoThreshold) fill(data map[string]interface{}, p thresholdPick) {
+	data["threshold_yield_pct"] = p.value
+	data["threshold_source"] = p.source
+	if t.percentile {
+		data["threshold_percentile"] = t.pct
+		data["threshold_lookback_days"] = t.lookback
+		data["threshold_samples"] = p.samples
+	}
+}
@@ -0,0 +1,58 @@
+package signals
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"value-sniffer-radar/internal/config"
+	"value-sniffer-radar/internal/marketdata"
+	"value-sniffer-radar/internal/repo"
+	"value-sniffer-radar/internal/session"
+)
+
+func TestCNRepoRealtimePercentileThreshold(t *testing.T) {
+	hs := repo.NewHistories()
+	p := CNRepoRealtimeParams{
+		RepoCodes: []string{"204001.SH"},
+		RepoThreshold: RepoThreshold{
+			MinYieldPct:   4.0,
+			ThresholdMode: "percentile", ThresholdPercentile: 90, ThresholdLookbackDays: 10,
+			HistoryPath: filepath.Join(t.TempDir(), "repo_history.json"),
+		},
+	}
+	if err := p.Normalize(Env{Histories: hs}); err != nil {
+		t.Fatal(err)
+	}
+	s := NewCNRepoRealtime(config.SignalConfig{Name: "rt"}, p)
+	rate := 2.5
+	day := func(d int) time.Time { return time.Date(2026, 1, d, 14, 50, 0, 0, session.Location) }
+	// History is fed by the engine's sampler around fusion, as in production.
+	md := func(d int) marketdata.Fusion {
+		return marketdata.NewRepoSampler(fakeFusion{snaps: map[string]marketdata.FusionSnapshot{
+			"204001.SH": {Symbol: "204001.SH", Class: marketdata.ClassRepo, ConsensusRatePct: rate, Confidence: marketdata.ConfidencePass},
+		}}, hs, func() time.Time { return day(d) })
+	}
+
+	// Build history at 1.5..1.9 over five days; until five days exist min_yield_pct (4%) holds.
+	for d := 1; d <= 5; d++ {
+		rate = 1.5 + 0.1*float64(d-1)
+		evs, _ := s.Evaluate(context.Background(), nil, fmt.Sprintf("202601%02d", d), md(d), session.Info{Now: day(d)})
+		if len(evs) != 0 {
+			t.Fatalf("day %d: unexpected events %v", d, evs[0].Data)
+		}
+	}
+
+	rate = 2.5
+	evs, err := s.Evaluate(context.Background(), nil, "20260106", md(6), session.Info{Now: day(6)})
+	if err != nil || len(evs) != 1 {
+		t.Fatalf("events=%d err=%v", len(evs), err)
+	}
+	d := evs[0].Data
+	thr := d["threshold_yield_pct"].(float64)
+	if d["threshold_source"] != "percentile" || d["threshold_samples"] != 5 || thr < 1.859 || thr > 1.861 {
+		t.Fatalf("data=%v", d)
+	}
+}
@@ -3,40 +3,119 @@ package signals
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"value-sniffer-radar/internal/config"
 	"value-sniffer-radar/internal/marketdata"
 	"value-sniffer-radar/internal/notifier"
+	"value-sniffer-radar/internal/session"
 	"value-sniffer-radar/internal/tushare"
 )
 
 type Signal interface {
 	Name() string
 	MinInterval() time.Duration
-	Evaluate(ctx context.Context, client *tushare.Client, tradeDate string, md marketdata.Fusion) ([]notifier.Event, error)
+	// sess carries the engine's clock and session phase; use sess.Now, never time.Now().
+	Evaluate(ctx context.Context, client *tushare.Client, tradeDate string, md marketdata.Fusion, sess session.Info) ([]notifier.Event, error)
 }
 
-func BuildAll(cfgs []config.SignalConfig) ([]Signal, error) {
+// Type is a registered signal type: the params its config entries decode
+// into and how to build a signal from them. Signal files register their
+// type from init, so adding one touches no other file.
+type Type struct {
+	Name string
+	// Params returns a pointer to zero params; entries decode into it, and
+	// it is normalized (Normalizer) before New sees it.
+	Params func() any
+	New    func(c config.SignalConfig, params any) (Signal, error)
+	// NeedsTushare reports whether a signal with these (normalized) params
+	// queries Tushare; nil means never.
+	NeedsTushare func(params any, env Env) bool
+}
+
+var registry = map[string]Type{}
+
+// Register makes a signal type available to config entries (type: name).
+func Register(t Type) { registry[t.Name] = t }
+
+// Types lists the registered signal types.
+func Types() []string {
+	out := make([]string, 0, len(registry))
+	for n := range registry {
+		out = append(out, n)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func always(any, Env) bool { return true }
+
+// DecodeParams decodes and normalizes the params of entry c.
+func DecodeParams(c config.SignalConfig, env Env) (any, error) {
+	t, ok := registry[c.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown signal type: %s (registered: %s)", c.Type, strings.Join(Types(), ", "))
+	}
+	name := c.Name
+	if name == "" {
+		name = c.Type
+	}
+	p := t.Params()
+	if err := c.DecodeParams(p); err != nil {
+		return nil, fmt.Errorf("signal %s (%s) params: %w", name, c.Type, err)
+	}
+	if n, ok := p.(Normalizer); ok {
+		if err := n.Normalize(env); err != nil {
+			return nil, fmt.Errorf("signal %s (%s) params: %w", name, c.Type, err)
+		}
+	}
+	return p, nil
+}
+
+func Build(c config.SignalConfig, env Env) (Signal, error) {
+	p, err := DecodeParams(c, env)
+	if err != nil {
+		return nil, err
+	}
+	return New(c, p)
+}
+
+// New builds entry c from params DecodeParams returned for it.
+func New(c config.SignalConfig, params any) (Signal, error) {
+	return registry[c.Type].New(c, params)
+}
+
+func BuildAll(cfgs []config.SignalConfig, env Env) ([]Signal, error) {
 	var out []Signal
 	for _, c := range cfgs {
 		if !c.Enabled {
 			continue
 		}
-		switch c.Type {
-		case "cb_premium":
-			out = append(out, NewCBPremium(c))
-		case "cb_double_low":
-			out = append(out, NewCBDoubleLow(c))
-		case "fund_premium":
-			out = append(out, NewFundPremium(c))
-		case "cn_repo_sniper":
-			out = append(out, NewCNRepoSniper(c))
-		case "cn_repo_realtime":
-			out = append(out, NewCNRepoRealtime(c))
-		default:
-			return nil, fmt.Errorf("unknown signal type: %s", c.Type)
+		sig, err := Build(c, env)
+		if err != nil {
+			return nil, err
 		}
+		out = append(out, sig)
 	}
 	return out, nil
 }
+
+// NeedTushare reports whether any enabled entry's signal queries Tushare.
+func NeedTushare(cfgs []config.SignalConfig, env Env) bool {
+	for _, c := range cfgs {
+		if !c.Enabled {
+			continue
+		}
+		t, ok := registry[c.Type]
+		if !ok || t.NeedsTushare == nil {
+			continue
+		}
+		// Entries that fail to decode fail Build with a better message.
+		if p, err := DecodeParams(c, env); err == nil && t.NeedsTushare(p, env) {
+			return true
+		}
+	}
+	return false
+}
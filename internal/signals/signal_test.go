@@ -0,0 +1,85 @@
+package signals
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"value-sniffer-radar/internal/config"
+)
+
+func TestExampleConfigBuilds(t *testing.T) {
+	cfg, err := config.Load(filepath.Join("..", "..", "configs", "config.example.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := EnvOf(cfg)
+	env.StateDir = t.TempDir()
+	for _, c := range cfg.Signals {
+		if _, err := Build(c, env); err != nil {
+			t.Fatalf("%s: %v", c.Name, err)
+		}
+	}
+}
+
+func parseEntries(t *testing.T, src string) []config.SignalConfig {
+	t.Helper()
+	var out []config.SignalConfig
+	if err := yaml.Unmarshal([]byte(src), &out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestBuildParams(t *testing.T) {
+	cs := parseEntries(t, `
+- type: cn_repo_sniper
+  name: nested
+  params:
+    repo_codes: ["204001.SH"]
+    min_yield_pct: 4
+- type: cn_repo_sniper
+  name: flat
+  repo_codes: ["204001.SH"]
+  min_yield_pct: 4
+  no_such_key: 1
+- type: cn_repo_sniper
+  name: typo
+  params:
+    min_yeild_pct: 4
+- type: cn_repo_sniper
+  name: bad_mode
+  params:
+    threshold_mode: rolling
+- type: cn_repo_magic
+  name: unknown_type
+`)
+	for _, c := range cs[:2] {
+		p, err := DecodeParams(c, Env{})
+		if err != nil {
+			t.Fatalf("%s: %v", c.Name, err)
+		}
+		if got := p.(YieldThresholder).YieldThreshold(); got != 4 {
+			t.Fatalf("%s: min_yield_pct=%v", c.Name, got)
+		}
+	}
+
+	wants := map[string][]string{
+		"typo":         {"line 15", `unknown key "min_yeild_pct"`, "min_yield_pct"},
+		"bad_mode":     {"signal bad_mode (cn_repo_sniper)", "threshold_mode"},
+		"unknown_type": {"unknown signal type: cn_repo_magic", "cn_repo_sniper"},
+	}
+	for _, c := range cs[2:] {
+		_, err := Build(c, Env{})
+		if err == nil {
+			t.Fatalf("%s: expected error", c.Name)
+		}
+		for _, w := range wants[c.Name] {
+			if !strings.Contains(err.Error(), w) {
+				t.Fatalf("%s: %v does not mention %q", c.Name, err, w)
+			}
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"value-sniffer-radar/internal/config"
+)
+
+// Snapshot is the engine policy state that must survive restarts:
+// dedupe keys, per-symbol cooldowns, per-signal last evaluation and daily counters.
+type Snapshot struct {
+	Version   int       `json:"version"`
+	SavedAt   time.Time `json:"saved_at"`
+	TradeDate string    `json:"trade_date,omitempty"`
+
+	Sent       map[string]time.Time `json:"sent"`
+	SymbolLast map[string]time.Time `json:"symbol_last"`
+	LastEval   map[string]time.Time `json:"last_eval"`
+	DailySent  map[string]int       `json:"daily_sent"`
+
+	// PostCloseDone maps lane -> exchange date (YYYYMMDD) whose post-close pass already ran.
+	PostCloseDone map[string]string `json:"post_close_done,omitempty"`
+}
+
+const snapshotVersion = 1
+
+// Store persists engine state. Implementations must make Save atomic:
+// a crash mid-save leaves the previous snapshot intact.
+type Store interface {
+	Name() string
+	Load() (Snapshot, error)
+	Save(s Snapshot) error
+}
+
+func Build(c config.EngineConfig) (Store, error) {
+	switch strings.TrimSpace(c.StateStore) {
+	case "", "file":
+		p := c.StatePath
+		if p == "" {
+			p = filepath.Join("state", "engine.state.json")
+		}
+		return NewFileStore(p), nil
+	case "memory":
+		return NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown engine.state_store: %s", c.StateStore)
+	}
+}
+
+// Empty returns a snapshot with all maps allocated.
+func Empty() Snapshot {
+	return Snapshot{
+		Version:    snapshotVersion,
+		Sent:       map[string]time.Time{},
+		SymbolLast: map[string]time.Time{},
+		LastEval:   map[string]time.Time{},
+		DailySent:  map[string]int{},
+
+		PostCloseDone: map[string]string{},
+	}
+}
+
+// PruneDaily keeps only daily counters scoped to tradeDate ("<trade_date>|...").
+func (s *Snapshot) PruneDaily(tradeDate string) {
+	for k := range s.DailySent {
+		if !strings.HasPrefix(k, tradeDate+"|") {
+			delete(s.DailySent, k)
+		}
+	}
+}
+
+func (s *Snapshot) normalize() {
+	if s.Sent == nil {
+		s.Sent = map[string]time.Time{}
+	}
+	if s.SymbolLast == nil {
+		s.SymbolLast = map[string]time.Time{}
+	}
+	if s.LastEval == nil {
+		s.LastEval = map[string]time.Time{}
+	}
+	if s.DailySent == nil {
+		s.DailySent = map[string]int{}
+	}
+	if s.PostCloseDone == nil {
+		s.PostCloseDone = map[string]string{}
+	}
+}
+
+// FileStore keeps the snapshot as one JSON file, replaced atomically (tmp + rename).
+type FileStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+func (f *FileStore) Name() string { return "file(" + f.path + ")" }
+
+func (f *FileStore) Load() (Snapshot, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	b, err := os.ReadFile(f.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Empty(), nil
+		}
+		return Empty(), err
+	}
+	var s Snapshot
+	if err := json.Unmarshal(b, &s); err != nil {
+		return Empty(), fmt.Errorf("state parse %s: %w", f.path, err)
+	}
+	if s.Version > snapshotVersion {
+		return Empty(), fmt.Errorf("state %s has unsupported version=%d", f.path, s.Version)
+	}
+	s.normalize()
+	return s, nil
+}
+
+func (f *FileStore) Save(s Snapshot) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s.Version = snapshotVersion
+	s.normalize()
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(f.path, append(b, '\n'))
+}
+
+// WriteFileAtomic writes data to a temp file in the same directory, syncs it and
+// renames it over path, so readers never observe a partially written file.
+func WriteFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// MemoryStore keeps the snapshot in process (no persistence); useful for tests and dry runs.
+type MemoryStore struct {
+	mu   sync.Mutex
+	snap Snapshot
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{snap: Empty()}
+}
+
+func (m *MemoryStore) Name() string { return "memory" }
+
+func (m *MemoryStore) Load() (Snapshot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return clone(m.snap), nil
+}
+
+func (m *MemoryStore) Save(s Snapshot) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s.normalize()
+	m.snap = clone(s)
+	return nil
+}
+
+func clone(s Snapshot) Snapshot {
+	out := s
+	out.Sent = make(map[string]time.Time, len(s.Sent))
+	for k, v := range s.Sent {
+		out.Sent[k] = v
+	}
+	out.SymbolLast = make(map[string]time.Time, len(s.SymbolLast))
+	for k, v := range s.SymbolLast {
+		out.SymbolLast[k] = v
+	}
+	out.LastEval = make(map[string]time.Time, len(s.LastEval))
+	for k, v := range s.LastEval {
+		out.LastEval[k] = v
+	}
+	out.DailySent = make(map[string]int, len(s.DailySent))
+	for k, v := range s.DailySent {
+		out.DailySent[k] = v
+	}
+	out.PostCloseDone = make(map[string]string, len(s.PostCloseDone))
+	for k, v := range s.PostCloseDone {
+		out.PostCloseDone[k] = v
+	}
+	return out
+}
@@ -0,0 +1,61 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "nested", "engine.state.json")
+	st := NewFileStore(path)
+
+	s, err := st.Load()
+	if err != nil {
+		t.Fatalf("load missing file err=%v", err)
+	}
+	if s.DailySent == nil || s.Sent == nil {
+		t.Fatalf("expected allocated maps on empty load")
+	}
+
+	at := time.Date(2026, 1, 29, 14, 50, 0, 0, time.UTC)
+	s.TradeDate = "20260129"
+	s.Sent["k"] = at
+	s.SymbolLast["action|sig|204001.SH"] = at
+	s.DailySent["20260129|action"] = 7
+	if err := st.Save(s); err != nil {
+		t.Fatalf("save err=%v", err)
+	}
+
+	got, err := NewFileStore(path).Load()
+	if err != nil {
+		t.Fatalf("reload err=%v", err)
+	}
+	if got.DailySent["20260129|action"] != 7 {
+		t.Fatalf("daily=%v", got.DailySent)
+	}
+	if !got.Sent["k"].Equal(at) {
+		t.Fatalf("sent=%v", got.Sent)
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Fatalf("expected only the snapshot file, got %d entries", len(entries))
+	}
+}
+
+func TestPruneDaily(t *testing.T) {
+	s := Empty()
+	s.DailySent["20260128|action"] = 30
+	s.DailySent["20260129|action"] = 3
+	s.DailySent["20260129|action|sigA"] = 1
+	s.PruneDaily("20260129")
+	if _, ok := s.DailySent["20260128|action"]; ok {
+		t.Fatalf("expected previous trade_date pruned: %v", s.DailySent)
+	}
+	if len(s.DailySent) != 2 {
+		t.Fatalf("daily=%v", s.DailySent)
+	}
+}
@@ -0,0 +1,205 @@
+package tushare
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"value-sniffer-radar/internal/state"
+)
+
+// DefaultCacheTTL is used for APIs without an explicit ttl. Reference data
+// (calendar, cb_basic) changes at most daily; daily data of a finished day
+// never changes and can live long (see OpenDayCacheTTL for unfinished ones).
+var DefaultCacheTTL = map[string]time.Duration{
+	"trade_cal":  24 * time.Hour,
+	"cb_basic":   24 * time.Hour,
+	"daily":      7 * 24 * time.Hour,
+	"cb_daily":   7 * 24 * time.Hour,
+	"fund_daily": 7 * 24 * time.Hour,
+	"fund_nav":   7 * 24 * time.Hour,
+	"repo_daily": 7 * 24 * time.Hour,
+}
+
+// OpenDayCacheTTL caps the ttl of daily data (dailyPublished) saved before
+// every date the query covers was over: Tushare publishes a day's rows
+// incrementally that evening, so such an answer may be partial.
+var OpenDayCacheTTL = 10 * time.Minute
+
+// dailyPublished are the APIs whose rows for a day appear over that evening.
+var dailyPublished = map[string]bool{
+	"daily": true, "cb_daily": true, "fund_daily": true, "fund_nav": true, "repo_daily": true,
+}
+
+// CacheOptions configures the response cache. APIs with no ttl (neither in TTL
+// nor DefaultCacheTTL) are never cached.
+type CacheOptions struct {
+	Dir string                   // on-disk cache dir; empty keeps the cache in memory only
+	TTL map[string]time.Duration // per api_name; overrides DefaultCacheTTL, <=0 disables
+}
+
+// Cache is a memory + disk cache of successful, non-empty query results keyed by
+// api_name, params and fields. Empty results are not cached: Tushare returns
+// nothing for a trade date whose data has not been published yet.
+type Cache struct {
+	dir string
+	ttl map[string]time.Duration
+
+	mu  sync.Mutex
+	mem map[string]cacheEntry
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+type cacheEntry struct {
+	API     string                   `json:"api"`
+	SavedAt time.Time                `json:"saved_at"`
+	Rows    []map[string]interface{} `json:"rows"`
+}
+
+func NewCache(opt CacheOptions) *Cache {
+	ttl := make(map[string]time.Duration, len(DefaultCacheTTL)+len(opt.TTL))
+	for k, v := range DefaultCacheTTL {
+		ttl[k] = v
+	}
+	for k, v := range opt.TTL {
+		ttl[k] = v
+	}
+	return &Cache{dir: opt.Dir, ttl: ttl, mem: map[string]cacheEntry{}}
+}
+
+// Stats returns cumulative hit/miss counters of cacheable queries.
+func (c *Cache) Stats() (hits, misses int64) {
+	return c.hits.Load(), c.misses.Load()
+}
+
+func (c *Cache) cacheable(api string) bool {
+	return c.ttl[api] > 0
+}
+
+// ttlOf is the ttl of a response to params saved at savedAt.
+func (c *Cache) ttlOf(api string, params map[string]any, savedAt time.Time) time.Duration {
+	ttl := c.ttl[api]
+	if dailyPublished[api] && ttl > OpenDayCacheTTL && !closedBefore(params, savedAt.In(exchangeLocation).Format("20060102")) {
+		return OpenDayCacheTTL
+	}
+	return ttl
+}
+
+// closedBefore reports whether the query names its last date (trade_date,
+// nav_date, ann_date or end_date) and every such date is before day. Open
+// ranges (start_date only, or no date at all) run up to today.
+func closedBefore(params map[string]any, day string) bool {
+	closed := false
+	for _, k := range []string{"trade_date", "nav_date", "ann_date", "end_date"} {
+		v, _ := params[k].(string)
+		if v == "" {
+			continue
+		}
+		if v >= day {
+			return false
+		}
+		closed = true
+	}
+	return closed
+}
+
+func (c *Cache) get(api, key string, params map[string]any, now time.Time) ([]map[string]interface{}, bool) {
+	ent, ok := c.entry(api, key)
+	if !ok || now.Sub(ent.SavedAt) > c.ttlOf(api, params, ent.SavedAt) {
+		c.misses.Add(1)
+		return nil, false
+	}
+	c.hits.Add(1)
+	return ent.Rows, true
+}
+
+// lookup returns a cached response regardless of its age (offline clients).
+func (c *Cache) lookup(api, key string) ([]map[string]interface{}, bool) {
+	ent, ok := c.entry(api, key)
+	if !ok {
+		c.misses.Add(1)
+		return nil, false
+	}
+	c.hits.Add(1)
+	return ent.Rows, true
+}
+
+func (c *Cache) entry(api, key string) (cacheEntry, bool) {
+	c.mu.Lock()
+	ent, ok := c.mem[key]
+	c.mu.Unlock()
+	if !ok && c.dir != "" {
+		ent, ok = c.readDisk(api, key)
+		if ok {
+			c.mu.Lock()
+			c.mem[key] = ent
+			c.mu.Unlock()
+		}
+	}
+	return ent, ok
+}
+
+func (c *Cache) put(api, key string, rows []map[string]interface{}, now time.Time) {
+	if len(rows) == 0 {
+		return
+	}
+	ent := cacheEntry{API: api, SavedAt: now, Rows: rows}
+	c.mu.Lock()
+	c.mem[key] = ent
+	c.mu.Unlock()
+	if c.dir == "" {
+		return
+	}
+	b, err := json.Marshal(ent)
+	if err == nil {
+		err = state.WriteFileAtomic(c.path(api, key), b)
+	}
+	if err != nil {
+		log.Printf("tushare cache write failed api=%s err=%v", api, err)
+	}
+}
+
+func (c *Cache) readDisk(api, key string) (cacheEntry, bool) {
+	b, err := os.ReadFile(c.path(api, key))
+	if err != nil {
+		return cacheEntry{}, false
+	}
+	var ent cacheEntry
+	if err := json.Unmarshal(b, &ent); err != nil {
+		log.Printf("tushare cache entry ignored api=%s err=%v", api, err)
+		return cacheEntry{}, false
+	}
+	return ent, true
+}
+
+func (c *Cache) path(api, key string) string {
+	return filepath.Join(c.dir, api, key+".json")
+}
+
+// cacheKey hashes api_name, params (sorted) and fields; the token is excluded.
+func cacheKey(api string, params map[string]any, fields []string) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteString(api)
+	for _, k := range keys {
+		v, _ := json.Marshal(params[k])
+		b.WriteString("|" + k + "=" + string(v))
+	}
+	b.WriteString("|fields=" + joinFields(fields))
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:16])
+}
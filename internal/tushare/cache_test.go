@@ -0,0 +1,146 @@
+package tushare
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"value-sniffer-radar/internal/clock"
+)
+
+func TestQueryCachesNonEmptyResultsOnDisk(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		var req request
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		resp := map[string]any{"code": 0, "msg": "", "data": map[string]any{"fields": []string{"ts_code", "close"}, "items": [][]any{}}}
+		if req.Params["trade_date"] == "20260105" {
+			resp["data"] = map[string]any{"fields": []string{"ts_code", "close"}, "items": [][]any{{"600000.SH", 7.5}}}
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	newClient := func() *Client {
+		return New(Options{BaseURL: srv.URL, Token: "t", Cache: NewCache(CacheOptions{Dir: dir})})
+	}
+	ctx := context.Background()
+
+	c := newClient()
+	for i := 0; i < 2; i++ {
+		rows, err := c.Query(ctx, "daily", map[string]any{"trade_date": "20260105"}, []string{"ts_code", "close"})
+		if err != nil || len(rows) != 1 || GetFloat(rows[0], "close") != 7.5 {
+			t.Fatalf("rows=%v err=%v", rows, err)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("http calls=%d want=1", n)
+	}
+	if h, m := c.CacheStats(); h != 1 || m != 1 {
+		t.Fatalf("hits=%d misses=%d", h, m)
+	}
+
+	// Empty (not yet published) results are not cached.
+	for i := 0; i < 2; i++ {
+		if _, err := c.Query(ctx, "daily", map[string]any{"trade_date": "20260106"}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := calls.Load(); n != 3 {
+		t.Fatalf("http calls=%d want=3", n)
+	}
+
+	// A restarted client is served from disk.
+	c2 := newClient()
+	if _, err := c2.Query(ctx, "daily", map[string]any{"trade_date": "20260105"}, []string{"ts_code", "close"}); err != nil {
+		t.Fatal(err)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Fatalf("http calls after restart=%d want=3", n)
+	}
+
+	// Offline clients answer from the cache only, whatever the entry's age.
+	off := New(Options{Cache: NewCache(CacheOptions{Dir: dir, TTL: map[string]time.Duration{"daily": time.Nanosecond}}), Offline: true})
+	if rows, err := off.Query(ctx, "daily", map[string]any{"trade_date": "20260105"}, []string{"ts_code", "close"}); err != nil || len(rows) != 1 {
+		t.Fatalf("offline rows=%v err=%v", rows, err)
+	}
+	if _, err := off.Query(ctx, "daily", map[string]any{"trade_date": "20260106"}, nil); !errors.Is(err, ErrOffline) {
+		t.Fatalf("offline miss err=%v", err)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Fatalf("http calls offline=%d want=3", n)
+	}
+}
+
+func TestCacheKeyIgnoresParamOrder(t *testing.T) {
+	a := cacheKey("trade_cal", map[string]any{"start_date": "1", "end_date": "2"}, []string{"cal_date"})
+	b := cacheKey("trade_cal", map[string]any{"end_date": "2", "start_date": "1"}, []string{"cal_date"})
+	if a != b {
+		t.Fatalf("keys differ: %s %s", a, b)
+	}
+	if a == cacheKey("trade_cal", map[string]any{"start_date": "1", "end_date": "3"}, []string{"cal_date"}) {
+		t.Fatal("different params must not share a key")
+	}
+}
+
+func TestCacheKeepsSameDayDailyDataShort(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		items := [][]any{{"510300.SH", 4.0}}
+		if n > 1 {
+			items = append(items, [][]any{{"159915.SZ", 2.0}}...) // published later that evening
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"code": 0, "msg": "", "data": map[string]any{"fields": []string{"ts_code", "unit_nav"}, "items": items}})
+	}))
+	defer srv.Close()
+
+	now := time.Date(2026, 1, 6, 18, 0, 0, 0, exchangeLocation)
+	c := New(Options{BaseURL: srv.URL, Token: "t", Cache: NewCache(CacheOptions{}), Clock: clock.Func(func() time.Time { return now })})
+	query := func(params map[string]any) int {
+		t.Helper()
+		rows, err := c.Query(context.Background(), "fund_nav", params, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(rows)
+	}
+	today := map[string]any{"nav_date": "20260106"}
+	lookback := map[string]any{"ts_code": "510300.SH", "start_date": "20251220", "end_date": "20260106"}
+
+	// The first answer of the day is partial; it must not be kept for a week.
+	if n := query(today); n != 1 {
+		t.Fatalf("rows=%d", n)
+	}
+	now = now.Add(time.Minute)
+	if n := query(today); n != 1 || calls.Load() != 1 {
+		t.Fatalf("short ttl: rows=%d calls=%d", n, calls.Load())
+	}
+	now = now.Add(OpenDayCacheTTL)
+	if n := query(today); n != 2 || calls.Load() != 2 {
+		t.Fatalf("after short ttl: rows=%d calls=%d", n, calls.Load())
+	}
+	// A range ending today is just as open; a saved answer for today stays
+	// short even once the day is over.
+	query(lookback)
+	now = now.Add(24 * time.Hour)
+	query(lookback)
+	if n := calls.Load(); n != 4 {
+		t.Fatalf("range ending today: calls=%d want=4", n)
+	}
+
+	// Refetched once the day is over, the answer is final and cached long.
+	query(today)
+	now = now.Add(3 * 24 * time.Hour)
+	query(today)
+	if n := calls.Load(); n != 5 {
+		t.Fatalf("finished day: calls=%d want=5", n)
+	}
+}
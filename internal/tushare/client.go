@@ -7,9 +7,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"strconv"
 	"time"
+
+	"value-sniffer-radar/internal/clock"
 )
 
 type Options struct {
@@ -17,6 +20,14 @@ type Options struct {
 	Token          string
 	TimeoutSeconds int
 	MaxRetries     int
+	Cache          *Cache               // optional
+	RateLimits     map[string]RateLimit // per api_name; "default" applies to the rest
+	Usage          *Usage               // optional call accounting
+	Clock          clock.Clock          // cache ages and usage days; nil: wall clock
+
+	// Offline answers only from Cache (ignoring TTLs) and never calls the
+	// API; backtests use it to replay what the live engine fetched.
+	Offline bool
 }
 
 type Client struct {
@@ -24,6 +35,11 @@ type Client struct {
 	token      string
 	httpClient *http.Client
 	maxRetries int
+	cache      *Cache
+	limiter    *limiter
+	usage      *Usage
+	offline    bool
+	clock      clock.Clock
 }
 
 func New(opt Options) *Client {
@@ -42,7 +58,31 @@ func New(opt Options) *Client {
 			Timeout: timeout,
 		},
 		maxRetries: retries,
+		cache:      opt.Cache,
+		limiter:    newLimiter(opt.RateLimits),
+		usage:      opt.Usage,
+		offline:    opt.Offline,
+		clock:      clock.Or(opt.Clock),
+	}
+}
+
+// ErrOffline is returned by an offline client for responses it has not cached.
+var ErrOffline = errors.New("tushare offline: response not cached")
+
+// Usage returns the call accounting (nil if not configured).
+func (c *Client) Usage() *Usage {
+	if c == nil {
+		return nil
+	}
+	return c.usage
+}
+
+// CacheStats returns cumulative cache hit/miss counters (zero without a cache).
+func (c *Client) CacheStats() (hits, misses int64) {
+	if c == nil || c.cache == nil {
+		return 0, 0
 	}
+	return c.cache.Stats()
 }
 
 type request struct {
@@ -62,6 +102,23 @@ type response struct {
 }
 
 func (c *Client) Query(ctx context.Context, apiName string, params map[string]any, fields []string) ([]map[string]interface{}, error) {
+	if c.offline {
+		if c.cache != nil {
+			if rows, ok := c.cache.lookup(apiName, cacheKey(apiName, params, fields)); ok {
+				return rows, nil
+			}
+		}
+		return nil, fmt.Errorf("%w: %s %v", ErrOffline, apiName, params)
+	}
+
+	var key string
+	if c.cache != nil && c.cache.cacheable(apiName) {
+		key = cacheKey(apiName, params, fields)
+		if rows, ok := c.cache.get(apiName, key, params, c.clock.Now()); ok {
+			return rows, nil
+		}
+	}
+
 	reqBody := request{
 		APIName: apiName,
 		Token:   c.token,
@@ -73,16 +130,62 @@ func (c *Client) Query(ctx context.Context, apiName string, params map[string]an
 
 	var lastErr error
 	for attempt := 1; attempt <= c.maxRetries; attempt++ {
+		if err := c.limiter.Wait(ctx, apiName); err != nil {
+			return nil, err
+		}
+		c.usage.add(ctx, apiName, c.clock.Now())
 		out, err := c.doOnce(ctx, reqBody)
 		if err == nil {
+			if key != "" {
+				c.cache.put(apiName, key, out, c.clock.Now())
+			}
 			return out, nil
 		}
 		lastErr = err
-		time.Sleep(time.Duration(attempt) * 400 * time.Millisecond)
+
+		var wait time.Duration
+		switch {
+		case IsRateLimited(err):
+			// Quotas are per minute: back off exponentially instead of burning retries.
+			c.limiter.drain(apiName)
+			wait = rateLimitBackoff(attempt)
+			log.Printf("tushare rate limited api=%s caller=%s attempt=%d backoff=%s", apiName, callerFrom(ctx), attempt, wait)
+		case isPermanent(err):
+			return nil, err
+		default:
+			wait = time.Duration(attempt) * 400 * time.Millisecond
+		}
+		if attempt == c.maxRetries {
+			break
+		}
+		if err := sleepCtx(ctx, wait); err != nil {
+			return nil, lastErr
+		}
 	}
 	return nil, lastErr
 }
 
+func rateLimitBackoff(attempt int) time.Duration {
+	d := 5 * time.Second << (attempt - 1)
+	if d > time.Minute || d <= 0 {
+		d = time.Minute
+	}
+	return d
+}
+
+// isPermanent is an api error that retrying cannot fix (bad params, no permission, out of points).
+func isPermanent(err error) bool {
+	var ae *APIError
+	return errors.As(err, &ae) && !IsRateLimited(err)
+}
+
+type httpStatusError struct {
+	status int
+	msg    string
+}
+
+func (e *httpStatusError) Error() string { return e.msg }
+
 func (c *Client) doOnce(ctx context.Context, reqBody request) ([]map[string]interface{}, error) {
 	b, err := json.Marshal(reqBody)
 	if err != nil {
@@ -106,7 +209,7 @@ func (c *Client) doOnce(ctx context.Context, reqBody request) ([]map[string]inte
 		return nil, err
 	}
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("tushare http status=%s body=%s", resp.Status, string(raw))
+		return nil, &httpStatusError{status: resp.StatusCode, msg: fmt.Sprintf("tushare http status=%s body=%s", resp.Status, string(raw))}
 	}
 
 	var tr response
@@ -114,16 +217,21 @@ func (c *Client) doOnce(ctx context.Context, reqBody request) ([]map[string]inte
 		return nil, fmt.Errorf("tushare parse: %w body=%s", err, string(raw))
 	}
 	if tr.Code != 0 {
-		return nil, fmt.Errorf("tushare api error code=%d msg=%s", tr.Code, tr.Msg)
+		return nil, &APIError{Code: tr.Code, Msg: tr.Msg}
 	}
 	return rowsToMaps(tr.Data.Fields, tr.Data.Items), nil
 }
 
 func (c *Client) LatestOpenTradeDate(ctx context.Context, lookbackDays int) (string, error) {
+	return c.LatestOpenTradeDateAt(ctx, c.clock.Now(), lookbackDays)
+}
+
+// LatestOpenTradeDateAt is LatestOpenTradeDate as of now (simulated clocks).
+func (c *Client) LatestOpenTradeDateAt(ctx context.Context, now time.Time, lookbackDays int) (string, error) {
 	if lookbackDays <= 0 {
 		lookbackDays = 30
 	}
-	end := time.Now()
+	end := now
 	start := end.AddDate(0, 0, -lookbackDays)
 	startStr := start.Format("20060102")
 	endStr := end.Format("20060102")
@@ -0,0 +1,147 @@
+package tushare
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimit is a token bucket: PerMinute calls refill evenly, up to Burst at once.
+type RateLimit struct {
+	PerMinute int
+	Burst     int // default max(1, PerMinute/10)
+}
+
+// APIError is a non-zero Tushare response code.
+type APIError struct {
+	Code int
+	Msg  string
+}
+
+func (e *APIError) Error() string {
+	return "tushare api error code=" + strconv.Itoa(e.Code) + " msg=" + e.Msg
+}
+
+// rateLimitCode is what Tushare returns when the per-minute quota of an api is exhausted
+// ("抱歉，您每分钟最多访问该接口N次").
+const rateLimitCode = 40203
+
+// IsRateLimited reports whether err is Tushare telling us to slow down.
+func IsRateLimited(err error) bool {
+	var ae *APIError
+	if errors.As(err, &ae) {
+		return ae.Code == rateLimitCode || strings.Contains(ae.Msg, "每分钟最多访问") || strings.Contains(ae.Msg, "每小时最多访问")
+	}
+	var he *httpStatusError
+	if errors.As(err, &he) {
+		return he.status == 429
+	}
+	return false
+}
+
+// limiter holds one bucket per api_name ("default" applies to the rest).
+type limiter struct {
+	mu      sync.Mutex
+	limits  map[string]RateLimit
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newLimiter(limits map[string]RateLimit) *limiter {
+	if len(limits) == 0 {
+		return nil
+	}
+	return &limiter{limits: limits, buckets: map[string]*bucket{}}
+}
+
+func (l *limiter) bucketLocked(api string) *bucket {
+	if b, ok := l.buckets[api]; ok {
+		return b
+	}
+	rl, ok := l.limits[api]
+	if !ok {
+		rl = l.limits["default"]
+	}
+	if rl.PerMinute <= 0 {
+		l.buckets[api] = nil
+		return nil
+	}
+	burst := rl.Burst
+	if burst <= 0 {
+		burst = rl.PerMinute / 10
+		if burst < 1 {
+			burst = 1
+		}
+	}
+	b := &bucket{rate: float64(rl.PerMinute) / 60, burst: float64(burst), tokens: float64(burst)}
+	l.buckets[api] = b
+	return b
+}
+
+// Wait blocks until a call to api is allowed or ctx is done.
+func (l *limiter) Wait(ctx context.Context, api string) error {
+	if l == nil {
+		return nil
+	}
+	for {
+		l.mu.Lock()
+		b := l.bucketLocked(api)
+		if b == nil {
+			l.mu.Unlock()
+			return nil
+		}
+		now := time.Now()
+		if !b.last.IsZero() {
+			b.tokens += now.Sub(b.last).Seconds() * b.rate
+			if b.tokens > b.burst {
+				b.tokens = b.burst
+			}
+		}
+		b.last = now
+		if b.tokens >= 1 {
+			b.tokens--
+			l.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		l.mu.Unlock()
+
+		if err := sleepCtx(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// drain empties the bucket after the server rejected a call, so concurrent
+// callers back off too instead of hammering the same api.
+func (l *limiter) drain(api string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if b := l.bucketLocked(api); b != nil {
+		b.tokens = 0
+		b.last = time.Now()
+	}
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,94 @@
+package tushare
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLimiterSpacesCallsAfterBurst(t *testing.T) {
+	l := newLimiter(map[string]RateLimit{"fund_nav": {PerMinute: 600, Burst: 2}}) // 10/s
+	ctx := context.Background()
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := l.Wait(ctx, "fund_nav"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 2 burst tokens, then 2 more at 100ms each.
+	if d := time.Since(start); d < 150*time.Millisecond {
+		t.Fatalf("limiter too permissive: 4 calls in %s", d)
+	}
+	// Unlisted apis without a default are unlimited.
+	if err := l.Wait(ctx, "daily"); err != nil {
+		t.Fatal(err)
+	}
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	l.drain("fund_nav")
+	if err := l.Wait(cctx, "fund_nav"); err == nil {
+		t.Fatal("expected ctx error while waiting for a token")
+	}
+}
+
+func TestQueryRateLimitAndUsage(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req request
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		calls.Add(1)
+		switch req.APIName {
+		case "fund_nav":
+			_ = json.NewEncoder(w).Encode(map[string]any{"code": 40203, "msg": "抱歉，您每分钟最多访问该接口80次"})
+		case "cb_basic":
+			_ = json.NewEncoder(w).Encode(map[string]any{"code": 40001, "msg": "权限不足"})
+		default:
+			_ = json.NewEncoder(w).Encode(map[string]any{"code": 0, "data": map[string]any{"fields": []string{"x"}, "items": [][]any{{1}}}})
+		}
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "usage.json")
+	u, err := NewUsage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := New(Options{BaseURL: srv.URL, Token: "t", MaxRetries: 3, Usage: u})
+
+	// Permanent api errors are not retried.
+	_, err = c.Query(WithCaller(context.Background(), "cb_premium"), "cb_basic", nil, nil)
+	if err == nil || IsRateLimited(err) || calls.Load() != 1 {
+		t.Fatalf("err=%v calls=%d", err, calls.Load())
+	}
+
+	// Rate limited: backoff honours ctx instead of blind fast retries.
+	ctx, cancel := context.WithTimeout(WithCaller(context.Background(), "fund_premium"), 200*time.Millisecond)
+	defer cancel()
+	_, err = c.Query(ctx, "fund_nav", nil, nil)
+	if !IsRateLimited(err) || calls.Load() != 2 {
+		t.Fatalf("err=%v calls=%d", err, calls.Load())
+	}
+
+	if _, err := c.Query(context.Background(), "trade_cal", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	if err := u.Flush(now); err != nil {
+		t.Fatal(err)
+	}
+	u2, err := NewUsage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := u2.Day(now.In(exchangeLocation).Format("20060102"))
+	if day["cb_premium"] != 1 || day["fund_premium"] != 1 || day["engine"] != 1 {
+		t.Fatalf("usage=%v", day)
+	}
+}
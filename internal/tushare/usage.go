@@ -0,0 +1,153 @@
+package tushare
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"value-sniffer-radar/internal/state"
+)
+
+// exchangeLocation is exchange time (CST): Tushare points reset and daily data
+// is published per exchange day.
+var exchangeLocation = time.FixedZone("CST", 8*3600)
+
+// usageKeepDays bounds the persisted history.
+const usageKeepDays = 31
+
+type callerKey struct{}
+
+// WithCaller tags Tushare calls made with ctx, for per-signal usage accounting.
+func WithCaller(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+func callerFrom(ctx context.Context) string {
+	if s, _ := ctx.Value(callerKey{}).(string); s != "" {
+		return s
+	}
+	return "engine"
+}
+
+// Usage counts requests actually sent to Tushare (cache hits are free) per
+// day, caller and api_name, persisted as JSON so quota burn is visible across restarts.
+type Usage struct {
+	path string
+
+	mu     sync.Mutex
+	counts map[string]int // "YYYYMMDD|caller|api" -> calls
+	dirty  bool
+}
+
+// NewUsage loads the counter file at path (empty path keeps counts in memory).
+func NewUsage(path string) (*Usage, error) {
+	u := &Usage{path: path, counts: map[string]int{}}
+	if path == "" {
+		return u, nil
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return u, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f usageFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, err
+	}
+	for k, n := range f.Calls {
+		// Hand-edited or damaged files may hold keys we cannot attribute.
+		if parts := strings.SplitN(k, "|", 3); len(parts) == 3 && parts[0] != "" {
+			u.counts[k] = n
+		}
+	}
+	return u, nil
+}
+
+type usageFile struct {
+	SavedAt time.Time      `json:"saved_at"`
+	Calls   map[string]int `json:"calls"`
+}
+
+func (u *Usage) add(ctx context.Context, api string, now time.Time) {
+	if u == nil {
+		return
+	}
+	k := now.In(exchangeLocation).Format("20060102") + "|" + callerFrom(ctx) + "|" + api
+	u.mu.Lock()
+	u.counts[k]++
+	u.dirty = true
+	u.mu.Unlock()
+}
+
+// Day returns caller -> calls for the given YYYYMMDD.
+func (u *Usage) Day(date string) map[string]int {
+	out := map[string]int{}
+	if u == nil {
+		return out
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for k, n := range u.counts {
+		parts := strings.SplitN(k, "|", 3)
+		if len(parts) == 3 && parts[0] == date {
+			out[parts[1]] += n
+		}
+	}
+	return out
+}
+
+// Flush persists the counters if they changed, dropping days older than usageKeepDays.
+func (u *Usage) Flush(now time.Time) error {
+	if u == nil || u.path == "" {
+		return nil
+	}
+	u.mu.Lock()
+	if !u.dirty {
+		u.mu.Unlock()
+		return nil
+	}
+	cutoff := now.In(exchangeLocation).AddDate(0, 0, -usageKeepDays).Format("20060102")
+	calls := make(map[string]int, len(u.counts))
+	for k, n := range u.counts {
+		if day, _, _ := strings.Cut(k, "|"); day < cutoff {
+			delete(u.counts, k)
+			continue
+		}
+		calls[k] = n
+	}
+	u.dirty = false
+	u.mu.Unlock()
+
+	b, err := json.MarshalIndent(usageFile{SavedAt: now, Calls: calls}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return state.WriteFileAtomic(u.path, append(b, '\n'))
+}
+
+// FormatDay renders Day(date) as "caller=n ..." sorted by caller.
+func (u *Usage) FormatDay(date string) string {
+	day := u.Day(date)
+	names := make([]string, 0, len(day))
+	for k := range day {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for i, k := range names {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(k + "=" + strconv.Itoa(day[k]))
+	}
+	return b.String()
+}
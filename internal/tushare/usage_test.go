@@ -0,0 +1,30 @@
+package tushare
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUsageDropsMalformedKeysOnLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.json")
+	content := `{"calls":{"20260106|sigA|daily":3,"garbage":1,"20260106|half":2}}`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	u, err := NewUsage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if day := u.Day("20260106"); len(day) != 1 || day["sigA"] != 3 {
+		t.Fatalf("day=%v", day)
+	}
+	u.dirty = true
+	if err := u.Flush(time.Date(2026, 1, 7, 10, 0, 0, 0, exchangeLocation)); err != nil {
+		t.Fatal(err)
+	}
+	if len(u.counts) != 1 {
+		t.Fatalf("counts=%v", u.counts)
+	}
+}